@@ -0,0 +1,117 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Assets serves the files of a file system under URLs containing a hash of
+// their content, like /static/css/app.3f2a9c1b4d5e6f70.css for css/app.css.
+// Since the URL of a file changes with its content, clients may cache the
+// files forever.
+//
+// The files are hashed once by NewAssets, so changes made to the file system
+// afterwards are not picked up.
+type Assets struct {
+	fsys   fs.FS
+	prefix string
+	urls   map[string]string // Hashed URLs by file name
+	names  map[string]string // File names by hashed name
+}
+
+// NewAssets hashes all files of the file system, which are served below the
+// prefix once registered with Router.ServeAssets.
+func NewAssets(fsys fs.FS, prefix string) (*Assets, error) {
+	a := &Assets{
+		fsys:   fsys,
+		prefix: strings.TrimSuffix(prefix, "/"),
+		urls:   make(map[string]string),
+		names:  make(map[string]string),
+	}
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		sum, err := hashFile(fsys, name)
+		if err != nil {
+			return err
+		}
+
+		// The hash is inserted before the extension, so that the content
+		// type can still be derived from the name
+		ext := path.Ext(name)
+		hashed := strings.TrimSuffix(name, ext) + "." + sum + ext
+		a.urls[name] = a.prefix + "/" + hashed
+		a.names[hashed] = name
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// hashFile returns the first 16 hex digits of the SHA-256 hash of a file.
+func hashFile(fsys fs.FS, name string) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8]), nil
+}
+
+// URL returns the hashed URL of the named file, e.g. for use in templates:
+//
+//	tmpl.Funcs(template.FuncMap{"asset": assets.URL})
+//
+//	<link rel="stylesheet" href="{{asset "css/app.css"}}">
+//
+// Names of files missing in the file system are returned below the prefix
+// unchanged, so a missing asset results in a 404 Not Found rather than a
+// broken template.
+func (a *Assets) URL(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if u, ok := a.urls[name]; ok {
+		return u
+	}
+	return a.prefix + "/" + name
+}
+
+// ServeAssets serves the files of the assets below their prefix. Requests for
+// hashed URLs are answered with headers allowing clients to cache the file
+// forever. Files requested by their original name are served as well, but
+// must be revalidated by clients on each use.
+func (r *Router) ServeAssets(a *Assets) {
+	fileServer := http.FileServerFS(a.fsys)
+
+	r.GET(a.prefix+"/{filepath...}", func(w http.ResponseWriter, req *http.Request) {
+		hashed := strings.TrimPrefix(req.PathValue("filepath"), "/")
+		if name, ok := a.names[hashed]; ok {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+			req.URL.Path = "/" + name
+		} else if _, ok := a.urls[hashed]; ok {
+			w.Header().Set("Cache-Control", "no-cache")
+			req.URL.Path = "/" + hashed
+		} else {
+			writeError(w, req, "404 page not found", http.StatusNotFound)
+			return
+		}
+		fileServer.ServeHTTP(w, req)
+	})
+}
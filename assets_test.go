@@ -0,0 +1,64 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAssets(t *testing.T) {
+	fsys := fstest.MapFS{
+		"css/app.css": {Data: []byte("body{}")},
+		"LICENSE":     {Data: []byte("BSD")},
+	}
+	assets, err := NewAssets(fsys, "/static/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := New()
+	router.ServeAssets(assets)
+
+	cssURL := assets.URL("css/app.css")
+	if !regexp.MustCompile(`^/static/css/app\.[0-9a-f]{16}\.css$`).MatchString(cssURL) {
+		t.Fatalf("unexpected hashed URL %q", cssURL)
+	}
+	if u := assets.URL("/css/app.css"); u != cssURL {
+		t.Errorf("leading slash: expected %q, got %q", cssURL, u)
+	}
+	if u := assets.URL("LICENSE"); !regexp.MustCompile(`^/static/LICENSE\.[0-9a-f]{16}$`).MatchString(u) {
+		t.Errorf("unexpected hashed URL %q", u)
+	}
+	if u := assets.URL("missing.js"); u != "/static/missing.js" {
+		t.Errorf("missing asset: got %q", u)
+	}
+
+	tests := []struct {
+		path, body, cacheControl string
+		code                     int
+	}{
+		{cssURL, "body{}", "public, max-age=31536000, immutable", http.StatusOK},
+		{"/static/css/app.css", "body{}", "no-cache", http.StatusOK},
+		{"/static/css/app.0000000000000000.css", "", "", http.StatusNotFound},
+		{"/static/missing.js", "", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.code || w.Header().Get("Cache-Control") != tt.cacheControl ||
+			tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s: unexpected response %d %q %v", tt.path, w.Code, w.Body.String(), w.Header())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, cssURL, nil))
+	if ct := w.Header().Get("Content-Type"); ct != "text/css; charset=utf-8" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+}
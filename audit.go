@@ -0,0 +1,110 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Kinds of conflicts, as reported by Audit.
+const (
+	// A route of a group is dispatched to another group
+	ConflictGroup = "group"
+
+	// A route of the default router is dispatched to a group
+	ConflictDefault = "default"
+
+	// A group or the default router is never dispatched to
+	ConflictShadowed = "shadowed"
+)
+
+// Conflict describes a conflict between the groups of a MultiRouter.
+type Conflict struct {
+	// One of the Conflict constants
+	Kind string
+
+	// Full path of the route involved, empty for conflicts between prefixes
+	Route string
+
+	// Prefixes of the groups involved, empty for the default router
+	Groups []string
+
+	Message string
+}
+
+func (c Conflict) String() string {
+	return c.Kind + ": " + c.Message
+}
+
+// Audit runs the conflict checks of the registration methods against all
+// groups, mounts and the default router at once and returns the conflicts
+// found, sorted by kind and route. Unlike the registration methods it neither
+// panics nor logs, so applications can check their configuration themselves,
+// e.g. in tests or at startup:
+//
+//	if conflicts := multi.Audit(); len(conflicts) > 0 {
+//		log.Fatal(conflicts)
+//	}
+//
+// Besides conflicting routes, Audit reports groups which are never
+// dispatched to: parameterized prefixes matching the same paths as an earlier
+// one, and the default router if a group is registered at the root.
+func (m *MultiRouter) Audit() []Conflict {
+	s := m.state.Load()
+	var conflicts []Conflict
+	add := func(kind, route, msg string, groups ...string) {
+		conflicts = append(conflicts, Conflict{Kind: kind, Route: route, Groups: groups, Message: msg})
+	}
+
+	for _, prefix := range s.prefixes {
+		g := s.groups[prefix]
+		if g.router == nil {
+			continue
+		}
+
+		for _, path := range g.router.getPaths() {
+			fullPath := g.fullPath(path)
+			if g.preservePath && strings.IndexByte(prefix, '{') < 0 && !strings.HasPrefix(path, prefix) {
+				add(ConflictGroup, fullPath, fmt.Sprintf("group '%s' preserves the path, but its route '%s' does not start with the prefix", prefix, path), prefix)
+			}
+			if other := s.dispatch(fullPath); other == nil {
+				add(ConflictGroup, fullPath, fmt.Sprintf("route '%s' of group '%s' is dispatched to no group", fullPath, prefix), prefix)
+			} else if other != g {
+				add(ConflictGroup, fullPath, fmt.Sprintf("route '%s' of group '%s' is dispatched to group '%s'", fullPath, prefix, other.prefix), prefix, other.prefix)
+			}
+		}
+	}
+
+	if s.defaultRouter != nil {
+		paths := s.defaultRouter.getPaths()
+		for _, path := range paths {
+			if g := s.tree.lookup(path); g != nil {
+				add(ConflictDefault, path, fmt.Sprintf("route '%s' of the default router is dispatched to group '%s'", path, g.prefix), g.prefix)
+			}
+		}
+		if _, ok := s.groups["/"]; ok && len(paths) > 0 {
+			add(ConflictShadowed, "", "the default router is never used, since a group is registered at the root", "/")
+		}
+	}
+
+	// Prefixes differing only in the names of their parameters match the
+	// same paths, so only the first one in matching order is dispatched to
+	for _, prefix := range s.prefixes {
+		if first := s.tree.find(prefix); prefix != "/" && first != s.groups[prefix] {
+			add(ConflictShadowed, "", fmt.Sprintf("group '%s' is never used, since group '%s' matches the same paths", prefix, first.prefix), prefix, first.prefix)
+		}
+	}
+
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		a, b := conflicts[i], conflicts[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Route < b.Route
+	})
+	return conflicts
+}
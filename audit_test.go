@@ -0,0 +1,66 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMultiRouterAudit(t *testing.T) {
+	multi := NewMultiRouter()
+	if conflicts := multi.Audit(); len(conflicts) != 0 {
+		t.Fatalf("expected no conflicts, got %v", conflicts)
+	}
+
+	logger, _ := newTestLogger()
+	multi.WarnOnConflict(logger)
+
+	multi.NewGroup("/api").GET("/v2/users", dummyHandler)
+	multi.NewGroup("/api/v2").GET("/items", dummyHandler)
+	multi.Mount("/api/legacy", http.NotFoundHandler())
+	multi.NewGroup("/t/{tenant}").GET("/info", dummyHandler)
+	multi.NewGroup("/t/{id}").GET("/info", dummyHandler)
+	multi.RegisterDefault(http.MethodGet, "/api/health", dummyHandler)
+
+	want := []struct {
+		kind, route, msg string
+	}{
+		{ConflictDefault, "/api/health", "group '/api'"},
+		{ConflictGroup, "/api/v2/users", "dispatched to group '/api/v2'"},
+		{ConflictGroup, "/t/{id}/info", "dispatched to group '/t/{tenant}'"},
+		{ConflictShadowed, "", "group '/t/{id}' is never used"},
+	}
+	conflicts := multi.Audit()
+	if len(conflicts) != len(want) {
+		t.Fatalf("expected %d conflicts, got %v", len(want), conflicts)
+	}
+	for i, w := range want {
+		c := conflicts[i]
+		if c.Kind != w.kind || c.Route != w.route || !strings.Contains(c.Message, w.msg) {
+			t.Errorf("conflict %d: expected %s %q %q, got %+v", i, w.kind, w.route, w.msg, c)
+		}
+	}
+
+	tenant := New()
+	tenant.GET("/", dummyHandler)
+	preserved := NewMultiRouter()
+	preserved.WarnOnConflict(logger)
+	preserved.Group("/{tenant}", tenant, PreservePath())
+	conflicts = preserved.Audit()
+	if len(conflicts) != 1 || conflicts[0].Kind != ConflictGroup || conflicts[0].Route != "/" ||
+		!strings.Contains(conflicts[0].Message, "dispatched to no group") {
+		t.Errorf("expected undispatched route, got %v", conflicts)
+	}
+
+	root := NewMultiRouter()
+	root.RegisterDefault(http.MethodGet, "/health", dummyHandler)
+	root.NewGroup("/").GET("/", dummyHandler)
+	conflicts = root.Audit()
+	if len(conflicts) != 1 || conflicts[0].Kind != ConflictShadowed || conflicts[0].Groups[0] != "/" {
+		t.Errorf("expected shadowed default router, got %v", conflicts)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var principalContextKey = &contextKey{"principal"}
+
+// ErrNoCredentials is returned by authenticators if the request carries no
+// credentials.
+var ErrNoCredentials = errors.New("httpmux: no credentials")
+
+// Authenticator authenticates requests, e.g. by validating a JWT, and returns
+// the principal the request is made by, like a user or a service account.
+// Authenticators may implement Challenge, returning the value of the
+// WWW-Authenticate header of responses to requests failing authentication.
+type Authenticator interface {
+	Authenticate(req *http.Request) (principal any, err error)
+}
+
+// AuthenticatorFunc is an adapter to use functions as Authenticators.
+type AuthenticatorFunc func(req *http.Request) (any, error)
+
+// Authenticate calls f(req).
+func (f AuthenticatorFunc) Authenticate(req *http.Request) (any, error) {
+	return f(req)
+}
+
+// bearerAuthenticator validates bearer tokens.
+type bearerAuthenticator struct {
+	validate func(ctx context.Context, token string) (any, error)
+}
+
+// BearerAuthenticator returns an Authenticator passing the bearer token of
+// the Authorization header to the validate function, which returns the
+// principal of a valid token, e.g. the claims of a JWT:
+//
+//	auth := httpmux.BearerAuthenticator(func(ctx context.Context, token string) (any, error) {
+//		return verifier.Verify(ctx, token)
+//	})
+//
+// Requests without a bearer token fail with ErrNoCredentials.
+func BearerAuthenticator(validate func(ctx context.Context, token string) (any, error)) Authenticator {
+	return bearerAuthenticator{validate}
+}
+
+func (a bearerAuthenticator) Authenticate(req *http.Request) (any, error) {
+	token := BearerToken(req)
+	if token == "" {
+		return nil, ErrNoCredentials
+	}
+	return a.validate(req.Context(), token)
+}
+
+func (a bearerAuthenticator) Challenge() string {
+	return "Bearer"
+}
+
+// BearerToken returns the token of an Authorization header of the Bearer
+// scheme, or an empty string.
+func BearerToken(req *http.Request) string {
+	scheme, token, ok := strings.Cut(req.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// Principal returns the principal of the request returned by the
+// Authenticator of the router or of RequireAuth, or nil if the request was
+// not authenticated:
+//
+//	user, _ := httpmux.Principal(req).(*Claims)
+func Principal(req *http.Request) any {
+	return req.Context().Value(principalContextKey)
+}
+
+// authenticator holds the authenticator of a router.
+type authenticator struct {
+	auth Authenticator
+	tags []string // sorted
+}
+
+// SetAuthenticator sets the Authenticator authenticating requests to the
+// routes of the router having one of the tags, see Tags, or to all routes if
+// no tags are given:
+//
+//	router.SetAuthenticator(httpmux.BearerAuthenticator(verifyToken), "private")
+//	router.GET("/me", showProfile, httpmux.Tags("private"))
+//
+// The Authenticator is invoked after the route was matched and before its
+// middlewares and handler, which can retrieve the principal with Principal.
+// Requests failing authentication are answered with 401 Unauthorized, or the
+// status of the error if it is a StatusError, without calling the handler.
+// Tags of MultiRouter groups are only taken into account by flattened
+// routers; protect groups with RequireAuth instead. A nil Authenticator
+// disables authentication.
+func (r *Router) SetAuthenticator(auth Authenticator, tags ...string) {
+	var a *authenticator
+	if auth != nil {
+		a = &authenticator{auth: auth, tags: mergeTags(nil, tags)}
+	}
+	r.updateSettings(func(s *settings) {
+		s.authenticator = a
+	})
+}
+
+// authenticate authenticates the request if the route of the handle requires
+// it. It returns the request with the principal, or false if the request was
+// answered.
+func (a *authenticator) authenticate(w http.ResponseWriter, req *http.Request, handle http.Handler) (*http.Request, bool) {
+	if len(a.tags) > 0 {
+		tags := routeOptionsOf(handle).tags
+		if !slices.ContainsFunc(a.tags, func(tag string) bool {
+			_, found := slices.BinarySearch(tags, tag)
+			return found
+		}) {
+			return req, true
+		}
+	}
+	return authenticateRequest(a.auth, w, req)
+}
+
+// authenticateRequest returns the request with the principal returned by the
+// authenticator, or answers it and returns false if authentication fails.
+func authenticateRequest(auth Authenticator, w http.ResponseWriter, req *http.Request) (*http.Request, bool) {
+	principal, err := auth.Authenticate(req)
+	if err != nil {
+		code := http.StatusUnauthorized
+		var se StatusError
+		if errors.As(err, &se) {
+			code = se.StatusCode()
+		}
+		if c, ok := auth.(interface{ Challenge() string }); ok && code == http.StatusUnauthorized {
+			w.Header().Set("WWW-Authenticate", c.Challenge())
+		}
+		writeError(w, req, strconv.Itoa(code)+" "+strings.ToLower(http.StatusText(code)), code)
+		return req, false
+	}
+	return req.WithContext(context.WithValue(req.Context(), principalContextKey, principal)), true
+}
+
+// RequireAuth returns a middleware authenticating all requests with the
+// Authenticator like SetAuthenticator, e.g. for MultiRouter groups:
+//
+//	multi.Group("/account", account, httpmux.WithMiddleware(httpmux.RequireAuth(auth)))
+func RequireAuth(auth Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req, ok := authenticateRequest(auth, w, req); ok {
+				next.ServeHTTP(w, req)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type forbiddenError struct{}
+
+func (forbiddenError) Error() string   { return "forbidden" }
+func (forbiddenError) StatusCode() int { return http.StatusForbidden }
+
+func testBearerAuthenticator() Authenticator {
+	return BearerAuthenticator(func(_ context.Context, token string) (any, error) {
+		switch token {
+		case "alice":
+			return "user:alice", nil
+		case "mallory":
+			return nil, forbiddenError{}
+		}
+		return nil, errors.New("invalid token")
+	})
+}
+
+func TestRouterAuthenticator(t *testing.T) {
+	router := New()
+	router.SetAuthenticator(testBearerAuthenticator(), "private", "admin")
+	whoami := func(w http.ResponseWriter, req *http.Request) {
+		p, _ := Principal(req).(string)
+		w.Write([]byte(p + " " + req.PathValue("id")))
+	}
+	router.GET("/me/{id}", whoami, Tags("private"))
+	router.GET("/public/{id}", whoami, Tags("public"))
+
+	tests := []struct {
+		path, auth string
+		code       int
+		body       string
+		challenge  string
+	}{
+		{"/me/1", "Bearer alice", http.StatusOK, "user:alice 1", ""},
+		{"/me/1", "bearer  alice", http.StatusOK, "user:alice 1", ""},
+		{"/me/1", "", http.StatusUnauthorized, "401 unauthorized\n", "Bearer"},
+		{"/me/1", "Basic YWxpY2U6", http.StatusUnauthorized, "401 unauthorized\n", "Bearer"},
+		{"/me/1", "Bearer bob", http.StatusUnauthorized, "401 unauthorized\n", "Bearer"},
+		{"/me/1", "Bearer mallory", http.StatusForbidden, "403 forbidden\n", ""},
+		{"/public/2", "", http.StatusOK, " 2", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.code || w.Body.String() != tt.body || w.Header().Get("WWW-Authenticate") != tt.challenge {
+			t.Errorf("%s with %q: got %d %q %q, want %d %q %q", tt.path, tt.auth,
+				w.Code, w.Body.String(), w.Header().Get("WWW-Authenticate"), tt.code, tt.body, tt.challenge)
+		}
+	}
+
+	// Without tags, all routes are authenticated
+	router.SetAuthenticator(AuthenticatorFunc(func(*http.Request) (any, error) {
+		return nil, ErrNoCredentials
+	}))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/public/2", nil))
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != "" {
+		t.Errorf("got %d %v, want 401 without challenge", w.Code, w.Header())
+	}
+
+	router.SetAuthenticator(nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/public/2", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("got %d after removing the authenticator", w.Code)
+	}
+}
+
+func TestRequireAuth(t *testing.T) {
+	multi := NewMultiRouter()
+	account := multi.NewGroup("/account", WithMiddleware(RequireAuth(testBearerAuthenticator())))
+	account.GET("/", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(Principal(req).(string)))
+	})
+
+	for auth, code := range map[string]int{"Bearer alice": http.StatusOK, "": http.StatusUnauthorized} {
+		req := httptest.NewRequest(http.MethodGet, "/account/", nil)
+		req.Header.Set("Authorization", auth)
+		w := httptest.NewRecorder()
+		multi.ServeHTTP(w, req)
+		if w.Code != code {
+			t.Errorf("%q: got %d, want %d", auth, w.Code, code)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"context"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BalanceStrategy selects the upstream server of a request among the healthy
+// ones.
+type BalanceStrategy int
+
+const (
+	// RoundRobin sends requests to the upstream servers in turn.
+	RoundRobin BalanceStrategy = iota
+
+	// LeastConnections sends requests to the upstream server with the fewest
+	// requests in flight.
+	LeastConnections
+)
+
+// HealthCheck configures the active health checks of a Balancer.
+type HealthCheck struct {
+	// Path requested on every upstream server, e.g. "/healthz". Servers
+	// answering with a status code other than 2xx are considered unhealthy
+	// until they recover. If it is empty, no checks are made.
+	Path string
+
+	// Time between checks, 10 seconds by default
+	Interval time.Duration
+
+	// Time a check may take, 2 seconds by default
+	Timeout time.Duration
+}
+
+// BalancerOption configures a Balancer.
+type BalancerOption func(*Balancer)
+
+// WithBalanceStrategy sets how requests are distributed, RoundRobin by default.
+func WithBalanceStrategy(strategy BalanceStrategy) BalancerOption {
+	return func(b *Balancer) {
+		b.strategy = strategy
+	}
+}
+
+// WithHealthCheck enables active health checks of the upstream servers.
+func WithHealthCheck(check HealthCheck) BalancerOption {
+	return func(b *Balancer) {
+		b.check = check
+	}
+}
+
+// WithBalancerProxyOptions configures the reverse proxies of the upstream
+// servers, e.g. to rewrite headers. An error handler set by
+// WithProxyErrorHandler replaces the one taking unreachable servers out of
+// rotation.
+func WithBalancerProxyOptions(opts ...ProxyOption) BalancerOption {
+	return func(b *Balancer) {
+		b.proxyOpts = append(b.proxyOpts, opts...)
+	}
+}
+
+// Balancer is a handler distributing requests across several upstream
+// servers, so that the router can act as a minimal API gateway:
+//
+//	users := httpmux.NewBalancer([]string{"http://10.0.0.1:8080", "http://10.0.0.2:8080"},
+//		httpmux.WithBalanceStrategy(httpmux.LeastConnections),
+//		httpmux.WithHealthCheck(httpmux.HealthCheck{Path: "/healthz"}))
+//	defer users.Close()
+//
+//	multi.Mount("/users", users)
+//
+// Requests are forwarded as by MultiRouter.Proxy, appending the request
+// path to the path of the upstream URL. Servers whose health check fails, or
+// which cannot be reached, are skipped until a health check succeeds again.
+// If no server is healthy, requests are answered with 503 Service
+// Unavailable.
+type Balancer struct {
+	upstreams []*upstream
+	strategy  BalanceStrategy
+	check     HealthCheck
+	proxyOpts []ProxyOption
+	next      atomic.Uint64 // Counter for RoundRobin
+
+	stop      context.CancelFunc
+	checksRun sync.WaitGroup
+}
+
+// upstream is an upstream server of a Balancer.
+type upstream struct {
+	url      *url.URL
+	proxy    *httputil.ReverseProxy
+	healthy  atomic.Bool
+	inFlight atomic.Int64
+}
+
+// NewBalancer returns a Balancer for the upstream URLs. If health checks are
+// enabled, they start immediately; Close stops them. NewBalancer panics if
+// no URL is given or a URL is not absolute.
+func NewBalancer(targets []string, opts ...BalancerOption) *Balancer {
+	if len(targets) == 0 {
+		panic("balancer needs at least one upstream")
+	}
+
+	b := &Balancer{}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	for _, target := range targets {
+		u, err := url.Parse(target)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			panic("invalid balancer upstream '" + target + "'")
+		}
+		up := &upstream{url: u}
+		up.healthy.Store(true)
+
+		// Unreachable servers are taken out of rotation until they pass a
+		// health check
+		proxyOpts := append([]ProxyOption{WithProxyErrorHandler(func(w http.ResponseWriter, req *http.Request, err error) {
+			if b.check.Path != "" && req.Context().Err() == nil {
+				up.healthy.Store(false)
+			}
+			writeError(w, req, "502 bad gateway", http.StatusBadGateway)
+		})}, b.proxyOpts...)
+		up.proxy = newReverseProxy(u, proxyOpts)
+		b.upstreams = append(b.upstreams, up)
+	}
+
+	if b.check.Path != "" {
+		if b.check.Interval <= 0 {
+			b.check.Interval = 10 * time.Second
+		}
+		if b.check.Timeout <= 0 {
+			b.check.Timeout = 2 * time.Second
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		b.stop = cancel
+		b.checksRun.Add(1)
+		go b.runChecks(ctx)
+	}
+	return b
+}
+
+// Close stops the health checks.
+func (b *Balancer) Close() {
+	if b.stop != nil {
+		b.stop()
+		b.checksRun.Wait()
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (b *Balancer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	up := b.pick()
+	if up == nil {
+		writeError(w, req, "503 service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	up.inFlight.Add(1)
+	defer up.inFlight.Add(-1)
+	up.proxy.ServeHTTP(w, req)
+}
+
+// pick returns the upstream server for the next request, or nil if none is
+// healthy.
+func (b *Balancer) pick() *upstream {
+	switch b.strategy {
+	case LeastConnections:
+		var best *upstream
+		for _, up := range b.upstreams {
+			if up.healthy.Load() && (best == nil || up.inFlight.Load() < best.inFlight.Load()) {
+				best = up
+			}
+		}
+		return best
+
+	default:
+		n := uint64(len(b.upstreams))
+		start := b.next.Add(1) - 1
+		for i := range n {
+			if up := b.upstreams[(start+i)%n]; up.healthy.Load() {
+				return up
+			}
+		}
+		return nil
+	}
+}
+
+// runChecks checks the health of all upstream servers once per interval until
+// the context is canceled.
+func (b *Balancer) runChecks(ctx context.Context) {
+	defer b.checksRun.Done()
+	client := &http.Client{Timeout: b.check.Timeout}
+	ticker := time.NewTicker(b.check.Interval)
+	defer ticker.Stop()
+
+	for {
+		for _, up := range b.upstreams {
+			up.healthy.Store(b.checkHealth(ctx, client, up))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkHealth reports whether the upstream server answers its health check
+// with a 2xx status code.
+func (b *Balancer) checkHealth(ctx context.Context, client *http.Client, up *upstream) bool {
+	u := *up.url
+	u.Path = singleJoiningSlash(u.Path, b.check.Path)
+	u.RawPath = ""
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return false
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	res.Body.Close()
+	return res.StatusCode >= 200 && res.StatusCode < 300
+}
+
+// singleJoiningSlash joins two paths with exactly one slash between them.
+func singleJoiningSlash(a, b string) string {
+	switch aSlash, bSlash := len(a) > 0 && a[len(a)-1] == '/', len(b) > 0 && b[0] == '/'; {
+	case aSlash && bSlash:
+		return a + b[1:]
+	case !aSlash && !bSlash:
+		return a + "/" + b
+	}
+	return a + b
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestUpstream(name string, healthy *atomic.Bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" && !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(name + " " + r.URL.Path))
+	}))
+}
+
+func TestBalancerRoundRobin(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	a, b := newTestUpstream("a", &healthy), newTestUpstream("b", &healthy)
+	defer a.Close()
+	defer b.Close()
+
+	lb := NewBalancer([]string{a.URL, b.URL})
+	defer lb.Close()
+
+	var bodies []string
+	for range 4 {
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+		bodies = append(bodies, w.Body.String())
+	}
+	if bodies[0] != "a /users" || bodies[1] != "b /users" || bodies[2] != "a /users" || bodies[3] != "b /users" {
+		t.Errorf("unexpected distribution %q", bodies)
+	}
+
+	if recv := catchPanic(func() { NewBalancer(nil) }); recv == nil {
+		t.Error("expected panic without upstreams")
+	}
+	if recv := catchPanic(func() { NewBalancer([]string{"10.0.0.1:80"}) }); recv == nil {
+		t.Error("expected panic for relative upstream")
+	}
+}
+
+func TestBalancerLeastConnections(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte("slow"))
+	}))
+	defer slow.Close()
+	var healthy atomic.Bool
+	healthy.Store(true)
+	fast := newTestUpstream("fast", &healthy)
+	defer fast.Close()
+
+	lb := NewBalancer([]string{slow.URL, fast.URL}, WithBalanceStrategy(LeastConnections))
+	done := make(chan struct{})
+	go func() {
+		lb.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		close(done)
+	}()
+	for lb.upstreams[0].inFlight.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	for range 3 {
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Body.String() != "fast /" {
+			t.Errorf("expected the idle upstream, got %q", w.Body.String())
+		}
+	}
+	close(release)
+	<-done
+}
+
+func TestBalancerHealthCheck(t *testing.T) {
+	var aHealthy, bHealthy atomic.Bool
+	aHealthy.Store(true)
+	a, b := newTestUpstream("a", &aHealthy), newTestUpstream("b", &bHealthy)
+	defer a.Close()
+	defer b.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	lb := NewBalancer([]string{a.URL, b.URL, down.URL},
+		WithHealthCheck(HealthCheck{Path: "/healthz", Interval: 5 * time.Millisecond}))
+	defer lb.Close()
+
+	waitFor := func(cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatal("timed out waiting for health checks")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitFor(func() bool { return !lb.upstreams[1].healthy.Load() && !lb.upstreams[2].healthy.Load() })
+
+	for range 3 {
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/x", nil))
+		if w.Body.String() != "a /x" {
+			t.Errorf("expected the healthy upstream, got %d %q", w.Code, w.Body.String())
+		}
+	}
+
+	aHealthy.Store(false)
+	waitFor(func() bool { return !lb.upstreams[0].healthy.Load() })
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/x", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", w.Code)
+	}
+
+	bHealthy.Store(true)
+	waitFor(func() bool { return lb.upstreams[1].healthy.Load() })
+	w = httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/x", nil))
+	if w.Body.String() != "b /x" {
+		t.Errorf("expected the recovered upstream, got %d %q", w.Code, w.Body.String())
+	}
+}
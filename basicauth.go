@@ -0,0 +1,61 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+)
+
+// BasicAuth returns a middleware requiring HTTP basic authentication, e.g. to
+// protect debug and admin groups:
+//
+//	multi.Group("/admin", admin, httpmux.WithMiddleware(
+//		httpmux.BasicAuth("admin", httpmux.BasicAuthUsers(map[string]string{
+//			"alice": os.Getenv("ALICE_PASSWORD"),
+//		})),
+//	))
+//
+// The validator reports whether the credentials are valid; it should compare
+// them in constant time, like the validator returned by BasicAuthUsers.
+// Requests without valid credentials are answered with 401 Unauthorized and a
+// WWW-Authenticate header for the realm.
+func BasicAuth(realm string, validate func(user, password string) bool) func(http.Handler) http.Handler {
+	challenge := "Basic realm=" + strconv.Quote(realm) + `, charset="UTF-8"`
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			user, password, ok := req.BasicAuth()
+			if ok && validate(user, password) {
+				next.ServeHTTP(w, req)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", challenge)
+			writeError(w, req, "401 unauthorized", http.StatusUnauthorized)
+		})
+	}
+}
+
+// BasicAuthUsers returns a validator for BasicAuth accepting the users with
+// the passwords, keyed by user name. Credentials are compared in constant
+// time, regardless of whether the user exists.
+func BasicAuthUsers(users map[string]string) func(user, password string) bool {
+	credentials := make(map[[sha256.Size]byte][sha256.Size]byte, len(users))
+	for user, password := range users {
+		credentials[sha256.Sum256([]byte(user))] = sha256.Sum256([]byte(password))
+	}
+	// Compared for unknown users, so that they take as long as known ones
+	var unknown [sha256.Size]byte
+
+	return func(user, password string) bool {
+		want, found := credentials[sha256.Sum256([]byte(user))]
+		if !found {
+			want = unknown
+		}
+		got := sha256.Sum256([]byte(password))
+		return subtle.ConstantTimeCompare(got[:], want[:]) == 1 && found
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBasicAuth(t *testing.T) {
+	multi := NewMultiRouter()
+	admin := multi.NewGroup("/admin", WithMiddleware(
+		BasicAuth(`ops "team"`, BasicAuthUsers(map[string]string{"alice": "secret"})),
+	))
+	admin.GET("/stats", func(w http.ResponseWriter, _ *http.Request) {})
+
+	tests := []struct {
+		name           string
+		user, password string
+		auth           bool
+		code           int
+	}{
+		{"valid", "alice", "secret", true, http.StatusOK},
+		{"wrong password", "alice", "guess", true, http.StatusUnauthorized},
+		{"unknown user", "bob", "secret", true, http.StatusUnauthorized},
+		{"empty password", "bob", "", true, http.StatusUnauthorized},
+		{"missing", "", "", false, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/admin/stats", nil)
+		if tt.auth {
+			req.SetBasicAuth(tt.user, tt.password)
+		}
+		w := httptest.NewRecorder()
+		multi.ServeHTTP(w, req)
+		if w.Code != tt.code {
+			t.Errorf("%s: got %d, want %d", tt.name, w.Code, tt.code)
+		}
+		challenge := w.Header().Get("WWW-Authenticate")
+		if want := `Basic realm="ops \"team\"", charset="UTF-8"`; tt.code == http.StatusUnauthorized && challenge != want {
+			t.Errorf("%s: WWW-Authenticate = %q, want %q", tt.name, challenge, want)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+// Package bench measures the routing performance of httpmux for a given
+// route table and request corpus.
+//
+// Synthetic benchmarks rarely reflect the shape of a real API. Run registers
+// the application's actual routes with a fresh Router, replays a corpus of
+// requests against it and reports the time and allocations per request,
+// broken down by the class of route each request matched:
+//
+//	report, err := bench.Run(routes, requests)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Print(report)
+//
+// Handlers are no-ops, so only the cost of the router itself is measured.
+package bench
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/g-h-miles/httpmux"
+)
+
+// Route classes a request can fall into.
+const (
+	ClassStatic   = "static"
+	ClassParam    = "param"
+	ClassCatchAll = "catch-all"
+	ClassNoMatch  = "no match"
+)
+
+// Route is an entry of the route table.
+type Route struct {
+	Method string
+	Path   string
+}
+
+// Request is an entry of the request corpus.
+type Request struct {
+	Method string
+	Path   string
+}
+
+// Result holds the measurements of a single route class.
+type Result struct {
+	Class string
+
+	// Number of requests of the corpus in this class
+	Requests int
+
+	NsPerOp     int64
+	AllocsPerOp int64
+	BytesPerOp  int64
+}
+
+// Report is the outcome of Run.
+type Report struct {
+	// Number of registered routes
+	Routes int
+
+	// Results per route class, ordered by class name
+	Results []Result
+}
+
+// String returns the report formatted as a table.
+func (r *Report) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d routes\n", r.Routes)
+	tw := tabwriter.NewWriter(&sb, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "class\trequests\tns/op\tallocs/op\tB/op\t")
+	for _, res := range r.Results {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t\n",
+			res.Class, res.Requests, res.NsPerOp, res.AllocsPerOp, res.BytesPerOp)
+	}
+	tw.Flush()
+	return sb.String()
+}
+
+// Run registers routes with a new Router and benchmarks the requests of the
+// corpus against it, grouped by route class.
+// Registration errors, such as conflicting routes, are returned instead of
+// causing a panic.
+func Run(routes []Route, requests []Request) (report *Report, err error) {
+	if len(requests) == 0 {
+		return nil, errors.New("bench: empty request corpus")
+	}
+
+	router, err := newRouter(routes)
+	if err != nil {
+		return nil, err
+	}
+
+	classes := make(map[string][]*http.Request)
+	for _, r := range requests {
+		req, err := http.NewRequest(r.Method, r.Path, nil)
+		if err != nil {
+			return nil, fmt.Errorf("bench: invalid request %s %s: %w", r.Method, r.Path, err)
+		}
+		class := classify(router, r.Method, req.URL.Path)
+		classes[class] = append(classes[class], req)
+	}
+
+	report = &Report{Routes: len(routes)}
+	for class, reqs := range classes {
+		res := measure(router, reqs)
+		res.Class = class
+		report.Results = append(report.Results, res)
+	}
+	sort.Slice(report.Results, func(i, j int) bool {
+		return report.Results[i].Class < report.Results[j].Class
+	})
+	return report, nil
+}
+
+func newRouter(routes []Route) (router *httpmux.Router, err error) {
+	defer func() {
+		if rcv := recover(); rcv != nil {
+			err = fmt.Errorf("bench: %v", rcv)
+		}
+	}()
+
+	router = httpmux.New()
+	for _, r := range routes {
+		router.HandleFunc(r.Method, r.Path, noop)
+	}
+	return router, nil
+}
+
+func noop(http.ResponseWriter, *http.Request) {}
+
+func classify(router *httpmux.Router, method, path string) string {
+	e := router.Explain(method, path)
+	switch {
+	case e.Outcome != httpmux.OutcomeMatch:
+		return ClassNoMatch
+	case strings.Contains(e.Route, "...}"):
+		return ClassCatchAll
+	case strings.Contains(e.Route, "{"):
+		return ClassParam
+	default:
+		return ClassStatic
+	}
+}
+
+func measure(router http.Handler, reqs []*http.Request) Result {
+	// Redirects modify the request path, so it is restored before each run
+	paths := make([]string, len(reqs))
+	for i, req := range reqs {
+		paths[i] = req.URL.Path
+	}
+	w := new(discardWriter)
+
+	br := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for j, req := range reqs {
+				req.URL.Path = paths[j]
+				router.ServeHTTP(w, req)
+			}
+		}
+	})
+
+	n := int64(br.N) * int64(len(reqs))
+	return Result{
+		Requests:    len(reqs),
+		NsPerOp:     br.T.Nanoseconds() / n,
+		AllocsPerOp: int64(br.MemAllocs) / n,
+		BytesPerOp:  int64(br.MemBytes) / n,
+	}
+}
+
+// discardWriter is a http.ResponseWriter which drops everything written to
+// it, reusing a single header map.
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = make(http.Header)
+	}
+	return w.header
+}
+
+func (w *discardWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (w *discardWriter) WriteHeader(int) {}
@@ -0,0 +1,65 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package bench
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping benchmark run in short mode")
+	}
+
+	routes := []Route{
+		{"GET", "/"},
+		{"GET", "/users/{id}"},
+		{"GET", "/files/{path...}"},
+	}
+	requests := []Request{
+		{"GET", "/"},
+		{"GET", "/users/1"},
+		{"GET", "/users/2"},
+		{"GET", "/files/a/b"},
+		{"GET", "/nope/nope"},
+	}
+
+	report, err := Run(routes, requests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Routes != 3 {
+		t.Errorf("expected 3 routes, got %d", report.Routes)
+	}
+
+	want := map[string]int{ClassCatchAll: 1, ClassNoMatch: 1, ClassParam: 2, ClassStatic: 1}
+	if len(report.Results) != len(want) {
+		t.Fatalf("unexpected results %+v", report.Results)
+	}
+	for _, res := range report.Results {
+		if res.Requests != want[res.Class] {
+			t.Errorf("class %s: expected %d requests, got %d", res.Class, want[res.Class], res.Requests)
+		}
+		if res.NsPerOp <= 0 {
+			t.Errorf("class %s: expected positive ns/op, got %d", res.Class, res.NsPerOp)
+		}
+	}
+
+	if s := report.String(); !strings.Contains(s, "allocs/op") || !strings.Contains(s, ClassCatchAll) {
+		t.Errorf("unexpected report:\n%s", s)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	_, err := Run([]Route{{"GET", "/a/{x}"}, {"GET", "/a/{y}"}}, []Request{{"GET", "/a/1"}})
+	if err == nil || !strings.Contains(err.Error(), "conflicts") {
+		t.Errorf("expected conflict error, got %v", err)
+	}
+
+	if _, err := Run([]Route{{"GET", "/"}}, nil); err == nil {
+		t.Error("expected error for empty corpus")
+	}
+}
@@ -0,0 +1,326 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FieldError is a problem with a single request value found by Bind.
+type FieldError struct {
+	// Where the value came from: "path", "query", "header", "form" or "body",
+	// empty for validation errors
+	Source string `json:"source,omitempty"`
+
+	// Name of the value in the request, e.g. the query parameter, empty for
+	// body errors not related to a field
+	Name string `json:"name,omitempty"`
+
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	name := strings.TrimSpace(e.Source + " " + e.Name)
+	if name == "" {
+		return e.Message
+	}
+	return name + ": " + e.Message
+}
+
+// BindError is returned by Bind if request values cannot be bound. It is a
+// StatusError with status 400 Bad Request.
+type BindError struct {
+	Errors []FieldError
+}
+
+func (e *BindError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Error()
+	}
+	return "invalid request: " + strings.Join(msgs, "; ")
+}
+
+// StatusCode returns 400.
+func (e *BindError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// Bind populates the struct dst points to from the request. The body is
+// decoded first: JSON bodies into the whole struct, following its json tags,
+// and form bodies into the fields tagged `form`. Then the fields tagged
+// `path`, `query` and `header` are set from the path values, the query
+// parameters and the request headers, overriding values of the body:
+//
+//	type UpdateUser struct {
+//		ID     int64    `path:"id"`
+//		DryRun bool     `query:"dry_run"`
+//		Token  string   `header:"X-Token"`
+//		Name   string   `json:"name"`
+//		Tags   []string `json:"tags"`
+//	}
+//
+//	var in UpdateUser
+//	if err := httpmux.Bind(req, &in); err != nil {
+//		httpmux.RespondError(w, req, err)
+//		return
+//	}
+//
+// Fields may be strings, booleans, numbers, time.Duration, time.Time in RFC
+// 3339 format, types implementing encoding.TextUnmarshaler, pointers to them,
+// or slices of them, which collect repeated query parameters, headers and
+// form values. Missing values leave fields unchanged. All values which cannot
+// be converted are reported together in a *BindError. Bind panics if a
+// tagged field has a type of another kind, whether or not the request carries
+// a value for it.
+//
+// If the router serving the request has a validator set by SetValidator, the
+// bound struct is validated afterwards, returning a *ValidationError if it is
+// invalid. RespondError answers the request with either error.
+func Bind(req *http.Request, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		panic("Bind requires a pointer to a struct, got " + v.Type().String())
+	}
+	v = v.Elem()
+	checkBindType(v.Type())
+
+	var errs []FieldError
+	isForm := false
+	if req.Body != nil && req.Body != http.NoBody {
+		ct, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+		switch {
+		case ct == "application/x-www-form-urlencoded" || ct == "multipart/form-data":
+			isForm = true
+			var err error
+			if ct == "multipart/form-data" {
+				err = req.ParseMultipartForm(32 << 20)
+			} else {
+				err = req.ParseForm()
+			}
+			if err != nil {
+				errs = append(errs, FieldError{Source: "form", Message: err.Error()})
+			}
+		case ct == "application/json" || strings.HasSuffix(ct, "+json") || ct == "":
+			if err := json.NewDecoder(req.Body).Decode(dst); err != nil && !errors.Is(err, io.EOF) {
+				fe := FieldError{Source: "body", Message: err.Error()}
+				var te *json.UnmarshalTypeError
+				if errors.As(err, &te) {
+					fe.Name, fe.Message = te.Field, "expected "+te.Type.String()
+				}
+				errs = append(errs, fe)
+			}
+		default:
+			errs = append(errs, FieldError{Source: "body", Message: "unsupported content type " + ct})
+		}
+	}
+
+	var query map[string][]string
+	errs = bindFields(v, func(source, name string) ([]string, bool) {
+		switch source {
+		case "path":
+			if value := req.PathValue(name); value != "" {
+				return []string{value}, true
+			}
+		case "query":
+			if query == nil {
+				query = req.URL.Query()
+			}
+			values, ok := query[name]
+			return values, ok
+		case "header":
+			values := req.Header.Values(name)
+			return values, len(values) > 0
+		case "form":
+			if isForm {
+				values, ok := req.PostForm[name]
+				if !ok && req.MultipartForm != nil {
+					values, ok = req.MultipartForm.Value[name]
+				}
+				return values, ok
+			}
+		}
+		return nil, false
+	}, errs)
+
+	if len(errs) > 0 {
+		return &BindError{Errors: errs}
+	}
+	return validateRequestValue(req, dst)
+}
+
+var bindSources = []string{"form", "path", "query", "header"}
+
+// bindFields sets the tagged fields of the struct, including those of
+// embedded structs, to the values returned by lookup.
+func bindFields(v reflect.Value, lookup func(source, name string) ([]string, bool), errs []FieldError) []FieldError {
+	t := v.Type()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			errs = bindFields(v.Field(i), lookup, errs)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		for _, source := range bindSources {
+			name, ok := f.Tag.Lookup(source)
+			if !ok {
+				continue
+			}
+			values, ok := lookup(source, name)
+			if !ok {
+				continue
+			}
+			if err := setField(v.Field(i), values); err != nil {
+				errs = append(errs, FieldError{Source: source, Name: name, Message: err.Error()})
+			}
+		}
+	}
+	return errs
+}
+
+// boundTypes holds the struct types checked by checkBindType.
+var boundTypes sync.Map
+
+// checkBindType panics if a tagged field of the struct type cannot be set by
+// Bind, so that unsupported types fail regardless of the request values.
+func checkBindType(t reflect.Type) {
+	if _, ok := boundTypes.Load(t); ok {
+		return
+	}
+	checkBindFields(t)
+	boundTypes.Store(t, struct{}{})
+}
+
+func checkBindFields(t reflect.Type) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			checkBindFields(f.Type)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		for _, source := range bindSources {
+			if _, ok := f.Tag.Lookup(source); ok && !bindable(f.Type) {
+				panic("Bind cannot set fields of type " + f.Type.String() + ", field " + t.String() + "." + f.Name)
+			}
+		}
+	}
+}
+
+// bindable reports whether setField can set fields of the type.
+func bindable(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice && !reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		t = t.Elem()
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) || t == durationType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// setField sets the field to the values, converted to its type.
+func setField(field reflect.Value, values []string) error {
+	if len(values) == 0 {
+		return nil
+	}
+	t := field.Type()
+
+	if t.Kind() == reflect.Slice && !reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		s := reflect.MakeSlice(t, len(values), len(values))
+		for i, value := range values {
+			if err := setValue(s.Index(i), value); err != nil {
+				return err
+			}
+		}
+		field.Set(s)
+		return nil
+	}
+	return setValue(field, values[0])
+}
+
+func setValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Pointer {
+		p := reflect.New(v.Type().Elem())
+		if err := setValue(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return errors.New("invalid duration '" + s + "'")
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.New("invalid boolean '" + s + "'")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("invalid integer '" + s + "'")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("invalid unsigned integer '" + s + "'")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return errors.New("invalid number '" + s + "'")
+		}
+		v.SetFloat(f)
+	default:
+		// Rejected by checkBindType
+		panic("Bind cannot set fields of type " + v.Type().String())
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindPage struct {
+	Page  int  `query:"page"`
+	Limit *int `query:"limit"`
+}
+
+type bindInput struct {
+	bindPage
+	ID      int64         `path:"id"`
+	Tags    []string      `query:"tag"`
+	Timeout time.Duration `query:"timeout"`
+	Since   time.Time     `query:"since"`
+	Token   string        `header:"X-Token"`
+	Name    string        `json:"name" form:"name"`
+	Score   float64       `json:"score"`
+}
+
+func TestBind(t *testing.T) {
+	var in bindInput
+	router := New()
+	router.PUT("/users/{id}", func(w http.ResponseWriter, req *http.Request) {
+		if err := Bind(req, &in); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodPut, "/users/42?page=2&limit=10&tag=a&tag=b&timeout=1m&since=2024-01-02T03:04:05Z", strings.NewReader(`{"name":"ann","score":1.5}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Token", "secret")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	limit := 10
+	want := bindInput{
+		bindPage: bindPage{Page: 2, Limit: &limit},
+		ID:       42,
+		Tags:     []string{"a", "b"},
+		Timeout:  time.Minute,
+		Since:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Token:    "secret",
+		Name:     "ann",
+		Score:    1.5,
+	}
+	if !reflect.DeepEqual(in, want) {
+		t.Errorf("unexpected binding:\n%+v\nwant:\n%+v", in, want)
+	}
+}
+
+func TestBindForm(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/?page=3", strings.NewReader("name=bo&page=9"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var in bindInput
+	if err := Bind(req, &in); err != nil {
+		t.Fatal(err)
+	}
+	if in.Name != "bo" || in.Page != 3 {
+		t.Errorf("unexpected binding %+v", in)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/?page=x&timeout=soon", strings.NewReader(`{"score":"high"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	var in bindInput
+	err := Bind(req, &in)
+	var be *BindError
+	if !errors.As(err, &be) {
+		t.Fatalf("expected a *BindError, got %v", err)
+	}
+	want := []FieldError{
+		{Source: "body", Name: "score", Message: "expected float64"},
+		{Source: "query", Name: "page", Message: "invalid integer 'x'"},
+		{Source: "query", Name: "timeout", Message: "invalid duration 'soon'"},
+	}
+	if !reflect.DeepEqual(be.Errors, want) {
+		t.Errorf("unexpected errors %+v", be.Errors)
+	}
+	if be.StatusCode() != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", be.StatusCode())
+	}
+	if msg := err.Error(); msg != "invalid request: body score: expected float64; query page: invalid integer 'x'; query timeout: invalid duration 'soon'" {
+		t.Errorf("unexpected message %q", msg)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`<user/>`))
+	req.Header.Set("Content-Type", "application/xml")
+	if err := Bind(req, &in); err == nil || !strings.Contains(err.Error(), "unsupported content type application/xml") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestBindUnsupportedType(t *testing.T) {
+	type input struct {
+		Page  int        `query:"page"`
+		C     complex64  `query:"c"`
+		Notes complex128 // not bound
+	}
+	for _, target := range []string{"/", "/?c=1"} {
+		var in input
+		recv := catchPanic(func() { Bind(httptest.NewRequest(http.MethodGet, target, nil), &in) })
+		if msg, _ := recv.(string); !strings.Contains(msg, "complex64") {
+			t.Errorf("%s: expected panic for the complex64 field, got %v", target, recv)
+		}
+	}
+
+	type supported struct {
+		IDs   []*int          `query:"id"`
+		Since **time.Duration `query:"since"`
+		Notes complex128
+	}
+	var in supported
+	if err := Bind(httptest.NewRequest(http.MethodGet, "/?id=1&id=2&since=1m", nil), &in); err != nil {
+		t.Fatal(err)
+	}
+	if len(in.IDs) != 2 || *in.IDs[1] != 2 || **in.Since != time.Minute {
+		t.Errorf("unexpected values %+v", in)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Breaker is a circuit breaker consulted by the router for every matched
+// route, see SetBreaker. Routes are identified by the method and pattern,
+// like "GET /users/{id}". Implementations must be safe for concurrent use.
+type Breaker interface {
+	// Allow reports whether a request to the route may be served.
+	Allow(route string) bool
+
+	// Record records the result of a request to the route allowed by Allow.
+	// Requests fail if they are answered with a 5xx status or panic.
+	Record(route string, success bool)
+}
+
+// SetBreaker sets the Breaker consulted for requests to the routes of the
+// router, so that routes of failing dependencies shed load instead of piling
+// up requests:
+//
+//	router.SetBreaker(httpmux.NewCircuitBreaker(5, 30*time.Second))
+//
+// Requests the Breaker does not allow are answered with 503 Service
+// Unavailable without calling the handler. A nil Breaker disables circuit
+// breaking.
+func (r *Router) SetBreaker(b Breaker) {
+	r.updateSettings(func(s *settings) {
+		s.breaker = b
+	})
+}
+
+// recordResult records the result of the request answered through ww, which
+// failed if it panicked.
+func recordResult(b Breaker, route string, ww WrappedWriter) {
+	if p := recover(); p != nil {
+		b.Record(route, false)
+		panic(p)
+	}
+	b.Record(route, ww.Status() < http.StatusInternalServerError)
+}
+
+// CircuitBreaker is an in-memory Breaker. The circuit of a route opens after
+// a number of consecutive failed requests, denying requests for a cooldown
+// period. Then a single trial request is allowed, whose success closes the
+// circuit, while its failure opens it for another cooldown period.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+type circuit struct {
+	failures int
+	openedAt time.Time
+	trial    bool // Whether a trial request is in flight
+}
+
+// NewCircuitBreaker returns a CircuitBreaker opening circuits after threshold
+// consecutive failures for the cooldown period. It panics if threshold is not
+// positive.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		panic("circuit breaker threshold must be positive")
+	}
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		circuits:  make(map[string]*circuit),
+	}
+}
+
+// Allow reports whether the circuit of the route is closed, or allows a trial
+// request once it was open for the cooldown period.
+func (b *CircuitBreaker) Allow(route string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuits[route]
+	if c == nil || c.failures < b.threshold {
+		return true
+	}
+	if c.trial || b.now().Sub(c.openedAt) < b.cooldown {
+		return false
+	}
+	c.trial = true
+	return true
+}
+
+// Record records the result of a request, closing the circuit of the route
+// on success.
+func (b *CircuitBreaker) Record(route string, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		delete(b.circuits, route)
+		return
+	}
+	c := b.circuits[route]
+	if c == nil {
+		c = new(circuit)
+		b.circuits[route] = c
+	}
+	c.failures++
+	c.trial = false
+	if c.failures >= b.threshold {
+		c.openedAt = b.now()
+	}
+}
+
+// Open reports whether the circuit of the route is open, i.e. requests are
+// denied.
+func (b *CircuitBreaker) Open(route string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuits[route]
+	return c != nil && c.failures >= b.threshold
+}
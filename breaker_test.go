@@ -0,0 +1,88 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(1000, 0)
+	breaker := NewCircuitBreaker(2, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	router := New()
+	router.SetBreaker(breaker)
+	router.PanicHandler = func(w http.ResponseWriter, _ *http.Request, _ any) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	status := http.StatusBadGateway
+	router.GET("/users/{id}", func(w http.ResponseWriter, _ *http.Request) {
+		if status == 0 {
+			panic("boom")
+		}
+		w.WriteHeader(status)
+	})
+	router.GET("/ok", func(w http.ResponseWriter, _ *http.Request) {})
+
+	serve := func(path string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+
+	const route = "GET /users/{id}"
+	if code := serve("/users/1"); code != http.StatusBadGateway || breaker.Open(route) {
+		t.Fatalf("first failure: got %d, open %v", code, breaker.Open(route))
+	}
+	status = 0
+	if code := serve("/users/2"); code != http.StatusInternalServerError || !breaker.Open(route) {
+		t.Fatalf("panic: got %d, open %v", code, breaker.Open(route))
+	}
+	if code := serve("/users/3"); code != http.StatusServiceUnavailable {
+		t.Errorf("open circuit: got %d, want 503", code)
+	}
+	if code := serve("/ok"); code != http.StatusOK {
+		t.Errorf("other route: got %d, want 200", code)
+	}
+
+	// A failed trial request opens the circuit again
+	now = now.Add(time.Minute)
+	status = http.StatusServiceUnavailable
+	if code := serve("/users/4"); code != http.StatusServiceUnavailable || !breaker.Open(route) {
+		t.Errorf("failed trial: got %d, open %v", code, breaker.Open(route))
+	}
+	if code := serve("/users/5"); code != http.StatusServiceUnavailable {
+		t.Errorf("reopened circuit: got %d, want 503", code)
+	}
+
+	// A successful trial request closes it
+	now = now.Add(time.Minute)
+	status = http.StatusNotFound
+	if code := serve("/users/6"); code != http.StatusNotFound || breaker.Open(route) {
+		t.Errorf("successful trial: got %d, open %v", code, breaker.Open(route))
+	}
+}
+
+func TestCircuitBreakerTrial(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := NewCircuitBreaker(1, time.Second)
+	b.now = func() time.Time { return now }
+
+	b.Record("r", false)
+	if b.Allow("r") {
+		t.Error("open circuit allowed a request")
+	}
+	now = now.Add(time.Second)
+	if !b.Allow("r") {
+		t.Error("trial request denied")
+	}
+	if b.Allow("r") {
+		t.Error("second trial request allowed while the first is in flight")
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Stickiness selects the value clients are told apart by when splitting
+// traffic, so that each client is served the same variant consistently.
+// The header takes precedence over the cookie. Clients without the cookie
+// are assigned a random identifier in it. Without header or cookie, requests
+// are assigned randomly.
+type Stickiness struct {
+	// Header identifying the client, e.g. "X-User-ID"
+	Header string
+
+	// Cookie identifying the client, created if missing
+	Cookie string
+}
+
+// StickyCookie identifies clients by the cookie with the name.
+func StickyCookie(name string) Stickiness {
+	return Stickiness{Cookie: name}
+}
+
+// StickyHeader identifies clients by the header with the name.
+func StickyHeader(name string) Stickiness {
+	return Stickiness{Header: name}
+}
+
+// stickyCookieMaxAge is the lifetime of cookies created for Stickiness.
+const stickyCookieMaxAge = 365 * 24 * time.Hour
+
+// key returns the value identifying the client of the request, creating the
+// cookie if it is missing, or a random value.
+func (s Stickiness) key(w http.ResponseWriter, req *http.Request) string {
+	if s.Header != "" {
+		if v := req.Header.Get(s.Header); v != "" {
+			return v
+		}
+	}
+	if s.Cookie == "" {
+		return randomID()
+	}
+	if c, err := req.Cookie(s.Cookie); err == nil && c.Value != "" {
+		return c.Value
+	}
+
+	id := randomID()
+	http.SetCookie(w, &http.Cookie{
+		Name:     s.Cookie,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   int(stickyCookieMaxAge.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	// Later assignments of the request see the cookie as well
+	req.AddCookie(&http.Cookie{Name: s.Cookie, Value: id})
+	return id
+}
+
+func randomID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// stickyFraction maps the key consistently to a value in [0, 1).
+func stickyFraction(key string) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return float64(h.Sum64()%1e6) / 1e6
+}
+
+// Canary returns a handler serving the given percentage of clients with the
+// canary handler and the others with the stable one, usable for single routes
+// or, mounted in a MultiRouter, for whole routers:
+//
+//	router.Handle(http.MethodGet, "/search",
+//		httpmux.Canary(search, searchV2, 5, httpmux.StickyCookie("canary")))
+//	multi.Mount("/api", httpmux.Canary(api, apiNext, 10, httpmux.StickyHeader("X-User-ID")))
+//
+// Clients are assigned by a hash of the value selected by the stickiness, so
+// the same clients keep getting the canary, and raising the percentage only
+// adds clients to it. The response carries a Vary header for a sticky header.
+// Canary panics if the percentage is not between 0 and 100.
+func Canary(stable, canary http.Handler, percent float64, stickiness Stickiness) http.Handler {
+	if math.IsNaN(percent) || percent < 0 || percent > 100 {
+		panic("canary percentage must be between 0 and 100, got " + strconv.FormatFloat(percent, 'g', -1, 64))
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if stickiness.Header != "" {
+			w.Header().Add("Vary", stickiness.Header)
+		}
+		if stickyFraction(stickiness.key(w, req))*100 < percent {
+			canary.ServeHTTP(w, req)
+		} else {
+			stable.ServeHTTP(w, req)
+		}
+	})
+}
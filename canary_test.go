@@ -0,0 +1,82 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestCanary(t *testing.T) {
+	variant := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Write([]byte(name))
+		})
+	}
+	stable, next := variant("stable"), variant("canary")
+
+	// Clients are split by the percentage and keep their variant
+	h := Canary(stable, next, 25, StickyHeader("X-User-ID"))
+	canaries := 0
+	for i := range 2000 {
+		user := strconv.Itoa(i)
+		var got string
+		for range 2 {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-User-ID", user)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if got != "" && w.Body.String() != got {
+				t.Fatalf("user %s switched from %s to %s", user, got, w.Body.String())
+			}
+			got = w.Body.String()
+			if w.Header().Get("Vary") != "X-User-ID" {
+				t.Fatalf("Vary = %q", w.Header().Get("Vary"))
+			}
+		}
+		if got == "canary" {
+			canaries++
+		}
+	}
+	if canaries < 400 || canaries > 600 {
+		t.Errorf("%d of 2000 users got the canary, want about 500", canaries)
+	}
+
+	// Clients without the cookie get one
+	h = Canary(stable, next, 50, StickyCookie("variant"))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "variant" || cookies[0].Value == "" {
+		t.Fatalf("got cookies %v", cookies)
+	}
+	first := w.Body.String()
+	for range 10 {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(cookies[0])
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Body.String() != first || len(w.Result().Cookies()) != 0 {
+			t.Errorf("got %s with cookies %v, want %s without", w.Body.String(), w.Result().Cookies(), first)
+		}
+	}
+
+	for percent, want := range map[float64]string{0: "stable", 100: "canary"} {
+		w := httptest.NewRecorder()
+		Canary(stable, next, percent, Stickiness{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Body.String() != want {
+			t.Errorf("%v%%: got %s, want %s", percent, w.Body.String(), want)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for an invalid percentage")
+		}
+	}()
+	Canary(stable, next, 101, Stickiness{})
+}
@@ -0,0 +1,157 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// GenerateClient writes the Go source of package pkg with an HTTP client for
+// the routes named by the Name option, e.g. to call an internal service with
+// a client kept in lockstep with its router:
+//
+//	var src bytes.Buffer
+//	err := httpmux.GenerateClient(&src, "userclient", users.NewRouter().Routes())
+//
+// The generated Client has a method for each named route, taking the path
+// parameters as arguments, and a request body with its content type for
+// methods other than GET, HEAD, DELETE and OPTIONS. A route named
+// "user.update" for PUT /users/{id} yields
+//
+//	func (c *Client) UserUpdate(ctx context.Context, id string, contentType string, body io.Reader) (*http.Response, error)
+//
+// Methods of names shared by routes of several methods end with the method,
+// like SignupGet and SignupPost. The methods return the response of the
+// Client's HTTPClient, which the caller must close; responses with error
+// status codes are not turned into errors. WEBSOCKET routes are skipped.
+// GenerateClient returns an error if names map to the same method, see
+// GenerateURLs.
+func GenerateClient(w io.Writer, pkg string, routes []Route) error {
+	if !token.IsIdentifier(pkg) {
+		return errors.New("httpmux: invalid package name '" + pkg + "'")
+	}
+
+	byName := make(map[string][]Route)
+	for _, route := range routes {
+		if route.Name != "" && route.Method != methodWebSocket {
+			byName[route.Name] = append(byName[route.Name], route)
+		}
+	}
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var body bytes.Buffer
+	var usesURL, usesHelper bool
+	methods := make(map[string]string)
+	for _, name := range names {
+		named := byName[name]
+		sort.Slice(named, func(i, j int) bool { return named[i].Method < named[j].Method })
+		for _, route := range named {
+			fn := goIdentifier(name, true)
+			if len(named) > 1 {
+				fn += goIdentifier(strings.ToLower(route.Method), true)
+			}
+			where := route.Method + " " + route.Path
+			if other, ok := methods[fn]; ok {
+				return fmt.Errorf("httpmux: routes %s and %s both generate Client.%s", other, where, fn)
+			}
+			methods[fn] = where
+
+			args, expr, err := urlExpr(route.Path, "clientEscapePath")
+			if err != nil {
+				return fmt.Errorf("httpmux: route '%s': %w", name, err)
+			}
+			usesURL = usesURL || len(args) > 0
+			usesHelper = usesHelper || strings.Contains(expr, "clientEscapePath(")
+
+			args = append([]string{"ctx context.Context"}, args...)
+			reqBody := "nil"
+			if hasRequestBody(route.Method) {
+				args = append(args, "contentType string", "body io.Reader")
+				reqBody = "body"
+			}
+			fmt.Fprintf(&body, "\n// %s calls the route %q, %s %s.\n", fn, name, route.Method, route.Path)
+			fmt.Fprintf(&body, "func (c *Client) %s(%s) (*http.Response, error) {\n", fn, strings.Join(args, ", "))
+			if reqBody == "body" {
+				fmt.Fprintf(&body, "\treturn c.do(ctx, %q, %s, contentType, body)\n}\n", route.Method, expr)
+			} else {
+				fmt.Fprintf(&body, "\treturn c.do(ctx, %q, %s, \"\", nil)\n}\n", route.Method, expr)
+			}
+		}
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by httpmux.GenerateClient. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg)
+	src.WriteString("\t\"context\"\n\t\"io\"\n\t\"net/http\"\n")
+	if usesURL {
+		src.WriteString("\t\"net/url\"\n")
+	}
+	src.WriteString("\t\"strings\"\n)\n")
+	src.WriteString(`
+// Client calls the routes of the service at BaseURL.
+type Client struct {
+	// URL of the service without a trailing slash, e.g. "http://users:8080"
+	BaseURL string
+
+	// Client sending the requests, http.DefaultClient if nil
+	HTTPClient *http.Client
+}
+
+func (c *Client) do(ctx context.Context, method, path, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.BaseURL, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+`)
+	body.WriteTo(&src)
+	if usesHelper {
+		src.WriteString(`
+// clientEscapePath escapes the segments of a catch-all parameter.
+func clientEscapePath(s string) string {
+	segments := strings.Split(s, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+`)
+	}
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(formatted)
+	return err
+}
+
+// hasRequestBody reports whether requests of the method usually have a body.
+func hasRequestBody(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete, http.MethodOptions:
+		return false
+	}
+	return true
+}
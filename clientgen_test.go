@@ -0,0 +1,61 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGenerateClient(t *testing.T) {
+	ok := func(w http.ResponseWriter, _ *http.Request) {}
+
+	router := New()
+	router.GET("/users/{id}", ok, Name("user.show"))
+	router.PUT("/users/{id}", ok, Name("user.update"))
+	router.GET("/signup", ok, Name("signup"))
+	router.POST("/signup", ok, Name("signup"))
+	router.GET("/files/{path...}", ok, Name("file"))
+	router.WEBSOCKET("/chat", ok)
+	router.GET("/healthz", ok)
+
+	var b bytes.Buffer
+	if err := GenerateClient(&b, "userclient", router.Routes()); err != nil {
+		t.Fatal(err)
+	}
+	src := b.String()
+
+	for _, want := range []string{
+		"// Code generated by httpmux.GenerateClient. DO NOT EDIT.\n\npackage userclient\n",
+		"\t\"net/url\"\n",
+		"type Client struct {",
+		"func (c *Client) File(ctx context.Context, path string) (*http.Response, error) {\n\treturn c.do(ctx, \"GET\", \"/files/\"+clientEscapePath(path), \"\", nil)\n}",
+		"func (c *Client) SignupGet(ctx context.Context) (*http.Response, error) {",
+		"func (c *Client) SignupPost(ctx context.Context, contentType string, body io.Reader) (*http.Response, error) {\n\treturn c.do(ctx, \"POST\", \"/signup\", contentType, body)\n}",
+		"// UserShow calls the route \"user.show\", GET /users/{id}.\nfunc (c *Client) UserShow(ctx context.Context, id string) (*http.Response, error) {",
+		"func (c *Client) UserUpdate(ctx context.Context, id string, contentType string, body io.Reader) (*http.Response, error) {\n\treturn c.do(ctx, \"PUT\", \"/users/\"+url.PathEscape(id), contentType, body)\n}",
+		"func clientEscapePath(s string) string {",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated client lacks %q:\n%s", want, src)
+		}
+	}
+	if strings.Contains(src, "Healthz") || strings.Contains(src, "chat") {
+		t.Errorf("generated client has methods for unnamed routes:\n%s", src)
+	}
+}
+
+func TestGenerateClientConflict(t *testing.T) {
+	routes := []Route{
+		{Method: http.MethodGet, Path: "/a", Name: "user.show"},
+		{Method: http.MethodGet, Path: "/b", Name: "user_show"},
+	}
+	err := GenerateClient(&bytes.Buffer{}, "client", routes)
+	if err == nil || !strings.Contains(err.Error(), "both generate Client.UserShow") {
+		t.Errorf("got error %v", err)
+	}
+}
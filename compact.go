@@ -0,0 +1,73 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import "unique"
+
+// Freeze signals that all routes are registered and compacts the route trees
+// for faster lookups: the children of each node are moved into one contiguous
+// block of memory, which improves cache locality while walking the tree. Path
+// segments and patterns are interned, so that repeated strings are stored
+// only once, even across routers.
+//
+// Freeze copies the whole trees, so it is meant to be called once, after
+// registering the routes and before serving. Routes can still be registered
+// afterwards; the trees are then served as they are until Freeze is called
+// again.
+func (r *Router) Freeze() {
+	r.mu.Lock()
+	if trees := r.trees.Load(); trees != nil && !trees.compacted {
+		r.trees.Store(trees.compact())
+	}
+	r.mu.Unlock()
+}
+
+// compact returns a compacted copy of the trees.
+func (t *methodTrees) compact() *methodTrees {
+	c := t.clone()
+	for method, root := range t.all() {
+		root := *root
+		root.compact()
+		c.set(method, &root)
+	}
+	c.compacted = true
+	return c
+}
+
+// compact interns the strings of all nodes and reallocates the children of
+// every node contiguously.
+// Only n itself is modified, all nodes below it are replaced by copies, so n
+// can be a copy of the root of a tree in use.
+func (n *node) compact() {
+	// Identical segments and patterns, e.g. of generated per-tenant routes,
+	// share their memory. This also releases the registered pattern strings
+	// the segments were sliced from.
+	n.path = intern(n.path)
+	n.indices = intern(n.indices)
+	n.fullPath = intern(n.fullPath)
+
+	if len(n.children) == 0 {
+		return
+	}
+
+	block := make([]node, len(n.children))
+	children := make([]*node, len(n.children))
+	for i, child := range n.children {
+		block[i] = *child
+		children[i] = &block[i]
+	}
+	n.children = children
+	for _, child := range n.children {
+		child.compact()
+	}
+}
+
+// intern returns the canonical copy of s.
+func intern(s string) string {
+	if s == "" {
+		return s
+	}
+	return unique.Make(s).Value()
+}
@@ -0,0 +1,257 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"unsafe"
+)
+
+func TestTreeCompact(t *testing.T) {
+	routes := []string{
+		"/hi",
+		"/contact",
+		"/co",
+		"/c",
+		"/a",
+		"/ab",
+		"/doc/",
+		"/doc/go_faq.html",
+		"/doc/go1.html",
+		"/α",
+		"/β",
+		"/search/{query}",
+		"/cmd/{tool}/{sub}",
+		"/cmd/{tool}/",
+		"/src/{filepath...}",
+		"/info/{user}/public",
+		"/info/{user}/project/{project}",
+	}
+	tree := &node{}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(route))
+	}
+
+	requests := testRequests{
+		{"/a", false, "/a"},
+		{"/", true, ""},
+		{"/hi", false, "/hi"},
+		{"/contact", false, "/contact"},
+		{"/co", false, "/co"},
+		{"/con", true, ""},
+		{"/cona", true, ""},
+		{"/no", true, ""},
+		{"/ab", false, "/ab"},
+		{"/α", false, "/α"},
+		{"/β", false, "/β"},
+		{"/cmd/test/", false, "/cmd/{tool}/"},
+		{"/cmd/test/3", false, "/cmd/{tool}/{sub}"},
+		{"/src/some/file.png", false, "/src/{filepath...}"},
+		{"/search/someth!ng+in+ünìcodé", false, "/search/{query}"},
+		{"/info/gordon/project/go", false, "/info/{user}/project/{project}"},
+	}
+
+	checkRequests(t, tree, requests)
+	tree.compact()
+	checkRequests(t, tree, requests)
+	checkPriorities(t, tree)
+
+	// Children are stored contiguously
+	for i := 1; i < len(tree.children); i++ {
+		prev := uintptr(unsafe.Pointer(tree.children[i-1]))
+		if uintptr(unsafe.Pointer(tree.children[i]))-prev != unsafe.Sizeof(node{}) {
+			t.Fatalf("children of the root are not contiguous")
+		}
+	}
+
+	// The compacted tree accepts new routes
+	tree.addRoute("/cona", fakeHandler("/cona"))
+	checkRequests(t, tree, testRequests{
+		{"/cona", false, "/cona"},
+		{"/contact", false, "/contact"},
+	})
+}
+
+func TestRouterFreezeGithubAPI(t *testing.T) {
+	router := New()
+	for _, route := range githubAPIStd {
+		router.HandleFunc(route.method, route.path, httpRouterHandle)
+	}
+
+	var count func(n *node) int
+	count = func(n *node) int {
+		c := 1
+		for _, child := range n.children {
+			c += count(child)
+		}
+		return c
+	}
+	before := count(router.trees.Load().get(http.MethodGet))
+	router.Freeze()
+	tree := router.trees.Load().get(http.MethodGet)
+	if after := count(tree); after != before {
+		t.Errorf("compaction changed the number of nodes from %d to %d", before, after)
+	}
+
+	for _, route := range githubAPIStd {
+		req := httptest.NewRequest(route.method, route.path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || req.Pattern != route.path {
+			t.Errorf("%s %s: got %d, pattern %q", route.method, route.path, w.Code, req.Pattern)
+		}
+	}
+}
+
+func TestRouterFreeze(t *testing.T) {
+	router := New()
+	router.GET("/user/{name}", func(_ http.ResponseWriter, _ *http.Request) {})
+
+	r, _ := http.NewRequest(http.MethodGet, "/user/gopher", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	if router.trees.Load().compacted {
+		t.Fatal("expected requests not to compact the trees")
+	}
+	router.Freeze()
+	if !router.trees.Load().compacted {
+		t.Fatal("expected Freeze to compact the trees")
+	}
+
+	router.GET("/user/{name}/about", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	if router.trees.Load().compacted {
+		t.Fatal("expected registration to reset the compaction state")
+	}
+
+	w := httptest.NewRecorder()
+	r, _ = http.NewRequest(http.MethodGet, "/user/gopher/about", nil)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusTeapot {
+		t.Errorf("unexpected status %d", w.Code)
+	}
+}
+
+func TestTreeAddRouteCopyOnWrite(t *testing.T) {
+	routes := []string{
+		"/hi",
+		"/contact",
+		"/co",
+		"/cmd/{tool}/{sub}",
+		"/src/{filepath...}",
+	}
+	tree := &node{}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(route))
+	}
+	old := *tree
+	checkRequests(t, &old, testRequests{{"/hi", false, "/hi"}})
+
+	for _, route := range []string{"/cmd/{tool}/{sub}/x", "/cmx", "/ha", "/c"} {
+		c := old
+		c.addRoute(route, fakeHandler(route))
+		checkRequests(t, &c, testRequests{{route, false, route}})
+
+		// The original tree is not modified
+		checkRequests(t, &old, testRequests{
+			{"/hi", false, "/hi"},
+			{"/contact", false, "/contact"},
+			{"/cmd/test/3", false, "/cmd/{tool}/{sub}"},
+			{route, true, ""},
+		})
+		checkPriorities(t, &old)
+	}
+}
+
+func TestRouterConcurrentRegistration(t *testing.T) {
+	router := New()
+	router.GET("/", func(_ http.ResponseWriter, _ *http.Request) {})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			router.GET(fmt.Sprintf("/route/%d/{id}", i), func(_ http.ResponseWriter, _ *http.Request) {})
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest(http.MethodGet, "/", nil)
+		router.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d", w.Code)
+		}
+	}
+	<-done
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest(http.MethodGet, "/route/99/x", nil)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("route registered at runtime not served: %d", w.Code)
+	}
+}
+
+func TestRouterFailedRegistration(t *testing.T) {
+	router := New()
+	router.GET("/user/{name}", func(_ http.ResponseWriter, _ *http.Request) {})
+
+	func() {
+		defer func() { recover() }()
+		router.GET("/user/{id}/about", func(_ http.ResponseWriter, _ *http.Request) {})
+	}()
+
+	// The conflicting route did not modify the published tree
+	if handle, _ := router.Lookup(http.MethodGet, "/user/gopher/about"); handle != nil {
+		t.Error("conflicting route was partially registered")
+	}
+	if handle, _ := router.Lookup(http.MethodGet, "/user/gopher"); handle == nil {
+		t.Error("existing route is missing")
+	}
+}
+
+func TestTreeCompactInternsStrings(t *testing.T) {
+	handlerFunc := func(_ http.ResponseWriter, _ *http.Request) {}
+
+	items := "items"
+	var trees []*node
+	for _, tenant := range []string{"acme", "globex"} {
+		router := New()
+		router.GET("/"+tenant+"/orders/{id}/items", handlerFunc)
+		router.GET("/"+tenant+"/invoices", handlerFunc)
+		// Built at runtime, so that the patterns do not share memory
+		router.GET("/shared/{id}/"+items, handlerFunc)
+		router.Freeze()
+		trees = append(trees, router.trees.Load().get(http.MethodGet))
+	}
+
+	var find func(n *node, fullPath string) *node
+	find = func(n *node, fullPath string) *node {
+		if n.fullPath == fullPath {
+			return n
+		}
+		for _, child := range n.children {
+			if found := find(child, fullPath); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+
+	a, b := find(trees[0], "/shared/{id}/items"), find(trees[1], "/shared/{id}/items")
+	if a == nil || b == nil {
+		t.Fatal("route not found in compacted trees")
+	}
+	if unsafe.StringData(a.fullPath) != unsafe.StringData(b.fullPath) {
+		t.Error("expected patterns to be interned")
+	}
+	if unsafe.StringData(a.path) != unsafe.StringData(b.path) {
+		t.Error("expected path segments to be interned")
+	}
+}
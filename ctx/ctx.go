@@ -0,0 +1,136 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+// Package ctx provides an optional request context for httpmux handlers, for
+// code bases used to the ergonomics of gin or echo. A Context bundles the
+// response writer and the request with helpers for the common chores:
+//
+//	router.GET("/users/{id}", ctx.Handler(func(c *ctx.Context) error {
+//		user, err := users.Find(c.Param("id"))
+//		if err != nil {
+//			return err
+//		}
+//		return c.JSON(http.StatusOK, user)
+//	}))
+//
+// Handlers stay plain http.HandlerFuncs, so they work with every middleware
+// and with the rest of httpmux; the package adds no routing of its own.
+package ctx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/g-h-miles/httpmux"
+)
+
+// Context carries the response writer and the request of a handler.
+type Context struct {
+	Writer  http.ResponseWriter
+	Request *http.Request
+}
+
+// New returns a Context for the response writer and the request.
+func New(w http.ResponseWriter, r *http.Request) *Context {
+	return &Context{Writer: w, Request: r}
+}
+
+// HandlerFunc is a handler taking a Context. A returned error is answered
+// with httpmux.RespondError, so the handler must not have written a response
+// before returning it.
+type HandlerFunc func(c *Context) error
+
+// Handler adapts the handler to an http.HandlerFunc.
+func Handler(fn HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := fn(New(w, r)); err != nil {
+			httpmux.RespondError(w, r, err)
+		}
+	}
+}
+
+// Context returns the context of the request.
+func (c *Context) Context() context.Context {
+	return c.Request.Context()
+}
+
+// Param returns the value of the path parameter of the matched route.
+func (c *Context) Param(name string) string {
+	return c.Request.PathValue(name)
+}
+
+// Query returns the first value of the query parameter, or "".
+func (c *Context) Query(name string) string {
+	return c.Request.URL.Query().Get(name)
+}
+
+// QueryInt returns the query parameter as an integer, or def if it is missing
+// or not an integer.
+func (c *Context) QueryInt(name string, def int) int {
+	n, err := strconv.Atoi(c.Query(name))
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// Header returns the first value of the request header.
+func (c *Context) Header(name string) string {
+	return c.Request.Header.Get(name)
+}
+
+// Bind populates dst from the request, see httpmux.Bind.
+func (c *Context) Bind(dst any) error {
+	return httpmux.Bind(c.Request, dst)
+}
+
+// SetHeader sets a response header.
+func (c *Context) SetHeader(name, value string) {
+	c.Writer.Header().Set(name, value)
+}
+
+// Status writes the status code without a body.
+func (c *Context) Status(code int) {
+	c.Writer.WriteHeader(code)
+}
+
+// JSON writes the value as JSON with the status code. The value is encoded
+// before anything is written, so an error encoding it can be returned from
+// the handler.
+func (c *Context) JSON(code int, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.Writer.WriteHeader(code)
+	c.Writer.Write(append(b, '\n'))
+	return nil
+}
+
+// String writes the string as plain text with the status code.
+func (c *Context) String(code int, s string) error {
+	c.Writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	c.Writer.WriteHeader(code)
+	_, err := c.Writer.Write([]byte(s))
+	return err
+}
+
+// Respond writes the value in the media type the request accepts, see
+// httpmux.Respond.
+func (c *Context) Respond(code int, v any) error {
+	return httpmux.Respond(c.Writer, c.Request, code, v)
+}
+
+// Render executes the named template of the router, see httpmux.Render.
+func (c *Context) Render(name string, data any) error {
+	return httpmux.Render(c.Writer, c.Request, name, data)
+}
+
+// Redirect redirects the request to the URL with the status code.
+func (c *Context) Redirect(code int, url string) {
+	http.Redirect(c.Writer, c.Request, url, code)
+}
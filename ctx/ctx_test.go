@@ -0,0 +1,100 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package ctx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/g-h-miles/httpmux"
+)
+
+func TestHandler(t *testing.T) {
+	router := httpmux.New()
+	router.GET("/users/{id}", Handler(func(c *Context) error {
+		c.SetHeader("X-Token", c.Header("X-Token"))
+		return c.JSON(http.StatusOK, map[string]any{
+			"id":    c.Param("id"),
+			"q":     c.Query("q"),
+			"limit": c.QueryInt("limit", 10),
+		})
+	}))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/users/7?q=go&limit=x", nil)
+	req.Header.Set("X-Token", "t")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `{"id":"7","limit":10,"q":"go"}` {
+		t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	if tok := w.Header().Get("X-Token"); tok != "t" {
+		t.Errorf("expected header X-Token, got %q", tok)
+	}
+}
+
+func TestHandlerError(t *testing.T) {
+	type input struct {
+		Page int `query:"page"`
+	}
+
+	router := httpmux.New()
+	router.GET("/bind", Handler(func(c *Context) error {
+		var in input
+		if err := c.Bind(&in); err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, "page")
+	}))
+	router.GET("/fail", Handler(func(c *Context) error {
+		return errors.New("boom")
+	}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/bind?page=x", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/bind?page=2", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "page" {
+		t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "boom") {
+		t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestContextJSONError(t *testing.T) {
+	router := httpmux.New()
+	router.GET("/json", Handler(func(c *Context) error {
+		return c.JSON(http.StatusCreated, map[string]any{"ch": make(chan int)})
+	}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/json", nil))
+	if w.Code != http.StatusInternalServerError || strings.TrimSpace(w.Body.String()) != `{"error":"Internal Server Error"}` {
+		t.Errorf("expected only the error response, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestContextRedirect(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := New(w, httptest.NewRequest(http.MethodGet, "/old", nil))
+	c.Redirect(http.StatusFound, "/new")
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/new" {
+		t.Errorf("unexpected redirect %d %q", w.Code, w.Header().Get("Location"))
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MountDebugVars registers prefix+"/vars", which serves the variables
+// published with expvar, like the /debug/vars endpoint of the expvar package,
+// plus a variable "httpmux" describing the router:
+//
+//	router.MountDebugVars("/debug")
+//
+// The variable holds the registered routes, the TreeStats and, if enabled,
+// the request statistics and latency histograms of the routes:
+//
+//	"httpmux": {
+//		"routes": ["GET /users", "GET /users/{id}"],
+//		"tree": {"Routes": 2, "Nodes": 3, ...},
+//		"stats": {"GET /users/{id}": {"hits": 12, "errors_4xx": 1, "errors_5xx": 0}},
+//		"latency": {"GET /users/{id}": {"count": 12, "mean_ms": 1.5, "p50_ms": 1, "p99_ms": 5}}
+//	}
+//
+// Since the endpoint exposes internals of the service, it should only be
+// reachable by operators, e.g. by mounting it on a router serving an
+// internal port.
+func (r *Router) MountDebugVars(prefix string) {
+	r.GET(strings.TrimSuffix(prefix, "/")+"/vars", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprintf(w, "{\n")
+		expvar.Do(func(kv expvar.KeyValue) {
+			fmt.Fprintf(w, "%q: %s,\n", kv.Key, kv.Value)
+		})
+		b, _ := json.Marshal(r.debugVars())
+		fmt.Fprintf(w, "%q: %s\n}\n", "httpmux", b)
+	})
+}
+
+// debugVars returns the variables describing the router for MountDebugVars.
+func (r *Router) debugVars() map[string]any {
+	var routes []string
+	for method, root := range r.trees.Load().all() {
+		root.walkRoutes(func(n *node) {
+			routes = append(routes, method+" "+n.fullPath)
+		})
+	}
+	sort.Strings(routes)
+
+	vars := map[string]any{
+		"routes": routes,
+		"tree":   r.TreeStats(),
+	}
+	if r.stats != nil {
+		vars["stats"] = statsVar(r.Stats())
+	}
+	if r.latency != nil {
+		latency := make(map[string]map[string]any)
+		for _, h := range r.Latencies() {
+			latency[h.Method+" "+h.Path] = map[string]any{
+				"count":   h.Count,
+				"mean_ms": milliseconds(h.Mean()),
+				"p50_ms":  milliseconds(h.Quantile(0.5)),
+				"p99_ms":  milliseconds(h.Quantile(0.99)),
+			}
+		}
+		vars["latency"] = latency
+	}
+	return vars
+}
+
+// milliseconds returns d in milliseconds, keeping -1 for quantiles above the
+// largest bucket.
+func milliseconds(d time.Duration) float64 {
+	if d < 0 {
+		return -1
+	}
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,56 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouterMountDebugVars(t *testing.T) {
+	router := New()
+	router.EnableStats()
+	router.RecordLatency()
+	router.GET("/users/{id}", dummyHandler)
+	router.MountDebugVars("/debug/")
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", w.Code)
+	}
+
+	var got struct {
+		Memstats map[string]any `json:"memstats"`
+		Httpmux  struct {
+			Routes  []string                      `json:"routes"`
+			Tree    TreeStats                     `json:"tree"`
+			Stats   map[string]map[string]uint64  `json:"stats"`
+			Latency map[string]map[string]float64 `json:"latency"`
+		} `json:"httpmux"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, w.Body.String())
+	}
+	if got.Memstats == nil {
+		t.Error("expected the expvar variables")
+	}
+	if len(got.Httpmux.Routes) != 2 || got.Httpmux.Routes[0] != "GET /debug/vars" || got.Httpmux.Routes[1] != "GET /users/{id}" {
+		t.Errorf("unexpected routes %v", got.Httpmux.Routes)
+	}
+	if got.Httpmux.Tree.Routes != 2 {
+		t.Errorf("unexpected tree stats %+v", got.Httpmux.Tree)
+	}
+	if got.Httpmux.Stats["GET /users/{id}"]["hits"] != 1 {
+		t.Errorf("unexpected stats %v", got.Httpmux.Stats)
+	}
+	if got.Httpmux.Latency["GET /users/{id}"]["count"] != 1 {
+		t.Errorf("unexpected latency %v", got.Httpmux.Latency)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// DumpFormat is an output format of Router.Dump.
+type DumpFormat string
+
+// Formats of Router.Dump.
+const (
+	// JSON array of DumpedRoute
+	DumpJSON DumpFormat = "json"
+
+	// Plain-text drawing of the route trees, one per method
+	DumpText DumpFormat = "text"
+
+	// Markdown table of the routes
+	DumpMarkdown DumpFormat = "markdown"
+)
+
+// DumpedRoute describes a route in the JSON output of Router.Dump.
+type DumpedRoute struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+
+	// Names of the path parameters
+	Params []string `json:"params,omitempty"`
+
+	// Metadata of routes loaded by LoadRoutes
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Dump returns the route table in the format, sorted by method and path, e.g.
+// to document the routes of a service, to diff them in reviews or to serve
+// them on a debugging endpoint:
+//
+//	table, _ := router.Dump(httpmux.DumpMarkdown)
+//	os.WriteFile("ROUTES.md", table, 0o644)
+//
+// The text format draws the route trees node by node, marking nodes holding a
+// route with its registered path, which shows how routes share prefixes.
+func (r *Router) Dump(format DumpFormat) ([]byte, error) {
+	trees := r.trees.Load()
+	var methods []string
+	for method := range trees.all() {
+		methods = append(methods, method)
+	}
+	slices.Sort(methods)
+
+	var routes []DumpedRoute
+	for _, method := range methods {
+		var paths []string
+		trees.get(method).walkRoutes(func(n *node) {
+			paths = append(paths, n.fullPath)
+		})
+		slices.Sort(paths)
+		for _, path := range paths {
+			routes = append(routes, DumpedRoute{
+				Method:   method,
+				Path:     path,
+				Params:   patternParams(path),
+				Metadata: r.RouteMetadata(method, path),
+			})
+		}
+	}
+
+	var b strings.Builder
+	switch format {
+	case DumpJSON:
+		if routes == nil {
+			routes = []DumpedRoute{}
+		}
+		return json.MarshalIndent(routes, "", "  ")
+
+	case DumpText:
+		for _, method := range methods {
+			b.WriteString(method + "\n")
+			r.dumpNode(&b, method, trees.get(method), "", "")
+		}
+
+	case DumpMarkdown:
+		b.WriteString("| Method | Path | Parameters | Metadata |\n")
+		b.WriteString("| --- | --- | --- | --- |\n")
+		for _, route := range routes {
+			var meta []string
+			for _, k := range slices.Sorted(maps.Keys(route.Metadata)) {
+				meta = append(meta, k+"="+route.Metadata[k])
+			}
+			fmt.Fprintf(&b, "| %s | `%s` | %s | %s |\n",
+				route.Method, route.Path,
+				markdownCell(strings.Join(route.Params, ", ")),
+				markdownCell(strings.Join(meta, ", ")))
+		}
+
+	default:
+		return nil, errors.New("httpmux: unknown dump format '" + string(format) + "'")
+	}
+	return []byte(b.String()), nil
+}
+
+// dumpNode draws the node and its children, indented by prefix.
+func (r *Router) dumpNode(b *strings.Builder, method string, n *node, prefix, branch string) {
+	// Skip the empty node above a catch-all
+	if n.path == "" && n.handle == nil && len(n.children) == 1 {
+		r.dumpNode(b, method, n.children[0], prefix, branch)
+		return
+	}
+
+	b.WriteString(prefix + branch + n.path)
+	if n.handle != nil {
+		b.WriteString("  [" + n.fullPath + "]")
+		meta := r.RouteMetadata(method, n.fullPath)
+		for _, k := range slices.Sorted(maps.Keys(meta)) {
+			b.WriteString(" " + k + "=" + meta[k])
+		}
+	}
+	b.WriteByte('\n')
+
+	switch branch {
+	case "├── ":
+		prefix += "│   "
+	case "└── ":
+		prefix += "    "
+	}
+	for i, child := range n.children {
+		childBranch := "├── "
+		if i == len(n.children)-1 {
+			childBranch = "└── "
+		}
+		r.dumpNode(b, method, child, prefix, childBranch)
+	}
+}
+
+// markdownCell escapes pipes, which would end a table cell.
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func newDumpRouter(t *testing.T) *Router {
+	t.Helper()
+	router := New()
+	noop := func(http.ResponseWriter, *http.Request) {}
+	router.GET("/", noop)
+	router.GET("/users/{id}", noop)
+	router.GET("/users/{id}/posts", noop)
+	router.GET("/static/{path...}", noop)
+	err := router.LoadRoutes(&RouteManifest{Routes: []RouteSpec{
+		{Method: http.MethodPost, Path: "/users", Handler: "health", Metadata: map[string]string{"owner": "a|b"}},
+	}}, newTestRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return router
+}
+
+func TestRouterDumpJSON(t *testing.T) {
+	out, err := newDumpRouter(t).Dump(DumpJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var routes []DumpedRoute
+	if err := json.Unmarshal(out, &routes); err != nil {
+		t.Fatal(err)
+	}
+	want := []DumpedRoute{
+		{Method: "GET", Path: "/"},
+		{Method: "GET", Path: "/static/{path...}", Params: []string{"path"}},
+		{Method: "GET", Path: "/users/{id}", Params: []string{"id"}},
+		{Method: "GET", Path: "/users/{id}/posts", Params: []string{"id"}},
+		{Method: "POST", Path: "/users", Metadata: map[string]string{"owner": "a|b"}},
+	}
+	if !reflect.DeepEqual(routes, want) {
+		t.Errorf("unexpected routes:\n%+v\nwant:\n%+v", routes, want)
+	}
+
+	out, _ = New().Dump(DumpJSON)
+	if string(out) != "[]" {
+		t.Errorf("expected an empty array, got %s", out)
+	}
+}
+
+func TestRouterDumpText(t *testing.T) {
+	out, err := newDumpRouter(t).Dump(DumpText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `GET
+/  [/]
+├── users/
+│   └── {id}  [/users/{id}]
+│       └── /posts  [/users/{id}/posts]
+└── static
+    └── /{path...}  [/static/{path...}]
+POST
+/users  [/users] owner=a|b
+`
+	if string(out) != want {
+		t.Errorf("unexpected dump:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestRouterDumpMarkdown(t *testing.T) {
+	out, err := newDumpRouter(t).Dump(DumpMarkdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "| Method | Path | Parameters | Metadata |\n" +
+		"| --- | --- | --- | --- |\n" +
+		"| GET | `/` |  |  |\n" +
+		"| GET | `/static/{path...}` | path |  |\n" +
+		"| GET | `/users/{id}` | id |  |\n" +
+		"| GET | `/users/{id}/posts` | id |  |\n" +
+		"| POST | `/users` |  | owner=a\\|b |\n"
+	if string(out) != want {
+		t.Errorf("unexpected dump:\n%s\nwant:\n%s", out, want)
+	}
+
+	if _, err := New().Dump("yaml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// experimentContextKey holds the variant of the experiment with the name.
+type experimentContextKey struct {
+	name string
+}
+
+// Variant is a variant of an Experiment.
+type Variant struct {
+	Name    string
+	Handler http.Handler
+
+	// Share of the traffic relative to the other variants, 1 if zero
+	Weight float64
+}
+
+// ExperimentOption configures an Experiment.
+type ExperimentOption func(*Experiment)
+
+// ExperimentCookie sets the name of the cookie persisting the assigned
+// variant, "exp_" followed by the name of the experiment by default.
+func ExperimentCookie(name string) ExperimentOption {
+	return func(e *Experiment) {
+		e.cookie = name
+	}
+}
+
+// ExperimentStickiness assigns clients without the cookie by a hash of the
+// value selected by the stickiness instead of randomly, e.g. so that users
+// get the same variant on all their devices. As with Canary, a cookie of the
+// stickiness is created if missing; unlike the cookie of the experiment, it
+// can be shared with other experiments and canaries.
+func ExperimentStickiness(stickiness Stickiness) ExperimentOption {
+	return func(e *Experiment) {
+		e.stickiness = stickiness
+		e.sticky = true
+	}
+}
+
+// Experiment is a handler splitting traffic between named variants for A/B
+// tests, see NewExperiment.
+type Experiment struct {
+	name       string
+	variants   []Variant
+	total      float64
+	cookie     string
+	stickiness Stickiness
+	sticky     bool
+}
+
+// NewExperiment returns a handler serving clients with the variants by their
+// weights:
+//
+//	checkout := httpmux.NewExperiment("checkout", []httpmux.Variant{
+//		{Name: "control", Handler: checkoutV1},
+//		{Name: "one-page", Handler: checkoutV2, Weight: 0.5},
+//	})
+//	router.Handle(http.MethodGet, "/checkout", checkout)
+//
+// Clients are assigned a variant on their first request, which is persisted
+// in a cookie, see ExperimentCookie, so they keep it even if the weights
+// change. Variants removed from the experiment are reassigned. Handlers and
+// middlewares inside the experiment get the variant of the request with
+// ExperimentVariant, e.g. for logging and analytics.
+// NewExperiment panics if there are no variants, names are duplicated,
+// handlers are nil or weights are negative.
+func NewExperiment(name string, variants []Variant, opts ...ExperimentOption) *Experiment {
+	if len(variants) == 0 {
+		panic("experiment '" + name + "' has no variants")
+	}
+	e := &Experiment{name: name, cookie: "exp_" + name}
+	seen := make(map[string]bool)
+	for _, v := range variants {
+		if seen[v.Name] {
+			panic("experiment '" + name + "' has duplicate variant '" + v.Name + "'")
+		}
+		seen[v.Name] = true
+		if v.Handler == nil {
+			panic("variant '" + v.Name + "' of experiment '" + name + "' has no handler")
+		}
+		if v.Weight < 0 {
+			panic("variant '" + v.Name + "' of experiment '" + name + "' has a negative weight")
+		}
+		if v.Weight == 0 {
+			v.Weight = 1
+		}
+		e.total += v.Weight
+		e.variants = append(e.variants, v)
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// experimentCookieMaxAge is the lifetime of experiment cookies.
+const experimentCookieMaxAge = 90 * 24 * time.Hour
+
+func (e *Experiment) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	v, ok := e.assigned(req)
+	if !ok {
+		v = e.assign(w, req)
+		http.SetCookie(w, &http.Cookie{
+			Name:     e.cookie,
+			Value:    v.Name,
+			Path:     "/",
+			MaxAge:   int(experimentCookieMaxAge.Seconds()),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	ctx := context.WithValue(req.Context(), experimentContextKey{e.name}, v.Name)
+	v.Handler.ServeHTTP(w, req.WithContext(ctx))
+}
+
+// assigned returns the variant persisted in the cookie of the request.
+func (e *Experiment) assigned(req *http.Request) (Variant, bool) {
+	c, err := req.Cookie(e.cookie)
+	if err != nil {
+		return Variant{}, false
+	}
+	for _, v := range e.variants {
+		if v.Name == c.Value {
+			return v, true
+		}
+	}
+	return Variant{}, false
+}
+
+// assign picks a variant by the weights.
+func (e *Experiment) assign(w http.ResponseWriter, req *http.Request) Variant {
+	var x float64
+	if e.sticky {
+		x = stickyFraction(e.name + "\x00" + e.stickiness.key(w, req))
+	} else {
+		x = rand.Float64()
+	}
+	x *= e.total
+	for _, v := range e.variants {
+		if x < v.Weight {
+			return v
+		}
+		x -= v.Weight
+	}
+	return e.variants[len(e.variants)-1]
+}
+
+// ExperimentVariant returns the name of the variant of the experiment the
+// request was assigned to, or an empty string if it did not pass the
+// experiment.
+func ExperimentVariant(req *http.Request, experiment string) string {
+	name, _ := req.Context().Value(experimentContextKey{experiment}).(string)
+	return name
+}
@@ -0,0 +1,125 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExperiment(t *testing.T) {
+	variant := func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(ExperimentVariant(req, "checkout")))
+	}
+	experiment := NewExperiment("checkout", []Variant{
+		{Name: "control", Handler: http.HandlerFunc(variant)},
+		{Name: "one-page", Handler: http.HandlerFunc(variant), Weight: 3},
+	})
+
+	counts := make(map[string]int)
+	for range 1000 {
+		w := httptest.NewRecorder()
+		experiment.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/checkout", nil))
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != "exp_checkout" || cookies[0].Value != w.Body.String() {
+			t.Fatalf("got cookies %v for variant %q", cookies, w.Body.String())
+		}
+		counts[w.Body.String()]++
+	}
+	if counts["control"] < 150 || counts["one-page"] < 650 || len(counts) != 2 {
+		t.Errorf("got assignments %v, want about 250 control and 750 one-page", counts)
+	}
+
+	// The cookie persists the assignment, unknown variants are reassigned
+	for value, want := range map[string]string{"control": "control", "one-page": "one-page", "removed": ""} {
+		req := httptest.NewRequest(http.MethodGet, "/checkout", nil)
+		req.AddCookie(&http.Cookie{Name: "exp_checkout", Value: value})
+		w := httptest.NewRecorder()
+		experiment.ServeHTTP(w, req)
+		if want == "" {
+			if len(w.Result().Cookies()) != 1 {
+				t.Errorf("%q: variant not reassigned", value)
+			}
+		} else if w.Body.String() != want || len(w.Result().Cookies()) != 0 {
+			t.Errorf("%q: got %q with cookies %v", value, w.Body.String(), w.Result().Cookies())
+		}
+	}
+
+	if v := ExperimentVariant(httptest.NewRequest(http.MethodGet, "/", nil), "checkout"); v != "" {
+		t.Errorf("got variant %q outside the experiment", v)
+	}
+}
+
+func TestExperimentStickiness(t *testing.T) {
+	experiment := NewExperiment("search", []Variant{
+		{Name: "a", Handler: http.NotFoundHandler()},
+		{Name: "b", Handler: http.NotFoundHandler()},
+	}, ExperimentStickiness(StickyHeader("X-User-ID")), ExperimentCookie("search"))
+
+	assign := func(user string) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-User-ID", user)
+		w := httptest.NewRecorder()
+		experiment.ServeHTTP(w, req)
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != "search" {
+			t.Fatalf("got cookies %v", cookies)
+		}
+		return cookies[0].Value
+	}
+	for _, user := range []string{"alice", "bob", "carol"} {
+		if a, b := assign(user), assign(user); a != b {
+			t.Errorf("%s: assigned %q and %q", user, a, b)
+		}
+	}
+}
+
+func TestExperimentStickyCookie(t *testing.T) {
+	experiment := NewExperiment("search", []Variant{
+		{Name: "a", Handler: http.NotFoundHandler()},
+		{Name: "b", Handler: http.NotFoundHandler()},
+	}, ExperimentStickiness(StickyCookie("client")), ExperimentCookie("search"))
+
+	w := httptest.NewRecorder()
+	experiment.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookies := map[string]string{}
+	for _, c := range w.Result().Cookies() {
+		cookies[c.Name] = c.Value
+	}
+	if len(cookies) != 2 || cookies["client"] == "" || cookies["search"] == "" {
+		t.Fatalf("got cookies %v", cookies)
+	}
+
+	// Clients keeping the sticky cookie are assigned the same variant
+	for range 5 {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(&http.Cookie{Name: "client", Value: cookies["client"]})
+		w := httptest.NewRecorder()
+		experiment.ServeHTTP(w, req)
+		if c := w.Result().Cookies(); len(c) != 1 || c[0].Name != "search" || c[0].Value != cookies["search"] {
+			t.Fatalf("expected variant %q, got cookies %v", cookies["search"], c)
+		}
+	}
+}
+
+func TestExperimentPanics(t *testing.T) {
+	h := http.NotFoundHandler()
+	for name, variants := range map[string][]Variant{
+		"none":      nil,
+		"duplicate": {{Name: "a", Handler: h}, {Name: "a", Handler: h}},
+		"negative":  {{Name: "a", Handler: h, Weight: -1}},
+		"nil":       {{Name: "a", Handler: h}, {Name: "b"}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: no panic", name)
+				}
+			}()
+			NewExperiment("e", variants)
+		}()
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Outcomes of a routing decision, as reported by Explain.
+const (
+	OutcomeMatch            = "match"
+	OutcomeRedirect         = "redirect"
+	OutcomeOptions          = "automatic OPTIONS response"
+	OutcomeMethodNotAllowed = "method not allowed"
+	OutcomeNotFound         = "not found"
+)
+
+// ExplainStep is a single node visited while matching a path.
+type ExplainStep struct {
+	// Path segment stored in the node
+	Node string
+
+	// Type of the node: "root", "static", "param" or "catch-all"
+	Type string
+
+	// Part of the request path which was left to match when the node was
+	// visited
+	Remaining string
+
+	// Name and value of the path parameter captured by a param or catch-all
+	// node
+	Param, Value string
+}
+
+// Explanation describes how the router handles a request.
+// It is returned by Router.Explain.
+type Explanation struct {
+	Method string
+	Path   string
+
+	// Nodes of the method's tree visited during the lookup
+	Steps []ExplainStep
+
+	// Reason why the lookup failed, empty if a route matched
+	Failure string
+
+	// Registered path of the matched route
+	Route string
+
+	// Whether the tree recommended a trailing slash redirect
+	TSR bool
+
+	// One of the Outcome constants
+	Outcome string
+
+	// Target of the redirect for OutcomeRedirect
+	RedirectTo string
+
+	// Value of the Allow header for OutcomeOptions and
+	// OutcomeMethodNotAllowed
+	Allow string
+}
+
+// String returns a human-readable, multi-line representation of the trace.
+func (e *Explanation) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s\n", e.Method, e.Path)
+	for _, s := range e.Steps {
+		fmt.Fprintf(&sb, "  node %q (%s), remaining %q", s.Node, s.Type, s.Remaining)
+		if s.Param != "" {
+			fmt.Fprintf(&sb, ", %s=%q", s.Param, s.Value)
+		}
+		sb.WriteByte('\n')
+	}
+	if e.Failure != "" {
+		fmt.Fprintf(&sb, "  no match: %s\n", e.Failure)
+	}
+	if e.TSR {
+		sb.WriteString("  trailing slash redirect recommended\n")
+	}
+
+	fmt.Fprintf(&sb, "outcome: %s", e.Outcome)
+	switch e.Outcome {
+	case OutcomeMatch:
+		fmt.Fprintf(&sb, " %s", e.Route)
+	case OutcomeRedirect:
+		fmt.Fprintf(&sb, " to %s", e.RedirectTo)
+	case OutcomeOptions, OutcomeMethodNotAllowed:
+		fmt.Fprintf(&sb, " (Allow: %s)", e.Allow)
+	}
+	return sb.String()
+}
+
+func (e *Explanation) visit(n *node, remaining string) {
+	e.Steps = append(e.Steps, ExplainStep{
+		Node:      n.path,
+		Type:      n.nType.String(),
+		Remaining: remaining,
+	})
+}
+
+func (e *Explanation) capture(name, value string) {
+	s := &e.Steps[len(e.Steps)-1]
+	s.Param, s.Value = name, value
+}
+
+func (t nodeType) String() string {
+	switch t {
+	case root:
+		return "root"
+	case param:
+		return "param"
+	case catchAll:
+		return "catch-all"
+	default:
+		return "static"
+	}
+}
+
+// Explain reports how the router would handle a request with the given method
+// and path, without invoking any handler.
+// The trace lists the tree nodes visited, where matching failed and which of
+// the router's fallbacks (redirects, OPTIONS and 405 handling) applies. It is
+// meant for debugging routes which unexpectedly do not match:
+//
+//	fmt.Println(router.Explain("GET", "/users/42/"))
+//
+// Explain uses a request without headers, see ExplainRequest for WebSocket
+// upgrades.
+func (r *Router) Explain(method, path string) *Explanation {
+	return r.ExplainRequest(&http.Request{Method: method, URL: &url.URL{Path: path}})
+}
+
+// ExplainRequest is like Explain, but for the method, path and headers of the
+// request, so that e.g. WebSocket upgrades are matched against the WEBSOCKET
+// routes.
+func (r *Router) ExplainRequest(req *http.Request) *Explanation {
+	path := req.URL.Path
+	e := &Explanation{Method: req.Method, Path: path}
+	trees := r.trees.Load()
+
+	root, wsRoot := roots(trees, req)
+	switch {
+	case req.Method == methodWebSocket:
+		e.Failure = "WEBSOCKET routes are only matched by GET upgrade requests"
+	case root == nil && wsRoot == nil:
+		e.Failure = "no routes are registered for method " + req.Method
+	default:
+		handle, tsr := lookupRoots(root, wsRoot, path, nil, nil, e)
+		if handle != nil {
+			e.Outcome = OutcomeMatch
+			return e
+		}
+		e.TSR = tsr
+
+		if target, ok := r.redirectTarget(trees, root, req.Method, path, tsr); ok {
+			e.Outcome = OutcomeRedirect
+			e.RedirectTo = target
+			return e
+		}
+	}
+
+	e.Outcome, e.Allow = r.unmatched(trees, req.Method, path)
+	return e
+}
@@ -0,0 +1,171 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTreeLookupTrace(t *testing.T) {
+	routes := []string{
+		"/hi",
+		"/b/",
+		"/search/{query}",
+		"/cmd/{tool}/",
+		"/src/{filepath...}",
+		"/x",
+		"/x/y",
+		"/y/",
+		"/y/z",
+		"/0/{id}",
+		"/0/{id}/1",
+		"/1/{id}/",
+		"/1/{id}/2",
+		"/aa",
+		"/a/",
+		"/admin",
+		"/admin/{category}",
+		"/admin/{category}/{page}",
+		"/doc",
+		"/doc/go_faq.html",
+		"/vendor/{x}/{y...}",
+	}
+	tree := &node{}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(route))
+	}
+
+	paths := append(routes,
+		"/", "/hi/", "/b", "/search/gopher", "/search/gopher/", "/cmd/vet",
+		"/src", "/src/a/b", "/x/", "/y", "/0/go/", "/1/go", "/a", "/admin/",
+		"/admin/config/", "/admin/config/permissions/", "/doc/", "/vendor/x",
+		"/no", "/_", "/api/world/abc",
+	)
+	for _, path := range paths {
+		handle, tsr := tree.getValue(path, nil, nil)
+		e := &Explanation{}
+		eHandle, eTSR := tree.lookup(path, nil, nil, e)
+		if (handle == nil) != (eHandle == nil) || tsr != eTSR {
+			t.Errorf("%s: traced lookup (%t, %t) differs from getValue (%t, %t)",
+				path, eHandle != nil, eTSR, handle != nil, tsr)
+		}
+		if (handle == nil) != (e.Failure != "") {
+			t.Errorf("%s: unexpected failure %q", path, e.Failure)
+		}
+	}
+}
+
+func TestRouterExplain(t *testing.T) {
+	handlerFunc := func(_ http.ResponseWriter, _ *http.Request) {}
+	router := New()
+	router.GET("/users/{id}", handlerFunc)
+	router.GET("/files/{filepath...}", handlerFunc)
+	router.POST("/upload", handlerFunc)
+
+	tests := []struct {
+		method, path string
+		outcome      string
+		detail       string
+	}{
+		{http.MethodGet, "/users/42", OutcomeMatch, "/users/{id}"},
+		{http.MethodGet, "/users/42/", OutcomeRedirect, "/users/42"},
+		{http.MethodGet, "/USERS/42", OutcomeRedirect, "/users/42"},
+		{http.MethodGet, "/upload", OutcomeMethodNotAllowed, "OPTIONS, POST"},
+		{http.MethodOptions, "/upload", OutcomeOptions, "OPTIONS, POST"},
+		{http.MethodGet, "/nope", OutcomeNotFound, ""},
+		{http.MethodPut, "/nope", OutcomeNotFound, ""},
+	}
+	for _, tt := range tests {
+		e := router.Explain(tt.method, tt.path)
+		var detail string
+		switch e.Outcome {
+		case OutcomeMatch:
+			detail = e.Route
+		case OutcomeRedirect:
+			detail = e.RedirectTo
+		default:
+			detail = e.Allow
+		}
+		if e.Outcome != tt.outcome || detail != tt.detail {
+			t.Errorf("%s %s: expected %s %q, got %s %q\n%s",
+				tt.method, tt.path, tt.outcome, tt.detail, e.Outcome, detail, e)
+		}
+	}
+
+	e := router.Explain(http.MethodGet, "/files/docs/readme.txt")
+	last := e.Steps[len(e.Steps)-1]
+	if last.Type != "catch-all" || last.Param != "filepath" || last.Value != "/docs/readme.txt" {
+		t.Errorf("unexpected last step %+v", last)
+	}
+	if s := e.String(); !strings.Contains(s, `filepath="/docs/readme.txt"`) ||
+		!strings.HasSuffix(s, "outcome: match /files/{filepath...}") {
+		t.Errorf("unexpected trace:\n%s", s)
+	}
+}
+
+func TestRouterExplainMatchesServeHTTP(t *testing.T) {
+	handlerFunc := func(_ http.ResponseWriter, _ *http.Request) {}
+	router := New()
+	router.GET("/users/{id}", handlerFunc)
+	router.GET("/strict/", handlerFunc, StrictSlash())
+	router.POST("/upload", handlerFunc, NoAutoOptions())
+	router.PUT("/upload", handlerFunc)
+	router.WEBSOCKET("/chat", handlerFunc)
+	router.GET("/page", handlerFunc)
+	router.WEBSOCKET("/page", handlerFunc)
+
+	tests := []struct {
+		req     *http.Request
+		outcome string
+	}{
+		{httptest.NewRequest(http.MethodGet, "/users/42", nil), OutcomeMatch},
+		{httptest.NewRequest(http.MethodGet, "/users/42/", nil), OutcomeRedirect},
+		{httptest.NewRequest(http.MethodGet, "/Users/42", nil), OutcomeRedirect},
+		{httptest.NewRequest(http.MethodGet, "/strict", nil), OutcomeNotFound},
+		{httptest.NewRequest(http.MethodGet, "/STRICT", nil), OutcomeNotFound},
+		{httptest.NewRequest(http.MethodGet, "/STRICT/", nil), OutcomeRedirect},
+		{httptest.NewRequest(http.MethodOptions, "/upload", nil), OutcomeMethodNotAllowed},
+		{httptest.NewRequest(http.MethodGet, "/upload", nil), OutcomeMethodNotAllowed},
+		{httptest.NewRequest(http.MethodGet, "/chat", nil), OutcomeNotFound},
+		{httptest.NewRequest(http.MethodPost, "/chat", nil), OutcomeMethodNotAllowed},
+		{newUpgradeRequest("/chat"), OutcomeMatch},
+		{newUpgradeRequest("/chat/"), OutcomeNotFound},
+		{newUpgradeRequest("/page"), OutcomeMatch},
+		{httptest.NewRequest(methodWebSocket, "/chat", nil), OutcomeMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		e := router.ExplainRequest(tt.req)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, tt.req)
+		var served string
+		switch {
+		case w.Code == http.StatusOK && w.Header().Get("Allow") == "":
+			served = OutcomeMatch
+		case w.Code == http.StatusOK:
+			served = OutcomeOptions
+		case w.Code == http.StatusMovedPermanently:
+			served = OutcomeRedirect
+			if loc := w.Header().Get("Location"); loc != e.RedirectTo {
+				t.Errorf("%s %s: redirected to %s, explained %s", tt.req.Method, tt.req.URL, loc, e.RedirectTo)
+			}
+		case w.Code == http.StatusMethodNotAllowed:
+			served = OutcomeMethodNotAllowed
+			if allow := w.Header().Get("Allow"); allow != e.Allow {
+				t.Errorf("%s %s: allowed %s, explained %s", tt.req.Method, tt.req.URL, allow, e.Allow)
+			}
+		default:
+			served = OutcomeNotFound
+		}
+
+		if served != tt.outcome || e.Outcome != tt.outcome {
+			t.Errorf("%s %s: served %s, explained %s, want %s\n%s",
+				tt.req.Method, tt.req.URL, served, e.Outcome, tt.outcome, e)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import "net/http"
+
+// Fallback sets a handler for requests no route matches, e.g. a legacy
+// http.ServeMux still serving the routes not yet migrated:
+//
+//	router := httpmux.New()
+//	router.GET("/users/{id}", showUser) // migrated
+//	router.Fallback(legacyMux)          // everything else
+//
+// Unlike NotFound, the fallback handler is expected to handle only some of
+// the requests: if it answers with 404 Not Found, its response is discarded
+// and the request is answered like without a fallback, with NotFound or the
+// group's not found handler. Requests for which a route exists, but not for
+// their method, are answered with 405 Method Not Allowed as usual.
+func (r *Router) Fallback(h http.Handler) {
+	r.fallback = h
+}
+
+// serveFallback passes the request to the fallback handler and reports
+// whether it handled it.
+func (r *Router) serveFallback(w http.ResponseWriter, req *http.Request) bool {
+	fw := &fallbackWriter{w: w, header: w.Header().Clone()}
+	r.fallback.ServeHTTP(fw, req)
+	if !fw.wroteHeader {
+		fw.WriteHeader(http.StatusOK)
+	}
+	return !fw.notFound
+}
+
+// fallbackWriter holds back a 404 response of a fallback handler, including
+// its headers, and passes on any other response.
+type fallbackWriter struct {
+	w           http.ResponseWriter
+	header      http.Header
+	wroteHeader bool
+	notFound    bool
+}
+
+func (fw *fallbackWriter) Header() http.Header {
+	if fw.wroteHeader && !fw.notFound {
+		return fw.w.Header()
+	}
+	return fw.header
+}
+
+func (fw *fallbackWriter) WriteHeader(code int) {
+	if fw.wroteHeader {
+		return
+	}
+	fw.wroteHeader = true
+	if code == http.StatusNotFound {
+		fw.notFound = true
+		return
+	}
+
+	h := fw.w.Header()
+	clear(h)
+	for k, v := range fw.header {
+		h[k] = v
+	}
+	fw.w.WriteHeader(code)
+}
+
+func (fw *fallbackWriter) Write(b []byte) (int, error) {
+	if !fw.wroteHeader {
+		fw.WriteHeader(http.StatusOK)
+	}
+	if fw.notFound {
+		return len(b), nil
+	}
+	return fw.w.Write(b)
+}
+
+// Flush implements http.Flusher. A held back response is not flushed.
+func (fw *fallbackWriter) Flush() {
+	if !fw.wroteHeader {
+		fw.WriteHeader(http.StatusOK)
+	}
+	if !fw.notFound {
+		http.NewResponseController(fw.w).Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (fw *fallbackWriter) Unwrap() http.ResponseWriter {
+	return fw.w
+}
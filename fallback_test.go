@@ -0,0 +1,55 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouterFallback(t *testing.T) {
+	legacy := http.NewServeMux()
+	legacy.HandleFunc("/legacy/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Legacy", "1")
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("legacy"))
+	})
+	legacy.HandleFunc("/implicit", func(w http.ResponseWriter, r *http.Request) {})
+
+	router := New()
+	router.GET("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("user"))
+	})
+	router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("custom not found"))
+	})
+	router.Fallback(legacy)
+
+	for _, tt := range []struct {
+		method, path string
+		code         int
+		body, legacy string
+	}{
+		{http.MethodGet, "/users/1", http.StatusOK, "user", ""},
+		{http.MethodGet, "/legacy/page", http.StatusTeapot, "legacy", "1"},
+		{http.MethodGet, "/implicit", http.StatusOK, "", ""},
+		{http.MethodGet, "/missing", http.StatusNotFound, "custom not found", ""},
+		{http.MethodPost, "/users/1", http.StatusMethodNotAllowed, "Method Not Allowed\n", ""},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.code || w.Body.String() != tt.body {
+			t.Errorf("%s %s: expected %d %q, got %d %q", tt.method, tt.path, tt.code, tt.body, w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("X-Legacy"); got != tt.legacy {
+			t.Errorf("%s %s: expected X-Legacy %q, got %q", tt.method, tt.path, tt.legacy, got)
+		}
+		if tt.code == http.StatusNotFound && w.Header().Get("X-Content-Type-Options") != "" {
+			t.Errorf("%s %s: headers of the discarded fallback response leaked", tt.method, tt.path)
+		}
+	}
+}
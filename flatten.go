@@ -0,0 +1,132 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Flatten returns a single Router serving the routes of all groups under
+// their full paths and the routes of the default router, e.g. to skip the
+// prefix dispatch in production while composing the application of groups.
+//
+// The routes keep the handlers they were registered with, including the
+// middlewares of their routers. Group middlewares and timeouts are applied
+// per route, so unlike with the MultiRouter they do not run for requests no
+// route matches. Handlers see the full request path, as with PreservePath,
+// and MatchedGroup reports no group.
+// Settings applied while serving, like NotFound, PanicHandler and the redirect
+// options, are taken from the default router, if any; those of the group
+// routers are not used.
+//
+// Flatten returns an error if Audit reports conflicts, or if the MultiRouter
+// uses features a single Router cannot provide: hosts, handlers added by
+// Mount, Proxy or Redirect, disabled groups, rewrite rules, group error
+// handlers, templates, validators, encoders, authenticators or breakers of
+// group routers and FallthroughNotFound.
+func (m *MultiRouter) Flatten() (flat *Router, err error) {
+	if conflicts := m.Audit(); len(conflicts) > 0 {
+		return nil, fmt.Errorf("httpmux: cannot flatten MultiRouter with conflicts: %s", conflicts[0])
+	}
+
+	s := m.state.Load()
+	if len(s.hosts) > 0 || len(s.wildcardHosts) > 0 {
+		return nil, errors.New("httpmux: cannot flatten MultiRouter with hosts")
+	}
+	if m.FallthroughNotFound {
+		return nil, errors.New("httpmux: cannot flatten MultiRouter with FallthroughNotFound")
+	}
+
+	flat = New()
+	if d := s.defaultRouter; d != nil {
+		flat.RedirectTrailingSlash = d.RedirectTrailingSlash
+		flat.RedirectFixedPath = d.RedirectFixedPath
+		flat.HandleMethodNotAllowed = d.HandleMethodNotAllowed
+		flat.HandleOPTIONS = d.HandleOPTIONS
+		flat.GlobalOPTIONS = d.GlobalOPTIONS
+		flat.NotFound = d.NotFound
+		flat.fallback = d.fallback
+		flat.MethodNotAllowed = d.MethodNotAllowed
+		flat.PanicHandler = d.PanicHandler
+		flat.LogRequests = d.LogRequests
+		flat.logger = d.logger
+		flat.SlowRequestThreshold = d.SlowRequestThreshold
+		flat.SlowRequest = d.SlowRequest
+		flat.settings.Store(d.settings.Load())
+	}
+
+	// Routes conflicting within the flat router panic on registration
+	defer func() {
+		if recv := recover(); recv != nil {
+			flat, err = nil, fmt.Errorf("httpmux: cannot flatten MultiRouter: %v", recv)
+		}
+	}()
+
+	defaults := flat.loadSettings()
+	for _, prefix := range s.prefixes {
+		g := s.groups[prefix]
+		var gs *settings
+		if g.router != nil {
+			gs = g.router.loadSettings()
+		}
+		switch {
+		case g.router == nil:
+			return nil, fmt.Errorf("httpmux: cannot flatten handler mounted at '%s'", prefix)
+		case g.disabled:
+			return nil, fmt.Errorf("httpmux: cannot flatten disabled group '%s'", prefix)
+		case len(g.rewrites) > 0:
+			return nil, fmt.Errorf("httpmux: cannot flatten group '%s' with rewrite rules", prefix)
+		case g.notFound != nil || g.methodNotAllowed != nil:
+			return nil, fmt.Errorf("httpmux: cannot flatten group '%s' with error handlers", prefix)
+		case gs.templates != nil && gs.templates != defaults.templates:
+			return nil, fmt.Errorf("httpmux: cannot flatten group '%s' with templates", prefix)
+		case gs.validator != nil && gs.validator != defaults.validator:
+			return nil, fmt.Errorf("httpmux: cannot flatten group '%s' with a validator", prefix)
+		case gs.encoders != nil && gs.encoders != defaults.encoders:
+			return nil, fmt.Errorf("httpmux: cannot flatten group '%s' with encoders", prefix)
+		case gs.authenticator != nil && gs.authenticator != defaults.authenticator:
+			return nil, fmt.Errorf("httpmux: cannot flatten group '%s' with an authenticator", prefix)
+		case gs.breaker != nil && gs.breaker != defaults.breaker:
+			return nil, fmt.Errorf("httpmux: cannot flatten group '%s' with a breaker", prefix)
+		}
+
+		for method, root := range g.router.trees.Load().all() {
+			root.walkRoutes(func(n *node) {
+				handle := n.handle
+				opts := routeOptionsOf(handle)
+				if rh, ok := handle.(*routeHandler); ok {
+					handle = rh.Handler
+				}
+				if g.timeout > 0 {
+					handle = timeoutHandler(g.timeout, handle)
+				}
+				for i := len(g.middlewares) - 1; i >= 0; i-- {
+					handle = g.middlewares[i](handle)
+				}
+				// Keep the route options outside of the group wrappers
+				opts.tags = mergeTags(g.tags, opts.tags)
+				if _, ok := n.handle.(*routeHandler); ok || len(g.tags) > 0 {
+					handle = &routeHandler{Handler: handle, opts: opts}
+				}
+
+				flat.Handle(method, g.fullPath(n.fullPath), handle)
+				// The MultiRouter dispatches the bare prefix to the route "/"
+				if n.fullPath == "/" && !g.preservePath && prefix != "/" {
+					flat.Handle(method, prefix, handle)
+				}
+			})
+		}
+	}
+
+	if s.defaultRouter != nil {
+		for method, root := range s.defaultRouter.trees.Load().all() {
+			root.walkRoutes(func(n *node) {
+				flat.Handle(method, n.fullPath, n.handle)
+			})
+		}
+	}
+	return flat, nil
+}
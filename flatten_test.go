@@ -0,0 +1,98 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMultiRouterFlatten(t *testing.T) {
+	respond := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body + " " + r.PathValue("tenant") + r.PathValue("id")))
+		}
+	}
+	tag := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Group", "api")
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	multi := NewMultiRouter()
+	api := multi.NewGroup("/api", WithMiddleware(tag))
+	api.GET("/", respond("api root"))
+	api.GET("/users/{id}", respond("user"))
+	multi.NewGroup("/t/{tenant}").POST("/items", respond("items"))
+	multi.NewGroup("/docs", PreservePath()).GET("/docs/index", respond("docs"))
+	def := New()
+	def.NotFound = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	def.GET("/home", respond("home"))
+	multi.Default(def)
+
+	flat, err := multi.Flatten()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method, path, body string
+		code               int
+	}{
+		{http.MethodGet, "/api", "api root ", http.StatusOK},
+		{http.MethodGet, "/api/", "api root ", http.StatusOK},
+		{http.MethodGet, "/api/users/7", "user 7", http.StatusOK},
+		{http.MethodPost, "/t/acme/items", "items acme", http.StatusOK},
+		{http.MethodGet, "/docs/index", "docs ", http.StatusOK},
+		{http.MethodGet, "/home", "home ", http.StatusOK},
+		{http.MethodGet, "/missing", "", http.StatusTeapot},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		flat.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.code || w.Body.String() != tt.body {
+			t.Errorf("%s %s: expected %d %q, got %d %q", tt.method, tt.path, tt.code, tt.body, w.Code, w.Body.String())
+		}
+		if strings.HasPrefix(tt.path, "/api") && w.Header().Get("X-Group") != "api" {
+			t.Errorf("%s: expected group middleware to run", tt.path)
+		}
+	}
+}
+
+func TestMultiRouterFlattenErrors(t *testing.T) {
+	tests := map[string]func(multi *MultiRouter){
+		"mount": func(multi *MultiRouter) {
+			multi.Mount("/legacy", http.NotFoundHandler())
+		},
+		"host": func(multi *MultiRouter) {
+			multi.Host("api.example.com", http.NotFoundHandler())
+		},
+		"rewrite": func(multi *MultiRouter) {
+			multi.NewGroup("/api", WithRewrite("/v1/{path...}", "/{path...}"))
+		},
+		"disabled": func(multi *MultiRouter) {
+			multi.NewGroup("/beta")
+			multi.Disable("/beta")
+		},
+		"conflict": func(multi *MultiRouter) {
+			logger, _ := newTestLogger()
+			multi.WarnOnConflict(logger)
+			multi.NewGroup("/api")
+			multi.RegisterDefault(http.MethodGet, "/api/users", dummyHandler)
+		},
+	}
+	for name, setup := range tests {
+		multi := NewMultiRouter()
+		setup(multi)
+		if flat, err := multi.Flatten(); err == nil || flat != nil {
+			t.Errorf("%s: expected error, got %v", name, err)
+		}
+	}
+}
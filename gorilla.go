@@ -0,0 +1,221 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// parseGorillaPath converts a path template of github.com/gorilla/mux to the
+// syntax of this package and returns the regular expressions of its
+// variables, keyed by name.
+func parseGorillaPath(tpl string) (string, map[string]*regexp.Regexp, error) {
+	var b strings.Builder
+	var patterns map[string]*regexp.Regexp
+	for i := 0; i < len(tpl); i++ {
+		if tpl[i] != '{' {
+			if tpl[i] == '}' {
+				return "", nil, errors.New("unbalanced braces in '" + tpl + "'")
+			}
+			b.WriteByte(tpl[i])
+			continue
+		}
+
+		// Find the matching brace; patterns may contain braces themselves
+		level, end := 0, -1
+		for j := i; j < len(tpl) && end < 0; j++ {
+			switch tpl[j] {
+			case '{':
+				level++
+			case '}':
+				if level--; level == 0 {
+					end = j
+				}
+			}
+		}
+		if end < 0 {
+			return "", nil, errors.New("unbalanced braces in '" + tpl + "'")
+		}
+		if i == 0 || tpl[i-1] != '/' || end+1 < len(tpl) && tpl[end+1] != '/' {
+			return "", nil, errors.New("variable " + tpl[i:end+1] + " in '" + tpl + "' does not span a whole path segment")
+		}
+
+		name, pattern, ok := strings.Cut(tpl[i+1:end], ":")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return "", nil, errors.New("missing variable name in '" + tpl + "'")
+		}
+		if ok {
+			re, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				return "", nil, err
+			}
+			if patterns == nil {
+				patterns = make(map[string]*regexp.Regexp)
+			}
+			patterns[name] = re
+		}
+		b.WriteString("{" + name + "}")
+		i = end
+	}
+	return b.String(), patterns, nil
+}
+
+// FromGorillaPath converts a path template of github.com/gorilla/mux to the
+// syntax of this package, dropping the regular expressions of variables:
+//
+//	httpmux.FromGorillaPath("/articles/{category}/{id:[0-9]+}") // "/articles/{category}/{id}"
+//
+// Variables must span whole path segments; templates like /files/{name}.json
+// cannot be converted and return an error. Use HandleGorilla to keep the
+// regular expressions.
+func FromGorillaPath(tpl string) (string, error) {
+	path, _, err := parseGorillaPath(tpl)
+	return path, err
+}
+
+// HandleGorilla registers the handler with a path template of
+// github.com/gorilla/mux, see FromGorillaPath. Requests whose variables do
+// not match their regular expressions are answered with 404 Not Found. Unlike
+// with gorilla/mux, no other route is tried then, so routes differing only by
+// the patterns of their variables cannot be migrated; they conflict.
+// HandleGorilla panics if the template is invalid.
+func (r *Router) HandleGorilla(method, tpl string, handler http.Handler) {
+	path, patterns, err := parseGorillaPath(tpl)
+	if err != nil {
+		panic(err.Error())
+	}
+	if len(patterns) > 0 {
+		next := handler
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			for name, re := range patterns {
+				if !re.MatchString(req.PathValue(name)) {
+					r.notFound(w, req)
+					return
+				}
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+	r.Handle(method, path, handler)
+}
+
+// Vars returns the path values of the route matched for the request, keyed
+// by parameter name, like mux.Vars of gorilla/mux. It returns nil if the
+// route has no parameters.
+func Vars(req *http.Request) map[string]string {
+	names := patternParams(req.Pattern)
+	if len(names) == 0 {
+		return nil
+	}
+	vars := make(map[string]string, len(names))
+	for _, name := range names {
+		vars[name] = req.PathValue(name)
+	}
+	return vars
+}
+
+// GorillaRoute describes a route of a gorilla/mux router for
+// GorillaMigrationReport, e.g. collected with its Walk method:
+//
+//	var routes []httpmux.GorillaRoute
+//	old.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+//		tpl, _ := route.GetPathTemplate()
+//		methods, _ := route.GetMethods()
+//		host, _ := route.GetHostTemplate()
+//		queries, _ := route.GetQueriesTemplates()
+//		routes = append(routes, httpmux.GorillaRoute{
+//			Path: tpl, Methods: methods, Host: host, Queries: queries,
+//		})
+//		return nil
+//	})
+type GorillaRoute struct {
+	Path    string
+	Methods []string
+
+	// Whether the route was registered with PathPrefix
+	Prefix bool
+
+	Host    string
+	Schemes []string
+	Queries []string
+
+	// Whether the route has header or custom matchers
+	CustomMatchers bool
+}
+
+// GorillaMigrationReport lists the constructs of the gorilla/mux routes which
+// cannot be migrated as they are, sorted by method and path. An empty report
+// means all routes can be registered with HandleGorilla.
+func GorillaMigrationReport(routes []GorillaRoute) []LintWarning {
+	var warnings []LintWarning
+	report := func(route GorillaRoute, msg string) {
+		method := strings.Join(route.Methods, ",")
+		if method == "" {
+			method = "*"
+		}
+		warnings = append(warnings, LintWarning{
+			Method:  method,
+			Routes:  []string{route.Path},
+			Message: msg,
+		})
+	}
+
+	// Converted paths with normalized parameter names, to find conflicts
+	seen := make(map[string]string)
+	for _, route := range routes {
+		path, _, err := parseGorillaPath(route.Path)
+		if err != nil {
+			report(route, err.Error())
+			continue
+		}
+		if len(route.Methods) == 0 {
+			report(route, "route matches any method, register it for each method")
+		}
+		if route.Prefix {
+			report(route, "path prefix, register the handler with a catch-all parameter like "+strings.TrimSuffix(path, "/")+"/{path...} or mount it in a MultiRouter")
+		}
+		if route.Host != "" {
+			report(route, "host matching, register the route on a router added with MultiRouter.Host")
+		}
+		if len(route.Schemes) > 0 {
+			report(route, "scheme matching is not supported")
+		}
+		if len(route.Queries) > 0 {
+			report(route, "query matching is not supported, check the query in the handler")
+		}
+		if route.CustomMatchers {
+			report(route, "header and custom matchers are not supported, check the request in the handler or a middleware")
+		}
+
+		shape := path
+		for _, name := range patternParams(path) {
+			shape = strings.Replace(shape, "{"+name+"}", "{}", 1)
+		}
+		for _, method := range route.Methods {
+			key := method + " " + shape
+			if other, ok := seen[key]; ok && other != route.Path {
+				warnings = append(warnings, LintWarning{
+					Method:  method,
+					Routes:  []string{other, route.Path},
+					Message: "routes differ only by their variables, which conflict",
+				})
+			}
+			seen[key] = route.Path
+		}
+	}
+
+	sort.SliceStable(warnings, func(i, j int) bool {
+		if warnings[i].Method != warnings[j].Method {
+			return warnings[i].Method < warnings[j].Method
+		}
+		return warnings[i].Routes[0] < warnings[j].Routes[0]
+	})
+	return warnings
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestFromGorillaPath(t *testing.T) {
+	for _, tt := range []struct {
+		tpl, path string
+		ok        bool
+	}{
+		{"/", "/", true},
+		{"/articles/{category}/{id:[0-9]+}", "/articles/{category}/{id}", true},
+		{"/codes/{code:[a-z]{3}}/", "/codes/{code}/", true},
+		{"/files/{name}.json", "", false},
+		{"/files/{name", "", false},
+		{"/files/name}", "", false},
+		{"/files/{:[0-9]+}", "", false},
+		{"/files/{id:[0-9}", "", false},
+		{"{id}", "", false},
+	} {
+		path, err := FromGorillaPath(tt.tpl)
+		if (err == nil) != tt.ok || path != tt.path {
+			t.Errorf("%s: expected %q (ok %v), got %q, %v", tt.tpl, tt.path, tt.ok, path, err)
+		}
+	}
+}
+
+func TestRouterHandleGorilla(t *testing.T) {
+	var vars map[string]string
+	router := New()
+	router.HandleGorilla(http.MethodGet, "/articles/{category}/{id:[0-9]+}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vars = Vars(r)
+	}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/articles/go/42", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if want := map[string]string{"category": "go", "id": "42"}; !reflect.DeepEqual(vars, want) {
+		t.Errorf("expected vars %v, got %v", want, vars)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/articles/go/latest", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a value not matching the pattern, got %d", w.Code)
+	}
+
+	if recv := catchPanic(func() { router.HandleGorilla(http.MethodGet, "/x/{a}.json", http.NotFoundHandler()) }); recv == nil {
+		t.Error("expected a panic for an invalid template")
+	}
+}
+
+func TestGorillaMigrationReport(t *testing.T) {
+	warnings := GorillaMigrationReport([]GorillaRoute{
+		{Path: "/users/{id:[0-9]+}", Methods: []string{"GET"}},
+		{Path: "/users/{name:[a-z]+}", Methods: []string{"GET"}},
+		{Path: "/static/", Methods: []string{"GET"}, Prefix: true},
+		{Path: "/api", Methods: []string{"POST"}, Host: "{sub}.example.com", Queries: []string{"v={v}"}},
+		{Path: "/secure", Methods: []string{"GET"}, Schemes: []string{"https"}, CustomMatchers: true},
+		{Path: "/any"},
+		{Path: "/files/{name}.txt", Methods: []string{"GET"}},
+	})
+
+	var got []string
+	for _, w := range warnings {
+		got = append(got, w.String())
+	}
+	want := []string{
+		"* /any: route matches any method, register it for each method",
+		"GET /files/{name}.txt: variable {name} in '/files/{name}.txt' does not span a whole path segment",
+		"GET /secure: scheme matching is not supported",
+		"GET /secure: header and custom matchers are not supported, check the request in the handler or a middleware",
+		"GET /static/: path prefix, register the handler with a catch-all parameter like /static/{path...} or mount it in a MultiRouter",
+		"GET /users/{id:[0-9]+}, /users/{name:[a-z]+}: routes differ only by their variables, which conflict",
+		"POST /api: host matching, register the route on a router added with MultiRouter.Host",
+		"POST /api: query matching is not supported, check the query in the handler",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected report:\n%q\nwant:\n%q", got, want)
+	}
+}
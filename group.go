@@ -0,0 +1,189 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"time"
+)
+
+// group is a router or handler registered for a path prefix
+type group struct {
+	prefix string
+	name   string  // reported by MatchedGroup, the prefix by default
+	router *Router // nil for handlers added by Mount
+
+	// Handler requests are dispatched to, the router wrapped by the group's
+	// middlewares
+	handler http.Handler
+
+	middlewares      []func(http.Handler) http.Handler
+	preservePath     bool
+	notFound         http.Handler
+	methodNotAllowed http.Handler
+	rewrites         []rewriteRule
+	timeout          time.Duration
+	tags             []string // sorted, set by WithGroupTags
+	disabled         bool     // set by MultiRouter.Disable
+
+	// Whether requests are passed to the router without wrappers and error
+	// handlers, see MultiRouter.groupRequest
+	direct bool
+}
+
+// GroupOption configures a group of a MultiRouter.
+type GroupOption func(*group)
+
+// WithMiddleware adds middlewares to a group. They wrap the group's router,
+// the first middleware being the outermost one. Unlike middlewares added by
+// Router.Use, they run for every request dispatched into the group, including
+// requests no route matches. They see the path the group's router sees, i.e.
+// with the prefix stripped unless the group preserves the path.
+func WithMiddleware(middlewares ...func(http.Handler) http.Handler) GroupOption {
+	return func(g *group) {
+		g.middlewares = append(g.middlewares, middlewares...)
+	}
+}
+
+// PreservePath dispatches requests to the group without stripping the prefix
+// from their path. The group's router must then register full paths,
+// including the prefix, which keeps absolute links built from the request
+// path intact:
+//
+//	api := httpmux.New()
+//	api.GET("/api/users", listUsers)
+//	multi.Group("/api", api, httpmux.PreservePath())
+func PreservePath() GroupOption {
+	return func(g *group) {
+		g.preservePath = true
+	}
+}
+
+// WithGroupNotFound sets the handler for requests dispatched to the group
+// which its router cannot route, e.g. to answer with JSON errors in an API
+// group. It takes precedence over the router's own NotFound handler, so
+// routers shared with other groups or the default router need not be
+// modified. It has no effect on handlers added by Mount.
+func WithGroupNotFound(handler http.Handler) GroupOption {
+	return func(g *group) {
+		g.notFound = handler
+	}
+}
+
+// WithGroupMethodNotAllowed sets the handler for requests dispatched to the
+// group which are answered with 405 Method Not Allowed. It takes precedence
+// over the router's own MethodNotAllowed handler. The Allow header is set
+// before the handler is called. It has no effect on handlers added by Mount.
+func WithGroupMethodNotAllowed(handler http.Handler) GroupOption {
+	return func(g *group) {
+		g.methodNotAllowed = handler
+	}
+}
+
+// WithRewrite rewrites the paths of requests dispatched to the group which
+// match the pattern, so that legacy URLs reach the current routes without
+// changing their handlers. Pattern and template are matched against the path
+// the group's router sees and may contain parameters spanning whole path
+// segments, the last one of the pattern may be a catch-all:
+//
+//	multi.Group("/api", api,
+//		httpmux.WithRewrite("/v1/{path...}", "/{path...}"),
+//		httpmux.WithRewrite("/old/{id}", "/new/{id}"),
+//	)
+//
+// Only the first matching rule is applied. The rules run after the group's
+// middlewares, which see the original path. WithRewrite panics if the rule is
+// invalid.
+func WithRewrite(pattern, template string) GroupOption {
+	rule := newRewriteRule(pattern, template)
+	return func(g *group) {
+		g.rewrites = append(g.rewrites, rule)
+	}
+}
+
+// WithTimeout limits the time requests dispatched to the group may take, e.g.
+// so that slow admin endpoints cannot tie up the server:
+//
+//	multi.Group("/admin", admin, httpmux.WithTimeout(5*time.Second))
+//
+// The context of the request gets a deadline, which handlers should observe.
+// If a handler does not return in time, the client receives 504 Gateway
+// Timeout; whatever the handler writes afterwards is discarded. Responses are
+// buffered until the handler returns, so the group's handlers cannot stream
+// responses. The group's middlewares run outside of the time limit and see the
+// 504 response. WebSocket upgrade requests and requests accepting
+// text/event-stream are exempt from the limit. See Timeout for single routes.
+func WithTimeout(d time.Duration) GroupOption {
+	return func(g *group) {
+		g.timeout = d
+	}
+}
+
+// WithName sets the name under which MatchedGroup reports the group, e.g. to
+// attribute logs and metrics to subsystems. It defaults to the prefix.
+func WithName(name string) GroupOption {
+	return func(g *group) {
+		g.name = name
+	}
+}
+
+var matchedGroupContextKey = &contextKey{"matched-group"}
+
+// MatchedGroup returns the name of the MultiRouter group which the request was
+// dispatched to, or an empty string if it was not dispatched to a group, e.g.
+// because it is served by the default router.
+func MatchedGroup(req *http.Request) string {
+	if g, ok := req.Context().Value(matchedGroupContextKey).(*group); ok {
+		return g.name
+	}
+	return ""
+}
+
+// RoutePattern returns the full pattern of the route matched by the request,
+// including the prefix of the MultiRouter group it was dispatched to, e.g. to
+// report the route in logs and metrics. Request.Pattern is relative to the
+// group's router instead. RoutePattern returns an empty string if no route
+// matched.
+func RoutePattern(req *http.Request) string {
+	if req.Pattern == "" {
+		return ""
+	}
+	if g, ok := req.Context().Value(matchedGroupContextKey).(*group); ok {
+		return g.fullPath(req.Pattern)
+	}
+	return req.Pattern
+}
+
+func newGroup(prefix string, router *Router, handler http.Handler, opts []GroupOption) *group {
+	g := &group{prefix: prefix, name: prefix, router: router}
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	if len(g.rewrites) > 0 {
+		handler = rewriteHandler(g.rewrites, handler)
+	}
+	if g.timeout > 0 {
+		handler = timeoutHandler(g.timeout, handler)
+	}
+
+	for i := len(g.middlewares) - 1; i >= 0; i-- {
+		handler = g.middlewares[i](handler)
+	}
+	g.handler = handler
+	g.direct = router != nil && len(g.rewrites) == 0 && g.timeout == 0 && len(g.middlewares) == 0 &&
+		g.notFound == nil && g.methodNotAllowed == nil
+	return g
+}
+
+// fullPath returns the path under which a route of the group's router is
+// reachable through the MultiRouter.
+func (g *group) fullPath(path string) string {
+	// The root group is dispatched to without stripping
+	if g.preservePath || g.prefix == "/" {
+		return path
+	}
+	return g.prefix + path
+}
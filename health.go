@@ -0,0 +1,133 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+)
+
+// HealthOption configures the health endpoints registered by Router.Health.
+type HealthOption func(*Health)
+
+// WithLivenessCheck adds a check to /livez, which fails if the process cannot
+// recover by itself and should be restarted, e.g. after a deadlock.
+func WithLivenessCheck(name string, check func() error) HealthOption {
+	return func(h *Health) {
+		h.live = append(h.live, healthCheck{name, check})
+	}
+}
+
+// WithReadinessCheck adds a check to /readyz, which fails while the service
+// cannot handle requests, e.g. because its database is unreachable.
+func WithReadinessCheck(name string, check func() error) HealthOption {
+	return func(h *Health) {
+		h.ready = append(h.ready, healthCheck{name, check})
+	}
+}
+
+// WithHealthPaths sets the paths of the liveness and readiness endpoints,
+// "/livez" and "/readyz" by default.
+func WithHealthPaths(live, ready string) HealthOption {
+	return func(h *Health) {
+		h.livePath, h.readyPath = live, ready
+	}
+}
+
+type healthCheck struct {
+	name  string
+	check func() error
+}
+
+// Health controls the health endpoints of a router.
+type Health struct {
+	live, ready         []healthCheck
+	livePath, readyPath string
+	draining            atomic.Bool
+}
+
+// HealthStatus is the JSON body of the responses of the health endpoints.
+type HealthStatus struct {
+	// "ok", "failed" or "draining"
+	Status string `json:"status"`
+
+	// Result of each check, "ok" or the error message
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Health registers the liveness and readiness endpoints /livez and /readyz,
+// which run their checks on every request and answer with a HealthStatus
+// and 200 OK if all checks pass, or 503 Service Unavailable otherwise:
+//
+//	health := router.Health(
+//		httpmux.WithReadinessCheck("db", db.Ping),
+//	)
+//
+//	// On shutdown, stop receiving traffic before closing the server
+//	health.Drain()
+//
+// While draining, /readyz fails without running its checks, so that load
+// balancers stop sending new requests, while /livez keeps passing.
+func (r *Router) Health(opts ...HealthOption) *Health {
+	h := &Health{livePath: "/livez", readyPath: "/readyz"}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	r.GET(h.livePath, func(w http.ResponseWriter, req *http.Request) {
+		h.serve(w, runHealthChecks(h.live))
+	})
+	r.GET(h.readyPath, func(w http.ResponseWriter, req *http.Request) {
+		if h.draining.Load() {
+			h.serve(w, HealthStatus{Status: "draining"})
+			return
+		}
+		h.serve(w, runHealthChecks(h.ready))
+	})
+	return h
+}
+
+// Drain makes the readiness endpoint fail, e.g. during a graceful shutdown.
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+// Resume ends draining.
+func (h *Health) Resume() {
+	h.draining.Store(false)
+}
+
+// Draining reports whether Drain was called and not undone by Resume.
+func (h *Health) Draining() bool {
+	return h.draining.Load()
+}
+
+// runHealthChecks runs the checks in order and returns the aggregated status.
+func runHealthChecks(checks []healthCheck) HealthStatus {
+	status := HealthStatus{Status: "ok"}
+	if len(checks) == 0 {
+		return status
+	}
+	status.Checks = make(map[string]string, len(checks))
+	for _, c := range checks {
+		if err := c.check(); err != nil {
+			status.Status = "failed"
+			status.Checks[c.name] = err.Error()
+		} else {
+			status.Checks[c.name] = "ok"
+		}
+	}
+	return status
+}
+
+func (h *Health) serve(w http.ResponseWriter, status HealthStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if status.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(status)
+}
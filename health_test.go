@@ -0,0 +1,70 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouterHealth(t *testing.T) {
+	var dbErr error
+	router := New()
+	health := router.Health(
+		WithLivenessCheck("loop", func() error { return nil }),
+		WithReadinessCheck("db", func() error { return dbErr }),
+	)
+
+	check := func(path string, code int, status string, checks map[string]string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var got HealthStatus
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if w.Code != code || got.Status != status || len(got.Checks) != len(checks) {
+			t.Errorf("%s: unexpected response %d %+v", path, w.Code, got)
+		}
+		for name, result := range checks {
+			if got.Checks[name] != result {
+				t.Errorf("%s: expected %s to be %q, got %q", path, name, result, got.Checks[name])
+			}
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s: unexpected content type %q", path, ct)
+		}
+	}
+
+	check("/livez", http.StatusOK, "ok", map[string]string{"loop": "ok"})
+	check("/readyz", http.StatusOK, "ok", map[string]string{"db": "ok"})
+
+	dbErr = errors.New("connection refused")
+	check("/readyz", http.StatusServiceUnavailable, "failed", map[string]string{"db": "connection refused"})
+
+	dbErr = nil
+	health.Drain()
+	if !health.Draining() {
+		t.Error("expected draining")
+	}
+	check("/readyz", http.StatusServiceUnavailable, "draining", nil)
+	check("/livez", http.StatusOK, "ok", map[string]string{"loop": "ok"})
+
+	health.Resume()
+	check("/readyz", http.StatusOK, "ok", map[string]string{"db": "ok"})
+
+	other := New()
+	other.Health(WithHealthPaths("/health/live", "/health/ready"))
+	for _, path := range []string{"/health/live", "/health/ready"} {
+		w := httptest.NewRecorder()
+		other.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK || w.Body.String() != "{\"status\":\"ok\"}\n" {
+			t.Errorf("%s: unexpected response %d %q", path, w.Code, w.Body.String())
+		}
+	}
+}
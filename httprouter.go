@@ -0,0 +1,53 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"strings"
+)
+
+// FromHTTPRouter adapts a handle of github.com/julienschmidt/httprouter, so
+// that handlers of an httprouter code base can be registered unchanged:
+//
+//	func showUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+//		fmt.Fprintf(w, "user %s", ps.ByName("id"))
+//	}
+//
+//	router.Handle(http.MethodGet, httpmux.FromHTTPRouterPath("/users/:id"), httpmux.FromHTTPRouter(showUser))
+//
+// The params are reconstructed from the path values of the matched route, in
+// the order of the route's path. Like with httprouter, the value of a
+// catch-all parameter starts with "/". The type parameters are inferred;
+// httprouter needs not be imported by this package.
+func FromHTTPRouter[Params ~[]Param, Param ~struct{ Key, Value string }](handle func(http.ResponseWriter, *http.Request, Params)) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var ps Params
+		for _, name := range patternParams(req.Pattern) {
+			ps = append(ps, Param{Key: name, Value: req.PathValue(name)})
+		}
+		handle(w, req, ps)
+	}
+}
+
+// FromHTTPRouterPath converts a path of httprouter syntax to the syntax of
+// this package: named parameters like :id become {id} and catch-all
+// parameters like *filepath become {filepath...}.
+//
+//	httpmux.FromHTTPRouterPath("/src/:repo/*filepath") // "/src/{repo}/{filepath...}"
+func FromHTTPRouterPath(path string) string {
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		if len(seg) > 1 {
+			switch seg[0] {
+			case ':':
+				segs[i] = "{" + seg[1:] + "}"
+			case '*':
+				segs[i] = "{" + seg[1:] + "...}"
+			}
+		}
+	}
+	return strings.Join(segs, "/")
+}
@@ -0,0 +1,49 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// hrParam and hrParams mimic httprouter.Param and httprouter.Params.
+type hrParam struct {
+	Key   string
+	Value string
+}
+
+type hrParams []hrParam
+
+func TestFromHTTPRouter(t *testing.T) {
+	var got hrParams
+	router := New()
+	router.Handle(http.MethodGet, FromHTTPRouterPath("/src/:repo/*filepath"), FromHTTPRouter(func(w http.ResponseWriter, r *http.Request, ps hrParams) {
+		got = ps
+	}))
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/src/httpmux/tree/main.go", nil))
+	want := hrParams{{"repo", "httpmux"}, {"filepath", "/tree/main.go"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected params %v, got %v", want, got)
+	}
+}
+
+func TestFromHTTPRouterPath(t *testing.T) {
+	for path, want := range map[string]string{
+		"/":                      "/",
+		"/users/:id":             "/users/{id}",
+		"/users/:id/posts/:post": "/users/{id}/posts/{post}",
+		"/src/*filepath":         "/src/{filepath...}",
+		"/static/file:name.txt":  "/static/file:name.txt",
+		"/:lang/docs/*path":      "/{lang}/docs/{path...}",
+	} {
+		if got := FromHTTPRouterPath(path); got != want {
+			t.Errorf("%s: expected %s, got %s", path, want, got)
+		}
+	}
+}
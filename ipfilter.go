@@ -0,0 +1,169 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// IPFilterOption configures the middleware of IPFilter.
+type IPFilterOption func(*ipFilter)
+
+// AllowIPs allows only clients with addresses in the networks, given in CIDR
+// notation like "10.0.0.0/8" or as single addresses. It panics if an address
+// is invalid.
+func AllowIPs(networks ...string) IPFilterOption {
+	prefixes := parsePrefixes(networks)
+	return func(f *ipFilter) {
+		f.allow = append(f.allow, prefixes...)
+	}
+}
+
+// DenyIPs denies clients with addresses in the networks, see AllowIPs. Denied
+// networks take precedence over allowed ones. It panics if an address is
+// invalid.
+func DenyIPs(networks ...string) IPFilterOption {
+	prefixes := parsePrefixes(networks)
+	return func(f *ipFilter) {
+		f.deny = append(f.deny, prefixes...)
+	}
+}
+
+// TrustProxies takes the client address from the X-Forwarded-For header, or
+// the header set by ClientIPHeader, of requests from the networks, e.g. of
+// load balancers. The address is the last one of the header not belonging to
+// the networks, so that clients cannot spoof it. It panics if an address is
+// invalid.
+func TrustProxies(networks ...string) IPFilterOption {
+	prefixes := parsePrefixes(networks)
+	return func(f *ipFilter) {
+		f.proxies = append(f.proxies, prefixes...)
+	}
+}
+
+// ClientIPHeader sets the header trusted proxies pass the client address in,
+// e.g. "X-Real-IP". It defaults to "X-Forwarded-For".
+func ClientIPHeader(name string) IPFilterOption {
+	return func(f *ipFilter) {
+		f.header = name
+	}
+}
+
+// IPFilterDenied sets the handler for requests of denied clients, which are
+// answered with 403 Forbidden by default.
+func IPFilterDenied(handler http.Handler) IPFilterOption {
+	return func(f *ipFilter) {
+		f.denied = handler
+	}
+}
+
+// IPFilter returns a middleware filtering requests by the address of the
+// client, e.g. to restrict admin groups to internal networks:
+//
+//	multi.Group("/admin", admin, httpmux.WithMiddleware(httpmux.IPFilter(
+//		httpmux.AllowIPs("10.0.0.0/8", "192.168.1.17"),
+//		httpmux.TrustProxies("10.1.0.0/16"),
+//	)))
+//
+// Requests are allowed unless the client is in a network of DenyIPs or, if
+// AllowIPs is given, not in one of its networks. Clients whose address cannot
+// be determined are denied if allowed networks are given.
+func IPFilter(opts ...IPFilterOption) func(http.Handler) http.Handler {
+	f := &ipFilter{header: "X-Forwarded-For"}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f.middleware
+}
+
+type ipFilter struct {
+	allow, deny, proxies []netip.Prefix
+	header               string
+	denied               http.Handler
+}
+
+func (f *ipFilter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if f.allowed(f.clientIP(req)) {
+			next.ServeHTTP(w, req)
+		} else if f.denied != nil {
+			f.denied.ServeHTTP(w, req)
+		} else {
+			writeError(w, req, "403 forbidden", http.StatusForbidden)
+		}
+	})
+}
+
+func (f *ipFilter) allowed(ip netip.Addr) bool {
+	if !ip.IsValid() {
+		return len(f.allow) == 0
+	}
+	if containsIP(f.deny, ip) {
+		return false
+	}
+	return len(f.allow) == 0 || containsIP(f.allow, ip)
+}
+
+// clientIP returns the address of the client, the invalid address if it
+// cannot be parsed.
+func (f *ipFilter) clientIP(req *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	ip = ip.Unmap()
+
+	// Walk the forwarded addresses from the nearest proxy
+	values := req.Header.Values(f.header)
+	for i := len(values) - 1; i >= 0 && containsIP(f.proxies, ip); i-- {
+		addrs := strings.Split(values[i], ",")
+		for j := len(addrs) - 1; j >= 0 && containsIP(f.proxies, ip); j-- {
+			forwarded, err := netip.ParseAddr(strings.TrimSpace(addrs[j]))
+			if err != nil {
+				return netip.Addr{}
+			}
+			ip = forwarded.Unmap()
+		}
+	}
+	return ip
+}
+
+func containsIP(prefixes []netip.Prefix, ip netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePrefixes parses networks in CIDR notation or single addresses.
+func parsePrefixes(networks []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(networks))
+	for _, network := range networks {
+		var p netip.Prefix
+		var err error
+		if strings.Contains(network, "/") {
+			p, err = netip.ParsePrefix(network)
+		} else {
+			var ip netip.Addr
+			if ip, err = netip.ParseAddr(network); err == nil {
+				p = netip.PrefixFrom(ip, ip.BitLen())
+			}
+		}
+		if err != nil {
+			panic("invalid network '" + network + "': " + err.Error())
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes
+}
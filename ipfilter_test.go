@@ -0,0 +1,82 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIPFilter(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {})
+
+	tests := []struct {
+		name      string
+		opts      []IPFilterOption
+		addr      string
+		forwarded []string
+		code      int
+	}{
+		{"no rules", nil, "1.2.3.4:1", nil, http.StatusOK},
+		{"allowed", []IPFilterOption{AllowIPs("10.0.0.0/8")}, "10.1.2.3:1", nil, http.StatusOK},
+		{"not allowed", []IPFilterOption{AllowIPs("10.0.0.0/8")}, "11.1.2.3:1", nil, http.StatusForbidden},
+		{"single address", []IPFilterOption{AllowIPs("192.168.1.17")}, "192.168.1.17:1", nil, http.StatusOK},
+		{"ipv6", []IPFilterOption{AllowIPs("fd00::/8")}, "[fd00::1]:1", nil, http.StatusOK},
+		{"mapped ipv4", []IPFilterOption{AllowIPs("10.0.0.0/8")}, "[::ffff:10.0.0.1]:1", nil, http.StatusOK},
+		{"denied", []IPFilterOption{DenyIPs("10.6.0.0/16")}, "10.6.0.1:1", nil, http.StatusForbidden},
+		{"deny wins", []IPFilterOption{AllowIPs("10.0.0.0/8"), DenyIPs("10.6.0.0/16")}, "10.6.0.1:1", nil, http.StatusForbidden},
+		{"untrusted forwarded", []IPFilterOption{AllowIPs("10.0.0.0/8")}, "1.2.3.4:1", []string{"10.0.0.1"}, http.StatusForbidden},
+		{"trusted forwarded", []IPFilterOption{AllowIPs("10.0.0.0/8"), TrustProxies("172.16.0.0/12")},
+			"172.16.0.1:1", []string{"1.1.1.1, 10.0.0.1, 172.16.0.2"}, http.StatusOK},
+		{"spoofed forwarded", []IPFilterOption{AllowIPs("10.0.0.0/8"), TrustProxies("172.16.0.0/12")},
+			"172.16.0.1:1", []string{"10.0.0.1", "1.1.1.1"}, http.StatusForbidden},
+		{"invalid forwarded", []IPFilterOption{DenyIPs("10.0.0.0/8"), TrustProxies("172.16.0.0/12")},
+			"172.16.0.1:1", []string{"unknown"}, http.StatusOK},
+		{"invalid remote", []IPFilterOption{AllowIPs("10.0.0.0/8")}, "pipe", nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tt.addr
+		for _, v := range tt.forwarded {
+			req.Header.Add("X-Forwarded-For", v)
+		}
+		w := httptest.NewRecorder()
+		IPFilter(tt.opts...)(ok).ServeHTTP(w, req)
+		if w.Code != tt.code {
+			t.Errorf("%s: got %d, want %d", tt.name, w.Code, tt.code)
+		}
+	}
+}
+
+func TestIPFilterOptions(t *testing.T) {
+	denied := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	filter := IPFilter(
+		AllowIPs("10.0.0.0/8"),
+		TrustProxies("127.0.0.1"),
+		ClientIPHeader("X-Real-IP"),
+		IPFilterDenied(denied),
+	)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+
+	for ip, code := range map[string]int{"10.0.0.1": http.StatusOK, "8.8.8.8": http.StatusNotFound} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "127.0.0.1:80"
+		req.Header.Set("X-Real-IP", ip)
+		w := httptest.NewRecorder()
+		filter.ServeHTTP(w, req)
+		if w.Code != code {
+			t.Errorf("%s: got %d, want %d", ip, w.Code, code)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for an invalid network")
+		}
+	}()
+	AllowIPs("10.0.0.0/33")
+}
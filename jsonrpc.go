@@ -0,0 +1,257 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+)
+
+// Error codes of JSON-RPC 2.0
+const (
+	JSONRPCParseError     = -32700
+	JSONRPCInvalidRequest = -32600
+	JSONRPCMethodNotFound = -32601
+	JSONRPCInvalidParams  = -32602
+	JSONRPCInternalError  = -32603
+	JSONRPCServerError    = -32000
+)
+
+// JSONRPCError is the error object of a JSON-RPC 2.0 response. Methods may
+// return it to answer with a specific code.
+type JSONRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *JSONRPCError) Error() string {
+	return e.Message
+}
+
+// JSONRPC is a JSON-RPC 2.0 endpoint mounted by Router.JSONRPC.
+type JSONRPC struct {
+	mu      sync.RWMutex
+	methods map[string]reflect.Value
+}
+
+// JSONRPC mounts a JSON-RPC 2.0 endpoint at POST path and returns it to
+// register its methods:
+//
+//	rpc := router.JSONRPC("/rpc")
+//	rpc.Register("users.get", func(ctx context.Context, in GetUser) (*User, error) { ... })
+//	rpc.Service("users", &Users{db: db}) // users.Get, users.Purge, ...
+//
+// Calls are served by the route, so they pass the middlewares of the router
+// and the panic handler like any other request; the request's context is
+// passed to the methods. Batches are answered with an array of the responses
+// of their calls, which are made in order. Requests consisting of
+// notifications only are answered with 204 No Content.
+//
+// Like with Service, the params are decoded into the input of the method,
+// which may also be given as an array with a single element, and validated by
+// the validator set by SetValidator, if any. Params which cannot be decoded or
+// validated are answered with the code JSONRPCInvalidParams and the field
+// errors as data. Errors returned by methods are answered with the code
+// JSONRPCServerError, unless they are a *JSONRPCError. As with RespondError,
+// only the message of a StatusError is passed on; other errors are answered
+// with "server error" and logged to the logger of the router.
+func (r *Router) JSONRPC(path string) *JSONRPC {
+	s := &JSONRPC{methods: make(map[string]reflect.Value)}
+	r.POST(path, s.serveHTTP)
+	return s
+}
+
+// Register registers the function as the method with the name. The function
+// must have the form func(context.Context[, In]) ([Out, ]error). Register
+// panics if it does not or if the name is already registered.
+func (s *JSONRPC) Register(name string, fn any) {
+	fv := reflect.ValueOf(fn)
+	if fv.Kind() != reflect.Func || !isRPCMethod(fv.Type()) {
+		panic("JSON-RPC method '" + name + "' must be of the form func(context.Context[, In]) ([Out, ]error)")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.methods[name]; ok {
+		panic("JSON-RPC method '" + name + "' is already registered")
+	}
+	s.methods[name] = fv
+}
+
+// Service registers the exported methods of the service of the form accepted
+// by Register as the methods {name}.{Method}, see Router.Service. Service
+// panics if the service has no suitable method.
+func (s *JSONRPC) Service(name string, service any) {
+	sv := reflect.ValueOf(service)
+	if name == "" {
+		name = reflect.Indirect(sv).Type().Name()
+	}
+
+	registered := 0
+	for i := range sv.NumMethod() {
+		if isRPCMethod(sv.Method(i).Type()) {
+			s.Register(name+"."+sv.Type().Method(i).Name, sv.Method(i).Interface())
+			registered++
+		}
+	}
+	if registered == 0 {
+		panic("service '" + name + "' has no method of the form func(context.Context[, In]) ([Out, ]error)")
+	}
+}
+
+type jsonRPCRequest struct {
+	Version string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type jsonRPCResponse struct {
+	Version string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *JSONRPCError   `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+var jsonNull = json.RawMessage("null")
+
+func (s *JSONRPC) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	var body json.RawMessage
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeJSONRPC(w, jsonRPCErrorResponse(nil, JSONRPCParseError, "parse error: "+err.Error()))
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '[' {
+		if resp, ok := s.call(req, body); ok {
+			writeJSONRPC(w, resp)
+		} else {
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil || len(batch) == 0 {
+		writeJSONRPC(w, jsonRPCErrorResponse(nil, JSONRPCInvalidRequest, "invalid request: empty batch"))
+		return
+	}
+	var responses []*jsonRPCResponse
+	for _, call := range batch {
+		if resp, ok := s.call(req, call); ok {
+			responses = append(responses, resp)
+		}
+	}
+	if len(responses) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSONRPC(w, responses)
+}
+
+// call makes a single call of the request and returns its response, or false
+// if it is a notification.
+func (s *JSONRPC) call(req *http.Request, data json.RawMessage) (*jsonRPCResponse, bool) {
+	var call jsonRPCRequest
+	if err := json.Unmarshal(data, &call); err != nil || call.Version != "2.0" || call.Method == "" {
+		return jsonRPCErrorResponse(nil, JSONRPCInvalidRequest, "invalid request"), true
+	}
+	isNotification := call.ID == nil
+
+	s.mu.RLock()
+	method, ok := s.methods[call.Method]
+	s.mu.RUnlock()
+	if !ok {
+		return jsonRPCErrorResponse(call.ID, JSONRPCMethodNotFound, "method not found: "+call.Method), !isNotification
+	}
+
+	mt := method.Type()
+	args := []reflect.Value{reflect.ValueOf(req.Context())}
+	if mt.NumIn() == 2 {
+		in, err := decodeJSONRPCParams(req, mt.In(1), call.Params)
+		if err != nil {
+			resp := jsonRPCErrorResponse(call.ID, JSONRPCInvalidParams, "invalid params: "+err.Error())
+			var be *BindError
+			var ve *ValidationError
+			switch {
+			case errors.As(err, &be):
+				resp.Error.Data = be.Errors
+			case errors.As(err, &ve):
+				resp.Error.Data = ve.Errors
+			}
+			return resp, !isNotification
+		}
+		args = append(args, in)
+	}
+
+	results := method.Call(args)
+	if err, _ := results[len(results)-1].Interface().(error); err != nil {
+		var re *JSONRPCError
+		var se StatusError
+		switch {
+		case errors.As(err, &re):
+		case errors.As(err, &se):
+			re = &JSONRPCError{Code: JSONRPCServerError, Message: err.Error()}
+		default:
+			logError(req, err)
+			re = &JSONRPCError{Code: JSONRPCServerError, Message: "server error"}
+		}
+		return &jsonRPCResponse{Version: "2.0", Error: re, ID: call.ID}, !isNotification
+	}
+
+	result := jsonNull
+	if mt.NumOut() == 2 {
+		var err error
+		if result, err = json.Marshal(results[0].Interface()); err != nil {
+			logError(req, err)
+			return jsonRPCErrorResponse(call.ID, JSONRPCInternalError, "internal error"), !isNotification
+		}
+	}
+	return &jsonRPCResponse{Version: "2.0", Result: result, ID: call.ID}, !isNotification
+}
+
+// decodeJSONRPCParams decodes the params into a new input of the type and
+// validates it.
+func decodeJSONRPCParams(req *http.Request, t reflect.Type, params json.RawMessage) (reflect.Value, error) {
+	in := newRPCInput(t)
+	params = bytes.TrimSpace(params)
+	if len(params) > 0 && params[0] == '[' && t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+		var positional []json.RawMessage
+		if err := json.Unmarshal(params, &positional); err != nil || len(positional) > 1 {
+			return in, &BindError{Errors: []FieldError{{Source: "body", Message: "expected a single positional param"}}}
+		}
+		params = nil
+		if len(positional) == 1 {
+			params = positional[0]
+		}
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, in.Interface()); err != nil {
+			return in, &BindError{Errors: []FieldError{{Source: "body", Message: err.Error()}}}
+		}
+	}
+	if err := validateRequestValue(req, in.Elem().Interface()); err != nil {
+		return in, err
+	}
+	return in.Elem(), nil
+}
+
+func jsonRPCErrorResponse(id json.RawMessage, code int, msg string) *jsonRPCResponse {
+	if id == nil {
+		id = jsonNull
+	}
+	return &jsonRPCResponse{Version: "2.0", Error: &JSONRPCError{Code: code, Message: msg}, ID: id}
+}
+
+func writeJSONRPC(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,121 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type jsonRPCCalc struct{}
+
+type jsonRPCPair struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+func (jsonRPCCalc) Add(_ context.Context, in jsonRPCPair) (int, error) {
+	return in.A + in.B, nil
+}
+
+func (jsonRPCCalc) Div(_ context.Context, in jsonRPCPair) (int, error) {
+	if in.B == 0 {
+		return 0, &JSONRPCError{Code: 1, Message: "division by zero"}
+	}
+	return in.A / in.B, nil
+}
+
+func (jsonRPCCalc) Fail(context.Context) error {
+	return errors.New("failed")
+}
+
+func (jsonRPCCalc) Find(context.Context) error {
+	return rpcNotFound{}
+}
+
+func TestJSONRPC(t *testing.T) {
+	router := New()
+	var calls []string
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls = append(calls, r.Pattern)
+			next.ServeHTTP(w, r)
+		})
+	})
+	rpc := router.JSONRPC("/rpc")
+	rpc.Service("calc", jsonRPCCalc{})
+	rpc.Register("echo", func(_ context.Context, s string) (string, error) { return s, nil })
+	router.SetValidator(func(v any) error {
+		if p, ok := v.(jsonRPCPair); ok && p.A < 0 {
+			return errors.New("a must not be negative")
+		}
+		return nil
+	})
+
+	tests := []struct {
+		name, body, want string
+		code             int
+	}{
+		{"call", `{"jsonrpc":"2.0","method":"calc.Add","params":{"a":1,"b":2},"id":1}`,
+			`{"jsonrpc":"2.0","result":3,"id":1}`, http.StatusOK},
+		{"positional", `{"jsonrpc":"2.0","method":"echo","params":["hi"],"id":"x"}`,
+			`{"jsonrpc":"2.0","result":"hi","id":"x"}`, http.StatusOK},
+		{"no output", `{"jsonrpc":"2.0","method":"calc.Fail","id":2}`,
+			`{"jsonrpc":"2.0","error":{"code":-32000,"message":"server error"},"id":2}`, http.StatusOK},
+		{"status error", `{"jsonrpc":"2.0","method":"calc.Find","id":2}`,
+			`{"jsonrpc":"2.0","error":{"code":-32000,"message":"no such user"},"id":2}`, http.StatusOK},
+		{"rpc error", `{"jsonrpc":"2.0","method":"calc.Div","params":{"a":1},"id":3}`,
+			`{"jsonrpc":"2.0","error":{"code":1,"message":"division by zero"},"id":3}`, http.StatusOK},
+		{"invalid params", `{"jsonrpc":"2.0","method":"calc.Add","params":{"a":"x"},"id":4}`,
+			`{"jsonrpc":"2.0","error":{"code":-32602,"message":"invalid params: invalid request: body: json: cannot unmarshal string into Go struct field jsonRPCPair.a of type int","data":[{"source":"body","message":"json: cannot unmarshal string into Go struct field jsonRPCPair.a of type int"}]},"id":4}`, http.StatusOK},
+		{"validation", `{"jsonrpc":"2.0","method":"calc.Add","params":{"a":-1},"id":5}`,
+			`{"jsonrpc":"2.0","error":{"code":-32602,"message":"invalid params: validation failed: a must not be negative","data":[{"message":"a must not be negative"}]},"id":5}`, http.StatusOK},
+		{"not found", `{"jsonrpc":"2.0","method":"nope","id":6}`,
+			`{"jsonrpc":"2.0","error":{"code":-32601,"message":"method not found: nope"},"id":6}`, http.StatusOK},
+		{"invalid request", `{"method":"echo","id":7}`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request"},"id":null}`, http.StatusOK},
+		{"parse error", `{`,
+			`{"jsonrpc":"2.0","error":{"code":-32700,"message":"parse error: unexpected EOF"},"id":null}`, http.StatusOK},
+		{"notification", `{"jsonrpc":"2.0","method":"echo","params":"hi"}`, ``, http.StatusNoContent},
+		{"batch", `[{"jsonrpc":"2.0","method":"calc.Add","params":{"a":1,"b":1},"id":1},{"jsonrpc":"2.0","method":"echo","params":"hi"},1]`,
+			`[{"jsonrpc":"2.0","result":2,"id":1},{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request"},"id":null}]`, http.StatusOK},
+		{"empty batch", `[]`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request: empty batch"},"id":null}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(tt.body)))
+		if got := strings.TrimSpace(w.Body.String()); w.Code != tt.code || got != tt.want {
+			t.Errorf("%s: got %d %s, want %d %s", tt.name, w.Code, got, tt.code, tt.want)
+		}
+	}
+	if len(calls) != len(tests) || calls[0] != "/rpc" {
+		t.Errorf("middleware saw %v", calls)
+	}
+}
+
+func TestJSONRPCRegisterPanics(t *testing.T) {
+	rpc := New().JSONRPC("/rpc")
+	rpc.Register("echo", func(_ context.Context, s string) (string, error) { return s, nil })
+
+	for name, register := range map[string]func(){
+		"duplicate": func() { rpc.Register("echo", func(context.Context) error { return nil }) },
+		"signature": func() { rpc.Register("bad", func(s string) string { return s }) },
+		"service":   func() { rpc.Service("empty", struct{}{}) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic", name)
+				}
+			}()
+			register()
+		}()
+	}
+}
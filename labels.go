@@ -0,0 +1,27 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"context"
+	"net/http"
+	"runtime/pprof"
+)
+
+// ProfileLabelKey is the key of the pprof label holding the registered path of
+// the matched route, if Router.ProfileLabels is set.
+var ProfileLabelKey = "route"
+
+// profileLabels wraps the handle of a route so that it runs with a pprof label
+// set to the route's path. The labels are also added to the request's context,
+// so that goroutines started with it can inherit them.
+func profileLabels(path string, handle http.Handler) http.Handler {
+	labels := pprof.Labels(ProfileLabelKey, path)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		pprof.Do(req.Context(), labels, func(ctx context.Context) {
+			handle.ServeHTTP(w, req.WithContext(ctx))
+		})
+	})
+}
@@ -0,0 +1,38 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
+	"testing"
+)
+
+func TestRouterProfileLabels(t *testing.T) {
+	var label string
+	var labeled bool
+	handler := func(_ http.ResponseWriter, req *http.Request) {
+		label, labeled = pprof.Label(req.Context(), ProfileLabelKey)
+	}
+
+	router := New()
+	router.GET("/plain", handler)
+	router.ProfileLabels = true
+	router.GET("/users/{id}", handler)
+
+	r, _ := http.NewRequest(http.MethodGet, "/users/42", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	if !labeled || label != "/users/{id}" {
+		t.Errorf("expected route label, got %q (%v)", label, labeled)
+	}
+
+	// Routes registered before enabling the option are not labeled
+	r, _ = http.NewRequest(http.MethodGet, "/plain", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	if labeled {
+		t.Errorf("unexpected label %q", label)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultLatencyBuckets are the histogram bucket upper bounds used by
+// RecordLatency if no buckets are given.
+var DefaultLatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// LatencyHistogram is a snapshot of the latency histogram of a single route.
+type LatencyHistogram struct {
+	Method string
+	Path   string
+
+	// Upper bounds of the buckets, in ascending order
+	Buckets []time.Duration
+
+	// Number of requests per bucket. Counts has one more element than
+	// Buckets, counting the requests slower than the largest bound.
+	Counts []uint64
+
+	// Total number of requests and the sum of their latencies
+	Count uint64
+	Sum   time.Duration
+}
+
+// Mean returns the average latency of the route.
+func (h LatencyHistogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Quantile returns an upper bound of the q-quantile (0 <= q <= 1) of the
+// latencies, i.e. the bound of the bucket the quantile falls into.
+// If it falls into the overflow bucket, -1 is returned.
+func (h LatencyHistogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := uint64(q * float64(h.Count))
+	if rank == 0 {
+		rank = 1
+	}
+	var seen uint64
+	for i, bound := range h.Buckets {
+		seen += h.Counts[i]
+		if seen >= rank {
+			return bound
+		}
+	}
+	return -1
+}
+
+// routeLatency holds the latency histogram of a single route.
+type routeLatency struct {
+	method string
+	path   string
+	counts []atomic.Uint64
+	count  atomic.Uint64
+	sum    atomic.Int64
+}
+
+func (l *routeLatency) record(buckets []time.Duration, d time.Duration) {
+	i := sort.Search(len(buckets), func(i int) bool { return d <= buckets[i] })
+	l.counts[i].Add(1)
+	l.count.Add(1)
+	l.sum.Add(int64(d))
+}
+
+// latencyRegistry keeps the latency histograms of all routes of a router.
+type latencyRegistry struct {
+	buckets []time.Duration
+	mu      sync.RWMutex
+	routes  map[string]*routeLatency
+}
+
+// measure wraps the handle of a route so that its latency is recorded.
+func (lr *latencyRegistry) measure(method, path string, handle http.Handler) http.Handler {
+	key := method + " " + path
+
+	lr.mu.Lock()
+	l := lr.routes[key]
+	if l == nil {
+		l = &routeLatency{
+			method: method,
+			path:   path,
+			counts: make([]atomic.Uint64, len(lr.buckets)+1),
+		}
+		lr.routes[key] = l
+	}
+	lr.mu.Unlock()
+
+	buckets := lr.buckets
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		defer func() {
+			l.record(buckets, time.Since(start))
+		}()
+		handle.ServeHTTP(w, req)
+	})
+}
+
+func (lr *latencyRegistry) snapshot() []LatencyHistogram {
+	lr.mu.RLock()
+	out := make([]LatencyHistogram, 0, len(lr.routes))
+	for _, l := range lr.routes {
+		h := LatencyHistogram{
+			Method:  l.method,
+			Path:    l.path,
+			Buckets: lr.buckets,
+			Counts:  make([]uint64, len(l.counts)),
+			Count:   l.count.Load(),
+			Sum:     time.Duration(l.sum.Load()),
+		}
+		for i := range l.counts {
+			h.Counts[i] = l.counts[i].Load()
+		}
+		out = append(out, h)
+	}
+	lr.mu.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Path != out[j].Path {
+			return out[i].Path < out[j].Path
+		}
+		return out[i].Method < out[j].Method
+	})
+	return out
+}
+
+// RecordLatency enables per-route latency histograms, which can be retrieved
+// using Latencies. The given bucket upper bounds are used, or
+// DefaultLatencyBuckets if none are given.
+// The latency is measured from the time the route was matched until its
+// handler returned. Only routes registered after the call are measured.
+func (r *Router) RecordLatency(buckets ...time.Duration) {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]time.Duration(nil), buckets...)
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+
+	r.latency = &latencyRegistry{
+		buckets: buckets,
+		routes:  make(map[string]*routeLatency),
+	}
+}
+
+// Latencies returns a snapshot of the latency histograms of all measured
+// routes, sorted by path and method. It returns nil if latency recording is
+// not enabled.
+func (r *Router) Latencies() []LatencyHistogram {
+	if r.latency == nil {
+		return nil
+	}
+	return r.latency.snapshot()
+}
@@ -0,0 +1,59 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRouterLatencies(t *testing.T) {
+	router := New()
+	if h := router.Latencies(); h != nil {
+		t.Errorf("expected nil histograms when disabled, got %v", h)
+	}
+
+	router.RecordLatency(time.Hour, time.Millisecond)
+	router.GET("/fast", func(_ http.ResponseWriter, _ *http.Request) {})
+	router.GET("/slow", func(_ http.ResponseWriter, _ *http.Request) {
+		time.Sleep(2 * time.Millisecond)
+	})
+
+	for _, path := range []string{"/fast", "/fast", "/slow"} {
+		r, _ := http.NewRequest(http.MethodGet, path, nil)
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	hs := router.Latencies()
+	if len(hs) != 2 {
+		t.Fatalf("expected 2 histograms, got %d", len(hs))
+	}
+	buckets := []time.Duration{time.Millisecond, time.Hour}
+	for _, h := range hs {
+		if !reflect.DeepEqual(h.Buckets, buckets) {
+			t.Errorf("%s: unexpected buckets %v", h.Path, h.Buckets)
+		}
+	}
+
+	fast, slow := hs[0], hs[1]
+	if fast.Path != "/fast" || fast.Count != 2 || !reflect.DeepEqual(fast.Counts, []uint64{2, 0, 0}) {
+		t.Errorf("unexpected histogram for /fast: %+v", fast)
+	}
+	if slow.Path != "/slow" || slow.Count != 1 || !reflect.DeepEqual(slow.Counts, []uint64{0, 1, 0}) {
+		t.Errorf("unexpected histogram for /slow: %+v", slow)
+	}
+	if slow.Mean() < 2*time.Millisecond {
+		t.Errorf("unexpected mean latency for /slow: %v", slow.Mean())
+	}
+	if q := slow.Quantile(0.99); q != time.Hour {
+		t.Errorf("unexpected 99th percentile for /slow: %v", q)
+	}
+	if q := fast.Quantile(0.5); q != time.Millisecond {
+		t.Errorf("unexpected median for /fast: %v", q)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"sort"
+	"strings"
+)
+
+// LintWarning describes a questionable route registration found by Lint.
+type LintWarning struct {
+	Method string
+
+	// Registered paths of the routes involved
+	Routes []string
+
+	Message string
+}
+
+func (w LintWarning) String() string {
+	return w.Method + " " + strings.Join(w.Routes, ", ") + ": " + w.Message
+}
+
+// Lint checks the registered routes for registrations which are valid, but
+// likely mistakes, and returns a warning for each finding, sorted by method and
+// path. It is meant to be run in tests or at startup:
+//
+//	for _, w := range router.Lint() {
+//		log.Println(w)
+//	}
+//
+// If RedirectTrailingSlash is enabled, Lint reports routes which differ only
+// by a trailing slash, e.g. /users and /users/. Each of them shadows the
+// redirect to the other one, so clients adding or dropping the slash silently
+// reach a different handler.
+func (r *Router) Lint() []LintWarning {
+	var warnings []LintWarning
+
+	for method, root := range r.trees.Load().all() {
+		routes := make(map[string]bool)
+		root.walkRoutes(func(n *node) {
+			routes[n.fullPath] = true
+		})
+
+		if r.RedirectTrailingSlash {
+			for route := range routes {
+				if len(route) > 1 && route[len(route)-1] == '/' && routes[route[:len(route)-1]] {
+					warnings = append(warnings, LintWarning{
+						Method:  method,
+						Routes:  []string{route[:len(route)-1], route},
+						Message: "routes differ only by a trailing slash, which disables the trailing slash redirect between them",
+					})
+				}
+			}
+		}
+	}
+
+	sort.Slice(warnings, func(i, j int) bool {
+		if warnings[i].Method != warnings[j].Method {
+			return warnings[i].Method < warnings[j].Method
+		}
+		return warnings[i].Routes[0] < warnings[j].Routes[0]
+	})
+	return warnings
+}
+
+// walkRoutes calls fn for every node of the tree holding a handle.
+func (n *node) walkRoutes(fn func(n *node)) {
+	if n.handle != nil {
+		fn(n)
+	}
+	for _, child := range n.children {
+		child.walkRoutes(fn)
+	}
+}
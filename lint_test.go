@@ -0,0 +1,43 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRouterLintTrailingSlashTwins(t *testing.T) {
+	handlerFunc := func(_ http.ResponseWriter, _ *http.Request) {}
+
+	router := New()
+	router.GET("/", handlerFunc)
+	router.GET("/users", handlerFunc)
+	router.GET("/users/", handlerFunc)
+	router.GET("/users/{id}", handlerFunc)
+	router.GET("/users/{id}/", handlerFunc)
+	router.GET("/docs/", handlerFunc)
+	router.POST("/users", handlerFunc)
+	router.DELETE("/users/", handlerFunc)
+
+	warnings := router.Lint()
+	if len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", warnings)
+	}
+	want := []string{
+		"GET /users, /users/: routes differ only by a trailing slash, which disables the trailing slash redirect between them",
+		"GET /users/{id}, /users/{id}/: routes differ only by a trailing slash, which disables the trailing slash redirect between them",
+	}
+	for i, w := range warnings {
+		if w.String() != want[i] {
+			t.Errorf("unexpected warning %d:\n got %q\nwant %q", i, w, want[i])
+		}
+	}
+
+	router.RedirectTrailingSlash = false
+	if warnings := router.Lint(); len(warnings) != 0 {
+		t.Errorf("expected no warnings without trailing slash redirects, got %v", warnings)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Attribute keys used by all log records the package emits.
+const (
+	logKeyMethod    = "method"
+	logKeyPath      = "path"
+	logKeyRoute     = "route"
+	logKeyStatus    = "status"
+	logKeyBytes     = "bytes"
+	logKeyDuration  = "duration"
+	logKeyRequestID = "request_id"
+	logKeyPanic     = "panic"
+	logKeyError     = "error"
+)
+
+// SetLogger sets the logger used for registration warnings, conflict
+// diagnostics, recovered panics, internal errors answered by RespondError and,
+// if LogRequests is enabled, request logs.
+// A nil logger disables logging, which is the default.
+func (r *Router) SetLogger(logger *slog.Logger) {
+	r.logger = logger
+	r.updateSettings(func(s *settings) {
+		s.logger = logger
+	})
+}
+
+// Logger returns the logger set by SetLogger, or nil.
+func (r *Router) Logger() *slog.Logger {
+	return r.logger
+}
+
+// warnRegistration reports suspicious, but valid, route registrations.
+func (r *Router) warnRegistration(method, path string) {
+	if r.logger == nil {
+		return
+	}
+	if strings.Contains(path, "{$}") {
+		r.logger.Warn("httpmux: {$} is ignored, routes always match exactly",
+			slog.String(logKeyMethod, method),
+			slog.String(logKeyRoute, path),
+		)
+	}
+	if method != strings.ToUpper(method) {
+		r.logger.Warn("httpmux: method is not upper case and will only match requests using the exact same spelling",
+			slog.String(logKeyMethod, method),
+			slog.String(logKeyRoute, path),
+		)
+	}
+}
+
+// logPanic reports a panic recovered by the PanicHandler.
+func (r *Router) logPanic(req *http.Request, rcv interface{}) {
+	if r.logger == nil {
+		return
+	}
+	r.logger.LogAttrs(req.Context(), slog.LevelError, "httpmux: recovered from panic",
+		append(requestAttrs(req, req.URL.Path), slog.Any(logKeyPanic, rcv))...,
+	)
+}
+
+// logRequest writes the request log record for a request which started
+// at the given time.
+func (r *Router) logRequest(w WrappedWriter, req *http.Request, path string, start time.Time) {
+	r.logger.LogAttrs(req.Context(), slog.LevelInfo, "httpmux: request",
+		append(requestAttrs(req, path),
+			slog.Int(logKeyStatus, w.Status()),
+			slog.Int64(logKeyBytes, w.BytesWritten()),
+			slog.Duration(logKeyDuration, time.Since(start)),
+		)...,
+	)
+}
+
+// logError logs an internal error answered by RespondError to the logger of
+// the router whose route matched the request.
+func logError(req *http.Request, err error) {
+	if logger := requestSettings(req).logger; logger != nil {
+		logger.LogAttrs(req.Context(), slog.LevelError, "httpmux: internal error",
+			append(requestAttrs(req, req.URL.Path), slog.String(logKeyError, err.Error()))...,
+		)
+	}
+}
+
+// requestAttrs returns the attributes describing req common to all records.
+func requestAttrs(req *http.Request, path string) []slog.Attr {
+	attrs := make([]slog.Attr, 0, 8)
+	attrs = append(attrs,
+		slog.String(logKeyMethod, req.Method),
+		slog.String(logKeyPath, path),
+	)
+	if route := req.Pattern; route != "" {
+		attrs = append(attrs, slog.String(logKeyRoute, route))
+	}
+	if id := RequestID(req); id != "" {
+		attrs = append(attrs, slog.String(logKeyRequestID, id))
+	}
+	return attrs
+}
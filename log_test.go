@@ -0,0 +1,83 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestLogger() (*slog.Logger, *bytes.Buffer) {
+	buf := new(bytes.Buffer)
+	return slog.New(slog.NewTextHandler(buf, nil)), buf
+}
+
+func TestRouterLoggerRegistrationWarnings(t *testing.T) {
+	logger, buf := newTestLogger()
+	router := New()
+	router.SetLogger(logger)
+
+	router.GET("/exact/{$}", func(_ http.ResponseWriter, _ *http.Request) {})
+	router.HandleFunc("get", "/lower", func(_ http.ResponseWriter, _ *http.Request) {})
+
+	out := buf.String()
+	if !strings.Contains(out, "level=WARN") || !strings.Contains(out, "route=/exact/{$}") {
+		t.Errorf("expected warning for {$} route, got %q", out)
+	}
+	if !strings.Contains(out, "method=get") {
+		t.Errorf("expected warning for lower case method, got %q", out)
+	}
+}
+
+func TestRouterLoggerPanic(t *testing.T) {
+	logger, buf := newTestLogger()
+	router := New()
+	router.SetLogger(logger)
+	router.PanicHandler = func(w http.ResponseWriter, _ *http.Request, _ interface{}) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	router.GET("/panic", func(_ http.ResponseWriter, _ *http.Request) {
+		panic("oops!")
+	})
+
+	r, _ := http.NewRequest(http.MethodGet, "/panic", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	out := buf.String()
+	if !strings.Contains(out, "level=ERROR") || !strings.Contains(out, "panic=oops!") {
+		t.Errorf("expected panic to be logged, got %q", out)
+	}
+}
+
+func TestRouterLogRequests(t *testing.T) {
+	logger, buf := newTestLogger()
+	router := New()
+	router.SetLogger(logger)
+	router.GET("/user/{name}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("hello"))
+	})
+
+	r, _ := http.NewRequest(http.MethodGet, "/user/gopher", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	if buf.Len() != 0 {
+		t.Fatalf("expected no request log without LogRequests, got %q", buf.String())
+	}
+
+	router.LogRequests = true
+	r, _ = http.NewRequest(http.MethodGet, "/user/gopher", nil)
+	RequestIDHandler(router).ServeHTTP(httptest.NewRecorder(), r)
+
+	out := buf.String()
+	for _, want := range []string{"method=GET", "path=/user/gopher", "request_id=", "status=202", "bytes=5", "duration="} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in request log, got %q", want, out)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+)
+
+// RouteManifest is a declarative route table, e.g. read from a JSON or YAML
+// file, so that routes can be reviewed and diffed as configuration:
+//
+//	{
+//		"routes": [
+//			{"method": "GET", "path": "/users/{id}", "handler": "showUser",
+//			 "middleware": ["auth"], "metadata": {"owner": "accounts"}}
+//		]
+//	}
+type RouteManifest struct {
+	Routes []RouteSpec `json:"routes" yaml:"routes"`
+}
+
+// RouteSpec describes a single route of a RouteManifest.
+type RouteSpec struct {
+	Method string `json:"method" yaml:"method"`
+	Path   string `json:"path" yaml:"path"`
+
+	// Name of the handler in the HandlerRegistry
+	Handler string `json:"handler" yaml:"handler"`
+
+	// Names of middlewares in the HandlerRegistry, the first one being the
+	// outermost. They run inside the middlewares added by Router.Use.
+	Middleware []string `json:"middleware,omitempty" yaml:"middleware,omitempty"`
+
+	// Arbitrary metadata, which can be retrieved with Router.RouteMetadata
+	Metadata map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+}
+
+// ParseRouteManifest decodes a route manifest with the unmarshal function,
+// e.g. yaml.Unmarshal of a YAML package. If unmarshal is nil, the data is
+// decoded as JSON, rejecting unknown fields.
+func ParseRouteManifest(data []byte, unmarshal func([]byte, any) error) (*RouteManifest, error) {
+	m := new(RouteManifest)
+	if unmarshal != nil {
+		if err := unmarshal(data, m); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// HandlerRegistry holds named handlers and middlewares, which route manifests
+// refer to.
+type HandlerRegistry struct {
+	handlers    map[string]http.Handler
+	middlewares map[string]func(http.Handler) http.Handler
+}
+
+// NewHandlerRegistry returns an empty HandlerRegistry.
+func NewHandlerRegistry() *HandlerRegistry {
+	return &HandlerRegistry{
+		handlers:    make(map[string]http.Handler),
+		middlewares: make(map[string]func(http.Handler) http.Handler),
+	}
+}
+
+// Handler registers a handler under the name. It panics if the name is
+// already in use.
+func (reg *HandlerRegistry) Handler(name string, handler http.Handler) {
+	if _, ok := reg.handlers[name]; ok {
+		panic("handler '" + name + "' is already registered")
+	}
+	reg.handlers[name] = handler
+}
+
+// HandlerFunc registers a handler function under the name, see Handler.
+func (reg *HandlerRegistry) HandlerFunc(name string, handler http.HandlerFunc) {
+	reg.Handler(name, handler)
+}
+
+// Middleware registers a middleware under the name. It panics if the name is
+// already in use.
+func (reg *HandlerRegistry) Middleware(name string, middleware func(http.Handler) http.Handler) {
+	if _, ok := reg.middlewares[name]; ok {
+		panic("middleware '" + name + "' is already registered")
+	}
+	reg.middlewares[name] = middleware
+}
+
+// LoadRoutes registers the routes of the manifest with the handlers and
+// middlewares of the registry:
+//
+//	data, _ := os.ReadFile("routes.yaml")
+//	m, err := httpmux.ParseRouteManifest(data, yaml.Unmarshal)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	if err := router.LoadRoutes(m, registry); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// The whole manifest is validated before any route is registered, so that a
+// manifest with unknown handler or middleware names or incomplete routes does
+// not leave the router half configured. All problems found are reported
+// together. Routes conflicting with each other or with routes registered
+// before are reported as an error instead of a panic; the routes preceding
+// the conflicting one stay registered then.
+func (r *Router) LoadRoutes(m *RouteManifest, reg *HandlerRegistry) (err error) {
+	var errs []error
+	for i, spec := range m.Routes {
+		if spec.Method == "" || spec.Path == "" {
+			errs = append(errs, fmt.Errorf("route %d: method and path are required", i))
+		}
+		if _, ok := reg.handlers[spec.Handler]; !ok {
+			errs = append(errs, fmt.Errorf("route %d (%s %s): unknown handler '%s'", i, spec.Method, spec.Path, spec.Handler))
+		}
+		for _, name := range spec.Middleware {
+			if _, ok := reg.middlewares[name]; !ok {
+				errs = append(errs, fmt.Errorf("route %d (%s %s): unknown middleware '%s'", i, spec.Method, spec.Path, name))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	var spec RouteSpec
+	defer func() {
+		if recv := recover(); recv != nil {
+			err = fmt.Errorf("route %s %s: %v", spec.Method, spec.Path, recv)
+		}
+	}()
+	for _, spec = range m.Routes {
+		handler := reg.handlers[spec.Handler]
+		for i := len(spec.Middleware) - 1; i >= 0; i-- {
+			handler = reg.middlewares[spec.Middleware[i]](handler)
+		}
+		r.Handle(spec.Method, spec.Path, handler)
+
+		if len(spec.Metadata) > 0 {
+			// Copy-on-write, like the trees, so that lookups need no lock
+			r.mu.Lock()
+			metadata := make(map[string]map[string]string)
+			if old := r.metadata.Load(); old != nil {
+				metadata = maps.Clone(*old)
+			}
+			metadata[spec.Method+" "+spec.Path] = maps.Clone(spec.Metadata)
+			r.metadata.Store(&metadata)
+			r.mu.Unlock()
+		}
+	}
+	return nil
+}
+
+// RouteMetadata returns the metadata of the route loaded by LoadRoutes with
+// the method and registered path, or nil. Handlers and middlewares can look up
+// the metadata of the matched route with the request's Pattern:
+//
+//	meta := router.RouteMetadata(req.Method, req.Pattern)
+//
+// The returned map must not be modified.
+func (r *Router) RouteMetadata(method, path string) map[string]string {
+	metadata := r.metadata.Load()
+	if metadata == nil {
+		return nil
+	}
+	return (*metadata)[method+" "+path]
+}
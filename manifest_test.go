@@ -0,0 +1,109 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestRegistry() *HandlerRegistry {
+	reg := NewHandlerRegistry()
+	reg.HandlerFunc("showUser", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("user " + r.PathValue("id")))
+	})
+	reg.HandlerFunc("health", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	for _, name := range []string{"outer", "inner"} {
+		reg.Middleware(name, func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(name + " "))
+				next.ServeHTTP(w, r)
+			})
+		})
+	}
+	return reg
+}
+
+func TestRouterLoadRoutes(t *testing.T) {
+	m, err := ParseRouteManifest([]byte(`{
+		"routes": [
+			{"method": "GET", "path": "/users/{id}", "handler": "showUser",
+			 "middleware": ["outer", "inner"], "metadata": {"owner": "accounts"}},
+			{"method": "GET", "path": "/healthz", "handler": "health"}
+		]
+	}`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := New()
+	var meta map[string]string
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			meta = router.RouteMetadata(r.Method, r.Pattern)
+			next.ServeHTTP(w, r)
+		})
+	})
+	if err := router.LoadRoutes(m, newTestRegistry()); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	if w.Body.String() != "outer inner user 42" || meta["owner"] != "accounts" {
+		t.Errorf("unexpected response %q with metadata %v", w.Body.String(), meta)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Body.String() != "ok" || meta != nil {
+		t.Errorf("unexpected response %q with metadata %v", w.Body.String(), meta)
+	}
+}
+
+func TestRouterLoadRoutesErrors(t *testing.T) {
+	if _, err := ParseRouteManifest([]byte(`{"routes": [{"method": "GET", "pth": "/"}]}`), nil); err == nil {
+		t.Error("expected error for unknown field")
+	}
+
+	router := New()
+	err := router.LoadRoutes(&RouteManifest{Routes: []RouteSpec{
+		{Method: "GET", Path: "/a", Handler: "health"},
+		{Method: "GET", Path: "/b", Handler: "missing"},
+		{Method: "GET", Path: "/c", Handler: "health", Middleware: []string{"unknown"}},
+		{Path: "/d", Handler: "health"},
+	}}, newTestRegistry())
+	for _, want := range []string{"unknown handler 'missing'", "unknown middleware 'unknown'", "route 3: method and path are required"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got %v", want, err)
+		}
+	}
+	if len(router.getPaths()) != 0 {
+		t.Error("expected no routes to be registered for an invalid manifest")
+	}
+
+	router.GET("/a", dummyHandler)
+	err = router.LoadRoutes(&RouteManifest{Routes: []RouteSpec{
+		{Method: "GET", Path: "/a", Handler: "health"},
+	}}, newTestRegistry())
+	if err == nil || !strings.HasPrefix(err.Error(), "route GET /a: ") {
+		t.Errorf("expected conflict error, got %v", err)
+	}
+}
+
+func TestParseRouteManifestUnmarshal(t *testing.T) {
+	// A stand-in for yaml.Unmarshal
+	unmarshal := func(data []byte, v any) error {
+		v.(*RouteManifest).Routes = []RouteSpec{{Method: "GET", Path: string(data), Handler: "health"}}
+		return nil
+	}
+	m, err := ParseRouteManifest([]byte("/from-yaml"), unmarshal)
+	if err != nil || len(m.Routes) != 1 || m.Routes[0].Path != "/from-yaml" {
+		t.Errorf("unexpected manifest %+v, %v", m, err)
+	}
+}
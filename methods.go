@@ -0,0 +1,170 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"iter"
+	"maps"
+	"net/http"
+	"strings"
+)
+
+// The standard HTTP methods in alphabetical order, which is the order in which
+// they are listed in Allow headers.
+var standardMethods = [...]string{
+	http.MethodConnect,
+	http.MethodDelete,
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodPatch,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodTrace,
+}
+
+// Index of each method in standardMethods
+const (
+	methodConnect = iota
+	methodDelete
+	methodGet
+	methodHead
+	methodOptions
+	methodPatch
+	methodPost
+	methodPut
+	methodTrace
+)
+
+// methodIndex returns the index of method in standardMethods, or -1 for
+// non-standard methods.
+func methodIndex(method string) int {
+	switch method {
+	case http.MethodConnect:
+		return methodConnect
+	case http.MethodDelete:
+		return methodDelete
+	case http.MethodGet:
+		return methodGet
+	case http.MethodHead:
+		return methodHead
+	case http.MethodOptions:
+		return methodOptions
+	case http.MethodPatch:
+		return methodPatch
+	case http.MethodPost:
+		return methodPost
+	case http.MethodPut:
+		return methodPut
+	case http.MethodTrace:
+		return methodTrace
+	}
+	return -1
+}
+
+// methodSet is a bitmask of standard methods, bit i representing
+// standardMethods[i].
+type methodSet uint16
+
+// allowValues holds the Allow header value of every possible methodSet, so
+// that 405 and OPTIONS responses can be answered without allocating.
+var allowValues [1 << len(standardMethods)]string
+
+func init() {
+	for set := range allowValues {
+		methods := make([]string, 0, len(standardMethods))
+		for i, method := range standardMethods {
+			if set&(1<<i) != 0 {
+				methods = append(methods, method)
+			}
+		}
+		allowValues[set] = strings.Join(methods, ", ")
+	}
+}
+
+// methodTrees holds the route trees of a router, one per method.
+// The trees of standard methods are stored in an array indexed by
+// methodIndex, so that the hot path does not need to hash the method. Trees of
+// non-standard methods are kept in a map.
+//
+// A methodTrees value is an immutable snapshot once it was published by the
+// router; registrations build a modified copy. A nil *methodTrees has no
+// trees.
+type methodTrees struct {
+	standard [len(standardMethods)]*node
+	custom   map[string]*node
+
+	// Cached value of global (*) allowed methods
+	globalAllowed string
+
+	// Largest number of parameters of any route
+	maxParams uint16
+
+	// Whether the trees were compacted
+	compacted bool
+
+	// Whether any route has RouteOptions
+	routeOptions bool
+}
+
+// clone returns a copy of t which shares the trees with t.
+func (t *methodTrees) clone() *methodTrees {
+	c := new(methodTrees)
+	if t != nil {
+		*c = *t
+		c.custom = maps.Clone(t.custom)
+	}
+	return c
+}
+
+// get returns the tree of the given method, or nil.
+func (t *methodTrees) get(method string) *node {
+	if t == nil {
+		return nil
+	}
+	if i := methodIndex(method); i >= 0 {
+		return t.standard[i]
+	}
+	return t.custom[method]
+}
+
+// set stores the tree of the given method.
+func (t *methodTrees) set(method string, root *node) {
+	if i := methodIndex(method); i >= 0 {
+		t.standard[i] = root
+		return
+	}
+	if t.custom == nil {
+		t.custom = make(map[string]*node)
+	}
+	t.custom[method] = root
+}
+
+// all iterates over all methods which have a tree.
+func (t *methodTrees) all() iter.Seq2[string, *node] {
+	return func(yield func(string, *node) bool) {
+		if t == nil {
+			return
+		}
+		for i, root := range t.standard {
+			if root != nil && !yield(standardMethods[i], root) {
+				return
+			}
+		}
+		for method, root := range t.custom {
+			if !yield(method, root) {
+				return
+			}
+		}
+	}
+}
+
+// webSocket returns the tree of the WEBSOCKET routes, or nil.
+func (t *methodTrees) webSocket() *node {
+	if t == nil || t.custom == nil {
+		return nil
+	}
+	return t.custom[methodWebSocket]
+}
@@ -0,0 +1,93 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import "net/http"
+
+// Middlewares is a stack of middlewares, the first one being the outermost.
+// Middlewares of the func(http.Handler) http.Handler form, like those of chi
+// and most other routers of the ecosystem, can be used as they are.
+type Middlewares []func(http.Handler) http.Handler
+
+// Chain returns the middlewares as a stack, to be reused for several routes,
+// groups or routers:
+//
+//	common := httpmux.Chain(middleware.RequestID, middleware.RealIP, middleware.Recoverer)
+//	router.Use(common...)
+//	admin.Handle(http.MethodGet, "/stats", common.HandlerFunc(showStats))
+//
+// Middlewares added by Router.Use run after the route was matched, so they
+// and the handler see the request's Pattern and path values, also if a
+// middleware replaces the request with WithContext or Clone. Middlewares which
+// must run before routing, like chi's StripSlashes or CleanPath, have to wrap
+// the router instead:
+//
+//	http.ListenAndServe(":8080", middleware.StripSlashes(router))
+//
+// Middlewares depending on chi's routing context, like RouteHeaders or
+// URLFormat, do not work with a Router.
+func Chain(middlewares ...func(http.Handler) http.Handler) Middlewares {
+	return Middlewares(middlewares)
+}
+
+// Handler returns the handler wrapped by the middlewares.
+func (mws Middlewares) Handler(h http.Handler) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// HandlerFunc returns the handler function wrapped by the middlewares.
+func (mws Middlewares) HandlerFunc(h http.HandlerFunc) http.Handler {
+	return mws.Handler(h)
+}
+
+// AliceChain is implemented by alice.Chain of github.com/justinas/alice.
+type AliceChain interface {
+	Then(h http.Handler) http.Handler
+}
+
+// FromAlice returns an alice chain as a middleware, e.g. to run an existing
+// chain after routing, where its middlewares see the request's Pattern and
+// path values:
+//
+//	chain := alice.New(timeoutHandler, authHandler)
+//	router.Use(httpmux.FromAlice(chain))
+//
+// The middleware can be added to a group with WithMiddleware as well. A chain
+// wrapping the whole router, chain.Then(router), needs no adapter, but runs
+// before routing.
+func FromAlice(chain AliceChain) func(http.Handler) http.Handler {
+	return chain.Then
+}
+
+// NegroniHandler is implemented by the middlewares of github.com/urfave/negroni,
+// see negroni.Handler.
+type NegroniHandler interface {
+	ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc)
+}
+
+// FromNegroni returns negroni middlewares as a single middleware, the first
+// one being the outermost, so that a negroni stack can run after routing
+// instead of wrapping the router:
+//
+//	// Before: n := negroni.New(recovery, logger); n.UseHandler(mux)
+//	router.Use(httpmux.FromNegroni(recovery, logger))
+//
+// The request reaching each middleware is passed on to the next one, so
+// requests replaced by a middleware keep the Pattern and path values.
+func FromNegroni(handlers ...NegroniHandler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		h := next.ServeHTTP
+		for i := len(handlers) - 1; i >= 0; i-- {
+			handler, inner := handlers[i], h
+			h = func(w http.ResponseWriter, r *http.Request) {
+				handler.ServeHTTP(w, r, inner)
+			}
+		}
+		return http.HandlerFunc(h)
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The middlewares below reproduce how the chi middlewares of the same names
+// treat the request and the response writer.
+
+type chiCtxKey struct{}
+
+// chiRequestID stores an ID in the context with WithContext.
+func chiRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), chiCtxKey{}, "req-1")
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// chiRealIP modifies the request in place.
+func chiRealIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ip := r.Header.Get("X-Real-IP"); ip != "" {
+			r.RemoteAddr = ip
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// chiTimeout replaces the context with a deadline.
+func chiTimeout(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// chiGetHead clones the request, routing HEAD requests like GET.
+func chiGetHead(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.Clone(r.Context()))
+	})
+}
+
+// chiLogger wraps the response writer to record the status.
+func chiLogger(status *int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := WrapWriter(w)
+			next.ServeHTTP(ww, r)
+			*status = ww.Status()
+		})
+	}
+}
+
+// chiStripSlashes rewrites the path, which must happen before routing.
+func chiStripSlashes(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.URL.Path) > 1 && strings.HasSuffix(r.URL.Path, "/") {
+			r.URL.Path = strings.TrimSuffix(r.URL.Path, "/")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func TestChiMiddlewareCompatibility(t *testing.T) {
+	var status int
+	var pattern, id, reqID, remote string
+	var deadline bool
+
+	router := New()
+	router.Use(Chain(chiRequestID, chiRealIP, chiTimeout(time.Minute), chiGetHead, chiLogger(&status))...)
+	router.GET("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		pattern, id = r.Pattern, r.PathValue("id")
+		reqID, _ = r.Context().Value(chiCtxKey{}).(string)
+		remote = r.RemoteAddr
+		_, deadline = r.Context().Deadline()
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42/", nil)
+	req.Header.Set("X-Real-IP", "10.0.0.1")
+	chiStripSlashes(router).ServeHTTP(httptest.NewRecorder(), req)
+
+	if pattern != "/users/{id}" || id != "42" {
+		t.Errorf("expected pattern and path value, got %q and %q", pattern, id)
+	}
+	if reqID != "req-1" || remote != "10.0.0.1" || !deadline {
+		t.Errorf("middleware changes lost: request id %q, remote address %q, deadline %v", reqID, remote, deadline)
+	}
+	if status != http.StatusAccepted {
+		t.Errorf("expected the logger to record status 202, got %d", status)
+	}
+}
+
+func TestChain(t *testing.T) {
+	var order []string
+	mw := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	h := Chain(mw("a"), mw("b")).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	})
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := strings.Join(order, ","); got != "a,b,handler" {
+		t.Errorf("unexpected order %s", got)
+	}
+
+	if h := Chain().Handler(http.NotFoundHandler()); h == nil {
+		t.Error("expected the handler for an empty chain")
+	}
+}
+
+// aliceChain mimics alice.Chain.
+type aliceChain []func(http.Handler) http.Handler
+
+func (c aliceChain) Then(h http.Handler) http.Handler {
+	return Middlewares(c).Handler(h)
+}
+
+// negroniFunc mimics negroni.HandlerFunc.
+type negroniFunc func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc)
+
+func (f negroniFunc) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	f(w, r, next)
+}
+
+func TestFromAlice(t *testing.T) {
+	var pattern, reqID string
+	router := New()
+	router.Use(FromAlice(aliceChain{chiRequestID}))
+	router.GET("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		pattern = r.Pattern
+		reqID, _ = r.Context().Value(chiCtxKey{}).(string)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	if pattern != "/users/{id}" || reqID != "req-1" {
+		t.Errorf("unexpected pattern %q and request id %q", pattern, reqID)
+	}
+}
+
+func TestFromNegroni(t *testing.T) {
+	var order []string
+	var id string
+	tagged := negroniFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		order = append(order, "first")
+		next(w, r.WithContext(context.WithValue(r.Context(), chiCtxKey{}, "negroni")))
+	})
+	logger := negroniFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		order = append(order, "second:"+r.Context().Value(chiCtxKey{}).(string))
+		next(w, r)
+	})
+
+	router := New()
+	router.Use(FromNegroni(tagged, logger))
+	router.GET("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id = r.PathValue("id")
+		order = append(order, "handler")
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/7", nil))
+	if got := strings.Join(order, ","); got != "first,second:negroni,handler" {
+		t.Errorf("unexpected order %s", got)
+	}
+	if id != "7" {
+		t.Errorf("expected path value 7, got %q", id)
+	}
+}
@@ -36,82 +36,495 @@
 package httpmux
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"maps"
 	"net/http"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
-// MultiRouter routes requests to different routers based on path prefixes
+// MultiRouter routes requests to different routers based on path prefixes.
+// Groups, hosts and the default router can be registered while requests are
+// being served.
 type MultiRouter struct {
-	routes          map[string]*Router
-	defaultRouter   *Router
-	prefixes        []string // Keep track of prefixes in order for longest match
-	registeredPaths []string // Track all paths registered in default router
-	enableWarnings  bool
+	// Groups, hosts and the default router. Registrations replace the whole
+	// state under mu, so requests are served without locking.
+	state atomic.Pointer[multiState]
+	mu    sync.Mutex
+
+	registeredPaths []string     // Track all paths registered in default router
+	enableWarnings  bool         // Log conflicts instead of panicking
+	logger          *slog.Logger // Logger for conflicts, set by WarnOnConflict
+
+	// If enabled, requests dispatched to a group whose router finds no
+	// matching route are passed on to the default router, with the original
+	// path, instead of being answered with the group router's NotFound
+	// handler. Groups with their own NotFound handler, set by
+	// WithGroupNotFound, do not fall through. Redirects and 405 responses of
+	// the group router are unaffected.
+	// This lets e.g. an SPA in the default router own every unknown path.
+	FallthroughNotFound bool
+
+	// NewRouter creates the routers of groups added by NewGroup and the
+	// default router created by RegisterDefault, New if nil. It configures
+	// these routers alike, e.g. with the same panic handler and middlewares:
+	//
+	//	multi.NewRouter = func() *httpmux.Router {
+	//		r := httpmux.New()
+	//		r.RedirectTrailingSlash = false
+	//		r.PanicHandler = handlePanic
+	//		r.Use(logRequests)
+	//		return r
+	//	}
+	NewRouter func() *Router
+
+	// Handler for requests to groups disabled by Disable. If nil, they are
+	// answered with 503 Service Unavailable; http.NotFoundHandler() hides the
+	// groups instead.
+	Disabled http.Handler
+}
+
+// notFoundFallbackContextKey and methodNotAllowedContextKey hold handlers
+// which a Router calls instead of its NotFound and MethodNotAllowed handlers.
+var (
+	notFoundFallbackContextKey = &contextKey{"not-found-fallback"}
+	methodNotAllowedContextKey = &contextKey{"method-not-allowed"}
+)
+
+// multiState is a snapshot of the groups, hosts and the default router of a
+// MultiRouter. It must not be modified once published.
+type multiState struct {
+	groups        map[string]*group
+	prefixes      []string    // Prefixes in matching order, longest first
+	tree          *prefixNode // Prefixes by path segment, built from prefixes
+	defaultRouter *Router
+
+	hosts         map[string]http.Handler // Handlers by exact host name
+	wildcardHosts []hostHandler           // Handlers for *.domain, longest suffix first
+}
+
+// clone returns a copy of the state, which can be modified.
+func (s *multiState) clone() *multiState {
+	c := *s
+	c.groups = maps.Clone(s.groups)
+	c.prefixes = slices.Clone(s.prefixes)
+	c.hosts = maps.Clone(s.hosts)
+	c.wildcardHosts = slices.Clone(s.wildcardHosts)
+	return &c
+}
+
+// hostHandler is a handler registered for all subdomains of a domain.
+type hostHandler struct {
+	suffix  string // e.g. ".example.com"
+	handler http.Handler
 }
 
 // NewMultiRouter creates a new MultiRouter
 func NewMultiRouter() *MultiRouter {
-	return &MultiRouter{
-		routes:         make(map[string]*Router),
-		prefixes:       make([]string, 0),
-		enableWarnings: true,
+	m := &MultiRouter{}
+	m.state.Store(&multiState{
+		groups:   make(map[string]*group),
+		prefixes: make([]string, 0),
+		tree:     &prefixNode{},
+	})
+	return m
+}
+
+// WarnOnConflict makes the MultiRouter log route conflicts as warnings to the
+// given logger instead of panicking. The conflicting registration is applied
+// anyway, so requests may be routed to an unexpected group. This is meant for
+// staging environments, which should boot with misconfigured plugins and
+// report them. A nil logger restores the default, panicking on conflicts.
+//
+//	multi.WarnOnConflict(slog.Default())
+func (m *MultiRouter) WarnOnConflict(logger *slog.Logger) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enableWarnings = logger != nil
+	m.logger = logger
+}
+
+// conflict reports a route conflict. m.mu must be held.
+func (m *MultiRouter) conflict(msg string) {
+	if !m.enableWarnings {
+		panic(msg)
 	}
+	m.logger.Warn("httpmux: " + msg)
 }
 
+// Routes returns the routers of all groups, keyed by prefix.
+// Handlers added by Mount are not included.
 func (m *MultiRouter) Routes() map[string]*Router {
-	return m.routes
+	s := m.state.Load()
+	routes := make(map[string]*Router, len(s.groups))
+	for prefix, g := range s.groups {
+		if g.router != nil {
+			routes[prefix] = g.router
+		}
+	}
+	return routes
+}
+
+// Route is a route registered in a MultiRouter, as returned by AllRoutes.
+type Route struct {
+	Method string
+
+	// Path of the route including the group's prefix
+	Path string
+
+	// Name of the group owning the route, empty for the default router
+	Group string
+
+	// Name of the route set by the Name option, if any
+	Name string
+
+	// Tags of the route and its group, sorted
+	Tags []string
+}
+
+// AllRoutes returns the routes of all groups and of the default router, sorted
+// by path and method, e.g. to generate documentation or to audit the routes
+// exposed by an application. If tags are given, only the routes having all of
+// them are returned, see Tags and WithGroupTags.
+// Handlers added by Mount or Host are not included, since their routes are
+// unknown to the MultiRouter.
+func (m *MultiRouter) AllRoutes(tags ...string) []Route {
+	var routes []Route
+	s := m.state.Load()
+	for _, g := range s.groups {
+		if g.router != nil {
+			routes = g.router.collectRoutes(routes, g.name, g.tags, tags, g.fullPath)
+		}
+	}
+	if s.defaultRouter != nil {
+		routes = s.defaultRouter.collectRoutes(routes, "", nil, tags, nil)
+	}
+
+	sortRoutes(routes)
+	return routes
 }
 
-// Group registers a router for a specific path prefix
-func (m *MultiRouter) Group(prefix string, router *Router) {
+// normalizePrefix adds a leading and removes a trailing slash.
+func normalizePrefix(prefix string) string {
 	if prefix != "" && !strings.HasPrefix(prefix, "/") {
 		prefix = "/" + prefix
 	}
 	if prefix != "/" && strings.HasSuffix(prefix, "/") {
 		prefix = prefix[:len(prefix)-1]
 	}
+	return prefix
+}
 
-	// Check conflicts - just call GetPaths() directly
-	paths := router.getPaths()
+// Group registers a router for a specific path prefix.
+// The prefix may contain parameters spanning whole path segments, e.g.
+// "/tenants/{tenant}". Their values are available to the group's handlers via
+// Request.PathValue.
+//
+// Requests are dispatched to the group with the longest prefix matching whole
+// path segments, regardless of the order of registration: with groups for
+// "/api" and "/api/v2", "/api/v2/users" is dispatched to the latter and
+// "/api/v3" to the former, but "/apiv2" to neither. Static segments are
+// matched before parameters, so "/tenants/admin" takes precedence over
+// "/tenants/{tenant}". Routes of a group shadowed by a longer prefix are
+// reported as conflicts.
+//
+// The group is configured by options, e.g. to add middlewares:
+//
+//	multi.Group("/api", apiRouter, httpmux.WithMiddleware(authenticate))
+func (m *MultiRouter) Group(prefix string, router *Router, opts ...GroupOption) {
+	prefix = normalizePrefix(prefix)
+	validatePrefix(prefix)
+	g := newGroup(prefix, router, router, opts)
 
-	for _, path := range paths {
-		fullPath := prefix + path
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.state.Load().clone()
+	s.addGroup(g)
+	m.checkGroup(s, g)
+	m.state.Store(s)
+}
+
+// ReplaceGroup replaces the router and options of the group registered for the
+// prefix, e.g. to reload a plugin or provision the routes of a tenant at
+// runtime. The new router is checked for conflicts like in Group; requests are
+// dispatched to either the old or the new router, never to none.
+// It reports whether a group was registered for the prefix; if not, nothing is
+// registered.
+func (m *MultiRouter) ReplaceGroup(prefix string, router *Router, opts ...GroupOption) bool {
+	prefix = normalizePrefix(prefix)
+	g := newGroup(prefix, router, router, opts)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.state.Load().clone()
+	old, ok := s.groups[prefix]
+	if !ok {
+		return false
+	}
+	g.disabled = old.disabled
+	s.addGroup(g)
+	m.checkGroup(s, g)
+	m.state.Store(s)
+	return true
+}
+
+// Disable makes the group or mounted handler registered for the prefix answer
+// all requests with the Disabled handler, without removing its routes, e.g.
+// during a staged rollout or an incident. Requests are not passed on to other
+// groups or the default router. It reports whether a group was registered for
+// the prefix.
+func (m *MultiRouter) Disable(prefix string) bool {
+	return m.setDisabled(prefix, true)
+}
+
+// Enable reverts Disable. It reports whether a group was registered for the
+// prefix.
+func (m *MultiRouter) Enable(prefix string) bool {
+	return m.setDisabled(prefix, false)
+}
+
+func (m *MultiRouter) setDisabled(prefix string, disabled bool) bool {
+	prefix = normalizePrefix(prefix)
 
-		// Check against all existing group prefixes
-		for existingPrefix := range m.routes {
-			if existingPrefix != "/" && existingPrefix != prefix && strings.HasPrefix(fullPath, existingPrefix) {
-				panic(fmt.Sprintf("GROUP CONFLICT: Group '%s' route '%s' (full path: '%s') conflicts with existing group '%s'", prefix, path, fullPath, existingPrefix))
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.state.Load().clone()
+	g, ok := s.groups[prefix]
+	if !ok {
+		return false
+	}
+	c := *g
+	c.disabled = disabled
+	s.addGroup(&c)
+	m.state.Store(s)
+	return true
+}
+
+// RemoveGroup removes the group or mounted handler registered for the prefix.
+// Requests are dispatched to the other groups and the default router
+// afterwards. It reports whether a group was registered for the prefix.
+func (m *MultiRouter) RemoveGroup(prefix string) bool {
+	prefix = normalizePrefix(prefix)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.state.Load().clone()
+	if _, ok := s.groups[prefix]; !ok {
+		return false
+	}
+	delete(s.groups, prefix)
+	s.prefixes = slices.DeleteFunc(s.prefixes, func(p string) bool {
+		return p == prefix
+	})
+	s.tree = newPrefixTree(s.prefixes, s.groups)
+	m.state.Store(s)
+	return true
+}
+
+// checkGroup reports routes dispatched to a different group than the one
+// registering them, after g was added to s.
+func (m *MultiRouter) checkGroup(s *multiState, g *group) {
+	prefix := g.prefix
+	if prefix != "/" {
+		if first := s.tree.find(prefix); first != g {
+			m.conflict(fmt.Sprintf("GROUP CONFLICT: Group '%s' matches the same paths as existing group '%s'", prefix, first.prefix))
+		}
+	}
+
+	if g.router != nil {
+		for _, path := range g.router.getPaths() {
+			fullPath := g.fullPath(path)
+			if g.preservePath && strings.IndexByte(prefix, '{') < 0 && !strings.HasPrefix(path, prefix) {
+				m.conflict(fmt.Sprintf("GROUP CONFLICT: Group '%s' preserves the path, but its route '%s' does not start with the prefix", prefix, path))
+			}
+
+			if other := s.dispatch(fullPath); other == nil {
+				m.conflict(fmt.Sprintf("GROUP CONFLICT: Group '%s' route '%s' (full path: '%s') is dispatched to no group", prefix, path, fullPath))
+			} else if other != g {
+				m.conflict(fmt.Sprintf("GROUP CONFLICT: Group '%s' route '%s' (full path: '%s') conflicts with existing group '%s'", prefix, path, fullPath, other.prefix))
 			}
 		}
 	}
 
 	// Check existing groups
-	for existingPrefix, existingRouter := range m.routes {
-		if existingPrefix == "/" || existingPrefix == prefix {
+	for existingPrefix, existing := range s.groups {
+		if existing == g || existing.router == nil {
 			continue
 		}
 
-		existingPaths := existingRouter.getPaths()
-		for _, existingPath := range existingPaths {
-			fullExistingPath := existingPrefix + existingPath
-			if strings.HasPrefix(fullExistingPath, prefix) {
-				panic(fmt.Sprintf("GROUP CONFLICT: New group '%s' conflicts with existing route '%s' in group '%s'", prefix, fullExistingPath, existingPrefix))
+		for _, existingPath := range existing.router.getPaths() {
+			fullExistingPath := existing.fullPath(existingPath)
+			if s.dispatch(fullExistingPath) == g {
+				m.conflict(fmt.Sprintf("GROUP CONFLICT: New group '%s' conflicts with existing route '%s' in group '%s'", prefix, fullExistingPath, existingPrefix))
+			}
+		}
+	}
+}
+
+// dispatch returns the group requests with the path are dispatched to, or nil
+// if they are handled by the default router.
+func (s *multiState) dispatch(path string) *group {
+	if g := s.tree.lookup(path); g != nil {
+		return g
+	}
+	return s.groups["/"]
+}
+
+// Mount delegates all requests below the prefix to an arbitrary handler, e.g.
+// another router package or a third-party admin UI. The prefix is stripped
+// from the request path before the handler is called:
+//
+//	multi.Mount("/legacy", gorillaRouter)
+//
+// Since the routes of the handler are unknown, only the prefix itself is
+// checked for conflicts: it must not be registered yet, nor shadow routes of
+// existing groups. The same options as for Group apply.
+func (m *MultiRouter) Mount(prefix string, handler http.Handler, opts ...GroupOption) {
+	prefix = normalizePrefix(prefix)
+	if prefix == "" || prefix == "/" {
+		panic("MOUNT CONFLICT: Cannot mount a handler at the root, use Default instead")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.state.Load().clone()
+	if _, ok := s.groups[prefix]; ok {
+		m.conflict(fmt.Sprintf("MOUNT CONFLICT: Mount point '%s' conflicts with existing group '%s'", prefix, prefix))
+	}
+	g := newGroup(prefix, nil, handler, opts)
+	s.addGroup(g)
+	for existingPrefix, existing := range s.groups {
+		if existing.router == nil {
+			continue
+		}
+		for _, existingPath := range existing.router.getPaths() {
+			fullExistingPath := existing.fullPath(existingPath)
+			if s.dispatch(fullExistingPath) == g {
+				m.conflict(fmt.Sprintf("MOUNT CONFLICT: Mount point '%s' conflicts with existing route '%s' in group '%s'", prefix, fullExistingPath, existingPrefix))
 			}
 		}
 	}
+	m.state.Store(s)
+}
 
-	m.routes[prefix] = router
-	m.prefixes = append(m.prefixes, prefix)
+// Redirect redirects all requests below the prefix to the target, keeping the
+// rest of the path and the query string, e.g. after moving a section of a
+// site:
+//
+//	multi.Redirect("/old-admin", "/admin", http.StatusMovedPermanently)
+//
+// redirects /old-admin/users?page=2 to /admin/users?page=2. The target may be
+// an absolute URL. The prefix must be static; it is registered and checked
+// for conflicts like a handler added by Mount. Redirect panics if code is not
+// a 3xx status code.
+func (m *MultiRouter) Redirect(prefix, target string, code int) {
+	if code < 300 || code > 399 {
+		panic(fmt.Sprintf("invalid redirect code %d for prefix '%s'", code, prefix))
+	}
+	prefix = normalizePrefix(prefix)
+	if strings.ContainsAny(prefix, "{}") {
+		panic("redirect prefixes must not contain parameters in prefix '" + prefix + "'")
+	}
+	target = strings.TrimSuffix(target, "/")
 
-	// Sort prefixes by length (longest first)
-	for i := len(m.prefixes) - 1; i > 0; i-- {
-		if len(m.prefixes[i]) > len(m.prefixes[i-1]) {
-			m.prefixes[i], m.prefixes[i-1] = m.prefixes[i-1], m.prefixes[i]
-		} else {
+	m.Mount(prefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		url := target + r.URL.Path[len(prefix):]
+		if url == "" {
+			url = "/"
+		}
+		if r.URL.RawQuery != "" {
+			url += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, url, code)
+	}), PreservePath())
+}
+
+// addGroup stores the group, replacing any group with the same prefix and
+// keeping the prefixes sorted for matching
+func (s *multiState) addGroup(g *group) {
+	_, exists := s.groups[g.prefix]
+	s.groups[g.prefix] = g
+	if exists {
+		s.tree = newPrefixTree(s.prefixes, s.groups)
+		return
+	}
+	s.prefixes = append(s.prefixes, g.prefix)
+
+	// Sort prefixes by length (longest first), static prefixes first
+	sort.SliceStable(s.prefixes, func(i, j int) bool {
+		a, b := s.prefixes[i], s.prefixes[j]
+		if pa, pb := strings.IndexByte(a, '{') >= 0, strings.IndexByte(b, '{') >= 0; pa != pb {
+			return pb
+		}
+		return len(a) > len(b)
+	})
+	s.tree = newPrefixTree(s.prefixes, s.groups)
+}
+
+// validatePrefix panics if the parameters of a group prefix do not span whole
+// path segments.
+func validatePrefix(prefix string) {
+	for rest := prefix; ; {
+		i := strings.IndexByte(rest, '{')
+		if i < 0 {
+			if strings.IndexByte(rest, '}') >= 0 {
+				panic("invalid group prefix '" + prefix + "'")
+			}
+			return
+		}
+		end := strings.IndexByte(rest[i:], '}') + i
+		if end < i || i == 0 || rest[i-1] != '/' || end == i+1 ||
+			strings.ContainsAny(rest[i+1:end], "{/.") ||
+			(end+1 < len(rest) && rest[end+1] != '/') {
+			panic("parameters in group prefixes must span whole path segments in prefix '" + prefix + "'")
+		}
+		rest = rest[end+1:]
+	}
+}
+
+// matchParamPrefix matches the path against a prefix containing parameters
+// and returns the rest of the path. If req is not nil, the parameter values
+// are stored as path values of the request.
+func matchParamPrefix(prefix, path string, req *http.Request) (rest string, ok bool) {
+	for {
+		i := strings.IndexByte(prefix, '{')
+		if i < 0 {
+			if path, ok = strings.CutPrefix(path, prefix); !ok {
+				return "", false
+			}
 			break
 		}
+		if path, ok = strings.CutPrefix(path, prefix[:i]); !ok {
+			return "", false
+		}
+
+		end := strings.IndexByte(prefix, '}')
+		name := prefix[i+1 : end]
+		prefix = prefix[end+1:]
+
+		n := strings.IndexByte(path, '/')
+		if n < 0 {
+			n = len(path)
+		}
+		if n == 0 {
+			return "", false
+		}
+		if req != nil {
+			req.SetPathValue(name, path[:n])
+		}
+		path = path[n:]
 	}
+
+	// The prefix must end at a segment boundary
+	if path != "" && path[0] != '/' {
+		return "", false
+	}
+	return path, true
 }
 
 // Default sets the default router for unmatched paths
@@ -119,88 +532,247 @@ func (m *MultiRouter) Default(router *Router) {
 	// Get all paths from the router being set as default
 	paths := router.getPaths()
 
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.state.Load().clone()
+
 	// Check each path against our group prefixes
 	for _, path := range paths {
-		for _, prefix := range m.prefixes {
-			if prefix != "/" && strings.HasPrefix(path, prefix) {
-				panic(fmt.Sprintf("ROUTE CONFLICT: Default router has route '%s' which conflicts with group '%s'! Move it to that group instead.", path, prefix))
+		if g := s.tree.lookup(path); g != nil {
+			m.conflict(fmt.Sprintf("ROUTE CONFLICT: Default router has route '%s' which conflicts with group '%s'! Move it to that group instead.", path, g.prefix))
+		}
+	}
+
+	s.defaultRouter = router
+	m.state.Store(s)
+}
+
+// Host registers a handler, usually a Router or another MultiRouter, for
+// requests to the given host. Requests are dispatched by their Host header
+// before any prefix matching; requests to other hosts are handled by the
+// groups and the default router of the MultiRouter.
+// A leading "*." matches all subdomains of a domain, but not the domain
+// itself. Exact host names take precedence over wildcards:
+//
+//	multi.Host("api.example.com", apiRouter)
+//	multi.Host("*.example.com", tenantRouter)
+//
+// Host names are matched case-insensitively, ignoring the port.
+func (m *MultiRouter) Host(host string, handler http.Handler) {
+	host = strings.ToLower(host)
+	if host == "" || host == "*." {
+		panic("HOST CONFLICT: host must not be empty")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.state.Load().clone()
+
+	if suffix, ok := strings.CutPrefix(host, "*"); ok {
+		if !strings.HasPrefix(suffix, ".") {
+			panic(fmt.Sprintf("HOST CONFLICT: Invalid wildcard host '%s', must start with '*.'", host))
+		}
+		for _, h := range s.wildcardHosts {
+			if h.suffix == suffix {
+				panic(fmt.Sprintf("HOST CONFLICT: Host '%s' is already registered", host))
 			}
 		}
+		s.wildcardHosts = append(s.wildcardHosts, hostHandler{suffix: suffix, handler: handler})
+		sort.SliceStable(s.wildcardHosts, func(i, j int) bool {
+			return len(s.wildcardHosts[i].suffix) > len(s.wildcardHosts[j].suffix)
+		})
+		m.state.Store(s)
+		return
+	}
+
+	if s.hosts == nil {
+		s.hosts = make(map[string]http.Handler)
+	}
+	if _, ok := s.hosts[host]; ok {
+		panic(fmt.Sprintf("HOST CONFLICT: Host '%s' is already registered", host))
 	}
+	s.hosts[host] = handler
+	m.state.Store(s)
+}
 
-	m.defaultRouter = router
+// hostHandler returns the handler registered for the host of the request, or
+// nil.
+func (s *multiState) hostHandler(r *http.Request) http.Handler {
+	return matchHost(requestHost(r), s.hosts, s.wildcardHosts)
+}
+
+// requestHost returns the lower-cased host of the request without the port.
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.HasSuffix(host, "]") {
+		host = host[:i]
+	}
+	return strings.ToLower(host)
+}
+
+// matchHost returns the handler for the host, preferring exact host names
+// over wildcards, or nil.
+func matchHost(host string, hosts map[string]http.Handler, wildcards []hostHandler) http.Handler {
+	if h := hosts[host]; h != nil {
+		return h
+	}
+	for _, h := range wildcards {
+		if len(host) > len(h.suffix) && strings.HasSuffix(host, h.suffix) {
+			return h.handler
+		}
+	}
+	return nil
 }
 
 // ServeHTTP implements http.Handler
 func (m *MultiRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s := m.state.Load()
+	if len(s.hosts) > 0 || len(s.wildcardHosts) > 0 {
+		if h := s.hostHandler(r); h != nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+	}
+
 	path := r.URL.Path
 
 	// Find the longest matching prefix
-	for _, prefix := range m.prefixes {
-		if prefix == "/" {
-			continue
+	if g := s.tree.lookup(path); g != nil {
+		if g.disabled {
+			m.serveDisabled(w, g, r)
+			return
 		}
 
-		if strings.HasPrefix(path, prefix) {
-			router := m.routes[prefix]
+		var rest string
+		if strings.IndexByte(g.prefix, '{') >= 0 {
+			rest, _ = matchParamPrefix(g.prefix, path, r)
+		} else {
+			rest = path[len(g.prefix):]
+		}
+		gr, direct := m.groupRequest(s, g, r)
 
-			// Strip prefix from path
-			originalPath := r.URL.Path
-			newPath := strings.TrimPrefix(path, prefix)
-			if newPath == "" {
-				newPath = "/"
-			}
-			r.URL.Path = newPath
+		// Strip prefix from path, the URL is shared with gr
+		originalPath := r.URL.Path
+		newPath := rest
+		if g.preservePath {
+			newPath = path
+		} else if newPath == "" {
+			newPath = "/"
+		}
+		r.URL.Path = newPath
 
-			router.ServeHTTP(w, r)
+		if direct {
+			g.router.serve(w, gr, g)
+		} else {
+			g.handler.ServeHTTP(w, gr)
+		}
+
+		// Restore original path
+		r.URL.Path = originalPath
+		return
+	}
 
-			// Restore original path
-			r.URL.Path = originalPath
+	// Check for root prefix "/"
+	if root := s.groups["/"]; root != nil {
+		if root.disabled {
+			m.serveDisabled(w, root, r)
 			return
 		}
+		if gr, direct := m.groupRequest(s, root, r); direct {
+			root.router.serve(w, gr, root)
+		} else {
+			root.handler.ServeHTTP(w, gr)
+		}
+		return
 	}
 
-	// Check for root prefix "/"
-	if rootRouter := m.routes["/"]; rootRouter != nil {
-		rootRouter.ServeHTTP(w, r)
+	// Paths below a group prefix never reach the default router, since the
+	// prefix tree matches whole segments. Overlapping routes of the default
+	// router are reported at registration instead.
+	if s.defaultRouter != nil {
+		s.defaultRouter.ServeHTTP(w, r)
 		return
 	}
 
-	// Before using default router, check if path conflicts with any group prefix
-	if m.defaultRouter != nil {
-		for _, prefix := range m.prefixes {
-			if prefix != "/" && strings.HasPrefix(path, prefix) {
-				panic(fmt.Sprintf("ROUTE CONFLICT: Path '%s' should be in group '%s', not default router!", path, prefix))
-			}
-		}
+	writeError(w, r, "404 page not found", http.StatusNotFound)
+}
 
-		m.defaultRouter.ServeHTTP(w, r)
+// serveDisabled answers a request to a disabled group.
+func (m *MultiRouter) serveDisabled(w http.ResponseWriter, g *group, r *http.Request) {
+	r = r.WithContext(context.WithValue(r.Context(), matchedGroupContextKey, g))
+	if m.Disabled != nil {
+		m.Disabled.ServeHTTP(w, r)
 		return
 	}
+	writeError(w, r, "503 service unavailable", http.StatusServiceUnavailable)
+}
+
+// groupRequest returns the request to dispatch to the group, carrying the
+// group and its error handlers in the context.
+// Requests to groups without wrappers and error handlers are returned
+// unchanged instead, with direct set, so that dispatching them does not
+// allocate. They must be passed to Router.serve, which adds the group to the
+// context only if the request reaches a handler.
+func (m *MultiRouter) groupRequest(s *multiState, g *group, r *http.Request) (req *http.Request, direct bool) {
+	fallthroughNotFound := m.FallthroughNotFound && s.defaultRouter != nil
+	if g.direct && !fallthroughNotFound {
+		return r, true
+	}
+
+	ctx := context.WithValue(r.Context(), matchedGroupContextKey, g)
+	if g.router != nil {
+		if g.notFound != nil {
+			ctx = context.WithValue(ctx, notFoundFallbackContextKey, g.notFound)
+		} else if defaultRouter := s.defaultRouter; fallthroughNotFound {
+			path := r.URL.Path
+			ctx = context.WithValue(ctx, notFoundFallbackContextKey,
+				http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					req.URL.Path = path
+					defaultRouter.ServeHTTP(w, req)
+				}),
+			)
+		}
+		if g.methodNotAllowed != nil {
+			ctx = context.WithValue(ctx, methodNotAllowedContextKey, g.methodNotAllowed)
+		}
+	}
 
-	http.NotFound(w, r)
+	return r.WithContext(ctx), false
 }
 
-// Convenience method to create a new router for a group
-func (m *MultiRouter) NewGroup(prefix string) *Router {
-	router := New()
-	m.Group(prefix, router)
+// Convenience method to create a new router for a group.
+// The router is created by NewRouter.
+func (m *MultiRouter) NewGroup(prefix string, opts ...GroupOption) *Router {
+	router := m.newRouter()
+	m.Group(prefix, router, opts...)
 	return router
 }
 
+// newRouter creates a router with NewRouter.
+func (m *MultiRouter) newRouter() *Router {
+	if m.NewRouter != nil {
+		return m.NewRouter()
+	}
+	return New()
+}
+
 // Add method to register routes in default router with conflict checking
 func (m *MultiRouter) RegisterDefault(method, path string, handler http.HandlerFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.state.Load()
+
 	// Check if path conflicts with any existing group prefix
-	for _, prefix := range m.prefixes {
-		if prefix != "/" && strings.HasPrefix(path, prefix) {
-			panic(fmt.Sprintf("ROUTE CONFLICT: Cannot register '%s' - conflicts with group '%s'", path, prefix))
-		}
+	if g := s.tree.lookup(path); g != nil {
+		m.conflict(fmt.Sprintf("ROUTE CONFLICT: Cannot register '%s' - conflicts with group '%s'", path, g.prefix))
 	}
 
-	if m.defaultRouter == nil {
-		m.defaultRouter = New()
+	if s.defaultRouter == nil {
+		s = s.clone()
+		s.defaultRouter = m.newRouter()
+		m.state.Store(s)
 	}
 
 	m.registeredPaths = append(m.registeredPaths, path)
-	m.defaultRouter.HandleFunc(method, path, handler)
+	s.defaultRouter.HandleFunc(method, path, handler)
 }
@@ -1,8 +1,13 @@
 package httpmux
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestMultiRouter_NoConflicts(t *testing.T) {
@@ -230,3 +235,829 @@ func TestMultiRouter_PrefixNormalization(t *testing.T) {
 
 	// Should not panic - different normalized prefixes
 }
+
+func TestMultiRouter_GroupMiddleware(t *testing.T) {
+	multi := NewMultiRouter()
+
+	var seen []string
+	record := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = append(seen, name+" "+r.URL.Path)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	apiRouter := New()
+	apiRouter.GET("/users", dummyHandler)
+	multi.Group("/api", apiRouter, WithMiddleware(record("outer")), WithMiddleware(record("inner")))
+
+	adminRouter := multi.NewGroup("/admin", WithMiddleware(record("admin")))
+	adminRouter.GET("/dashboard", dummyHandler)
+
+	for _, path := range []string{"/api/users", "/api/missing", "/admin/dashboard"} {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		multi.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	want := []string{
+		"outer /users", "inner /users",
+		"outer /missing", "inner /missing",
+		"admin /dashboard",
+	}
+	if strings.Join(seen, ",") != strings.Join(want, ",") {
+		t.Errorf("unexpected middleware calls %v, want %v", seen, want)
+	}
+}
+
+func TestMultiRouter_Mount(t *testing.T) {
+	multi := NewMultiRouter()
+	multi.NewGroup("/api").GET("/users", dummyHandler)
+
+	legacy := http.NewServeMux()
+	legacy.HandleFunc("/reports/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("legacy " + r.URL.Path))
+	})
+	multi.Mount("/legacy/", legacy)
+
+	w := httptest.NewRecorder()
+	multi.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/legacy/reports/2024", nil))
+	if w.Body.String() != "legacy /reports/2024" {
+		t.Errorf("unexpected response %q", w.Body.String())
+	}
+	if _, ok := multi.Routes()["/legacy"]; ok {
+		t.Error("mounted handlers must not be listed as routers")
+	}
+
+	for _, prefix := range []string{"/legacy", "/api", "/api/users", "/"} {
+		recv := catchPanic(func() {
+			multi.Mount(prefix, legacy)
+		})
+		if recv == nil || !strings.Contains(recv.(string), "MOUNT CONFLICT") {
+			t.Errorf("expected mount conflict for %q, got %v", prefix, recv)
+		}
+	}
+}
+
+func TestMultiRouter_Host(t *testing.T) {
+	respond := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, _ *http.Request) {
+			w.Write([]byte(body))
+		}
+	}
+
+	multi := NewMultiRouter()
+	multi.NewGroup("/api").GET("/users", respond("group"))
+
+	apiRouter := New()
+	apiRouter.GET("/users", respond("api host"))
+	multi.Host("API.example.com", apiRouter)
+
+	tenantRouter := New()
+	tenantRouter.GET("/users", respond("tenant"))
+	multi.Host("*.example.com", tenantRouter)
+
+	eu := New()
+	eu.GET("/users", respond("eu tenant"))
+	multi.Host("*.eu.example.com", eu)
+
+	tests := []struct {
+		host, path, body string
+	}{
+		{"api.example.com", "/users", "api host"},
+		{"api.example.com:8080", "/users", "api host"},
+		{"acme.example.com", "/users", "tenant"},
+		{"acme.eu.example.com", "/users", "eu tenant"},
+		{"example.com", "/api/users", "group"},
+		{"other.org", "/api/users", "group"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		r.Host = tt.host
+		w := httptest.NewRecorder()
+		multi.ServeHTTP(w, r)
+		if w.Body.String() != tt.body {
+			t.Errorf("%s%s: got %q, want %q", tt.host, tt.path, w.Body.String(), tt.body)
+		}
+	}
+
+	for _, host := range []string{"api.example.com", "*.example.com", "*example.com", ""} {
+		if recv := catchPanic(func() { multi.Host(host, apiRouter) }); recv == nil {
+			t.Errorf("expected panic registering host %q", host)
+		}
+	}
+}
+
+func TestMultiRouter_ParamPrefix(t *testing.T) {
+	multi := NewMultiRouter()
+
+	tenantRouter := multi.NewGroup("/tenants/{tenant}")
+	tenantRouter.GET("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.PathValue("tenant") + " " + r.PathValue("id") + " " + r.URL.Path))
+	})
+
+	projectRouter := multi.NewGroup("/orgs/{org}/projects/{project}")
+	projectRouter.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.PathValue("org") + "/" + r.PathValue("project")))
+	})
+
+	adminRouter := multi.NewGroup("/tenants/admin")
+	adminRouter.GET("/users/{id}", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("admin"))
+	})
+
+	tests := []struct {
+		path, body string
+		code       int
+	}{
+		{"/tenants/acme/users/42", "acme 42 /users/42", http.StatusOK},
+		{"/tenants/admin/users/42", "admin", http.StatusOK},
+		{"/orgs/go/projects/mux", "go/mux", http.StatusOK},
+		{"/orgs/go/projects/mux/", "go/mux", http.StatusOK},
+		{"/orgs/go/projectsx/mux", "", http.StatusNotFound},
+		{"/tenants//users/42", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		multi.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.code || (tt.body != "" && w.Body.String() != tt.body) {
+			t.Errorf("%s: got %d %q, want %d %q", tt.path, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+
+	for _, prefix := range []string{"/tenants/x{tenant}", "/t/{tenant}x", "/t/{}", "/t/{a/b}", "/t/{rest...}", "/t/{tenant"} {
+		if recv := catchPanic(func() { multi.Group(prefix, New()) }); recv == nil {
+			t.Errorf("expected panic for invalid prefix %q", prefix)
+		}
+	}
+}
+
+func TestMultiRouter_FallthroughNotFound(t *testing.T) {
+	multi := NewMultiRouter()
+
+	apiRouter := multi.NewGroup("/api")
+	apiRouter.GET("/users", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("users"))
+	})
+
+	spa := New()
+	spa.GET("/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("spa " + r.URL.Path))
+	})
+	multi.Default(spa)
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		multi.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	if w := serve(http.MethodGet, "/api/unknown"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without fallthrough, got %d", w.Code)
+	}
+
+	multi.FallthroughNotFound = true
+	if w := serve(http.MethodGet, "/api/unknown"); w.Body.String() != "spa /api/unknown" {
+		t.Errorf("expected fallthrough to default router, got %d %q", w.Code, w.Body.String())
+	}
+	if w := serve(http.MethodGet, "/api/users"); w.Body.String() != "users" {
+		t.Errorf("unexpected response %q", w.Body.String())
+	}
+	if w := serve(http.MethodPost, "/api/users"); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 from group router, got %d", w.Code)
+	}
+}
+
+func TestMultiRouter_PreservePath(t *testing.T) {
+	multi := NewMultiRouter()
+
+	var seen string
+	apiRouter := New()
+	apiRouter.GET("/api/users/{id}", func(_ http.ResponseWriter, r *http.Request) {
+		seen = r.URL.Path + " " + r.PathValue("id")
+	})
+	multi.Group("/api", apiRouter, PreservePath(), WithMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/users/42" {
+				t.Errorf("middleware saw path %q", r.URL.Path)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/api/users/42", nil)
+	multi.ServeHTTP(httptest.NewRecorder(), r)
+	if seen != "/api/users/42 42" {
+		t.Errorf("unexpected handler view %q", seen)
+	}
+
+	// Conflict checks use the full paths
+	adminRouter := New()
+	adminRouter.GET("/users", dummyHandler)
+	if recv := catchPanic(func() { multi.Group("/api/users", adminRouter) }); recv == nil {
+		t.Error("expected conflict with preserved path route")
+	}
+
+	wrongRouter := New()
+	wrongRouter.GET("/users", dummyHandler)
+	if recv := catchPanic(func() { multi.Group("/wrong", wrongRouter, PreservePath()) }); recv == nil {
+		t.Error("expected panic for route outside of the prefix")
+	}
+}
+
+func TestMultiRouter_MatchedGroup(t *testing.T) {
+	multi := NewMultiRouter()
+
+	var seen string
+	record := func(_ http.ResponseWriter, r *http.Request) {
+		seen = MatchedGroup(r)
+	}
+	multi.NewGroup("/api").GET("/users", record)
+	multi.NewGroup("/admin", WithName("backoffice")).GET("/users", record)
+	multi.RegisterDefault(http.MethodGet, "/home", record)
+
+	for path, want := range map[string]string{
+		"/api/users":   "/api",
+		"/admin/users": "backoffice",
+		"/home":        "",
+	} {
+		seen = "unset"
+		multi.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		if seen != want {
+			t.Errorf("%s: expected group %q, got %q", path, want, seen)
+		}
+	}
+}
+
+func TestMultiRouter_RoutePattern(t *testing.T) {
+	multi := NewMultiRouter()
+
+	var seen string
+	record := func(_ http.ResponseWriter, r *http.Request) {
+		seen = RoutePattern(r)
+	}
+	multi.NewGroup("/api").GET("/users/{id}", record)
+	multi.NewGroup("/v2", PreservePath()).GET("/v2/items", record)
+	multi.RegisterDefault(http.MethodGet, "/home", record)
+
+	for path, want := range map[string]string{
+		"/api/users/42": "/api/users/{id}",
+		"/v2/items":     "/v2/items",
+		"/home":         "/home",
+	} {
+		seen = "unset"
+		multi.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		if seen != want {
+			t.Errorf("%s: expected route %q, got %q", path, want, seen)
+		}
+	}
+
+	if p := RoutePattern(httptest.NewRequest(http.MethodGet, "/", nil)); p != "" {
+		t.Errorf("expected no route pattern, got %q", p)
+	}
+}
+
+func TestMultiRouter_PlainGroupAllocs(t *testing.T) {
+	multi := NewMultiRouter()
+	api := multi.NewGroup("/api")
+	api.GET("/users/{id}", func(_ http.ResponseWriter, _ *http.Request) {})
+	multi.NewGroup("/admin", WithName("backoffice")).GET("/users", dummyHandler)
+
+	s := multi.state.Load()
+	req := httptest.NewRequest(http.MethodGet, "/api/users/42", nil)
+	for _, prefix := range []string{"/api", "/admin"} {
+		if gr, direct := multi.groupRequest(s, s.groups[prefix], req); gr != req || !direct {
+			t.Errorf("%s: expected the request to be dispatched unchanged", prefix)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	if allocs := testing.AllocsPerRun(100, func() {
+		multi.groupRequest(s, s.groups["/api"], req)
+	}); allocs != 0 {
+		t.Errorf("expected no allocations to prepare the dispatch, got %v", allocs)
+	}
+	// The group is added to the context of the request once the route matched
+	if allocs := testing.AllocsPerRun(100, func() {
+		multi.ServeHTTP(w, req)
+	}); allocs > 2 {
+		t.Errorf("expected at most 2 allocations to serve a plain group, got %v", allocs)
+	}
+	if req.URL.Path != "/api/users/42" {
+		t.Errorf("expected the path to be restored, got %q", req.URL.Path)
+	}
+}
+
+func TestMultiRouter_GroupErrorHandlers(t *testing.T) {
+	multi := NewMultiRouter()
+
+	jsonError := func(code int) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(code)
+			w.Write([]byte(`{"error":` + strconv.Itoa(code) + `}`))
+		})
+	}
+
+	shared := New()
+	shared.GET("/users", dummyHandler)
+	multi.Group("/api", shared,
+		WithGroupNotFound(jsonError(http.StatusNotFound)),
+		WithGroupMethodNotAllowed(jsonError(http.StatusMethodNotAllowed)),
+	)
+	multi.Group("/web", shared)
+
+	tests := []struct {
+		method, path, body string
+		code               int
+	}{
+		{http.MethodGet, "/api/missing", `{"error":404}`, http.StatusNotFound},
+		{http.MethodPost, "/api/users", `{"error":405}`, http.StatusMethodNotAllowed},
+		{http.MethodGet, "/web/missing", "404 page not found\n", http.StatusNotFound},
+		{http.MethodPost, "/web/users", "Method Not Allowed\n", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		multi.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.code || w.Body.String() != tt.body {
+			t.Errorf("%s %s: got %d %q, want %d %q", tt.method, tt.path, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+}
+
+func TestMultiRouter_WarnOnConflict(t *testing.T) {
+	logger, buf := newTestLogger()
+
+	multi := NewMultiRouter()
+	multi.WarnOnConflict(logger)
+	multi.NewGroup("/admin").GET("/users", dummyHandler)
+
+	defaultRouter := New()
+	defaultRouter.GET("/admin/dashboard", dummyHandler)
+	multi.Default(defaultRouter) // must not panic
+	multi.RegisterDefault(http.MethodGet, "/admin/settings", dummyHandler)
+
+	out := buf.String()
+	if strings.Count(out, "level=WARN") != 2 || !strings.Contains(out, "ROUTE CONFLICT") ||
+		!strings.Contains(out, "/admin/dashboard") || !strings.Contains(out, "/admin/settings") {
+		t.Errorf("expected conflicts to be logged, got:\n%s", out)
+	}
+
+	multi.WarnOnConflict(nil)
+	if recv := catchPanic(func() { multi.Default(defaultRouter) }); recv == nil {
+		t.Error("expected panic after restoring the default")
+	}
+}
+
+func TestMultiRouter_PreservePathUndispatched(t *testing.T) {
+	tenant := New()
+	tenant.GET("/", dummyHandler)
+
+	multi := NewMultiRouter()
+	recv := catchPanic(func() { multi.Group("/{tenant}", tenant, PreservePath()) })
+	if msg, _ := recv.(string); !strings.Contains(msg, "GROUP CONFLICT") || !strings.Contains(msg, "dispatched to no group") {
+		t.Errorf("expected group conflict, got %v", recv)
+	}
+
+	logger, buf := newTestLogger()
+	multi = NewMultiRouter()
+	multi.WarnOnConflict(logger)
+	multi.Group("/{tenant}", tenant, PreservePath()) // must not panic
+	if out := buf.String(); !strings.Contains(out, "level=WARN") || !strings.Contains(out, "dispatched to no group") {
+		t.Errorf("expected the conflict to be logged, got:\n%s", out)
+	}
+}
+
+func TestMultiRouter_RemoveReplaceGroup(t *testing.T) {
+	respond := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, _ *http.Request) {
+			w.Write([]byte(body))
+		}
+	}
+	serve := func(multi *MultiRouter, path string) string {
+		w := httptest.NewRecorder()
+		multi.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Body.String()
+	}
+
+	multi := NewMultiRouter()
+	multi.NewGroup("/plugin").GET("/info", respond("v1"))
+	multi.RegisterDefault(http.MethodGet, "/{path...}", respond("default"))
+
+	v2 := New()
+	v2.GET("/info", respond("v2"))
+	if !multi.ReplaceGroup("/plugin/", v2) {
+		t.Fatal("expected group to be replaced")
+	}
+	if body := serve(multi, "/plugin/info"); body != "v2" {
+		t.Errorf("expected replaced router to serve, got %q", body)
+	}
+	if len(multi.state.Load().prefixes) != 1 {
+		t.Errorf("expected prefix to be listed once, got %v", multi.state.Load().prefixes)
+	}
+
+	if multi.ReplaceGroup("/unknown", v2) {
+		t.Error("expected no replacement for unknown prefix")
+	}
+	if _, ok := multi.Routes()["/unknown"]; ok {
+		t.Error("unknown group must not be registered")
+	}
+
+	if !multi.RemoveGroup("/plugin") {
+		t.Fatal("expected group to be removed")
+	}
+	if body := serve(multi, "/plugin/info"); body != "default" {
+		t.Errorf("expected default router after removal, got %q", body)
+	}
+	if multi.RemoveGroup("/plugin") {
+		t.Error("expected second removal to report false")
+	}
+}
+
+func TestMultiRouter_AllRoutes(t *testing.T) {
+	multi := NewMultiRouter()
+	api := multi.NewGroup("/api", WithName("api"))
+	api.GET("/users/{id}", dummyHandler)
+	api.POST("/users", dummyHandler)
+	multi.NewGroup("/tenants/{tenant}", PreservePath()).GET("/tenants/{tenant}/info", dummyHandler)
+	multi.Mount("/static", http.NotFoundHandler())
+	multi.RegisterDefault(http.MethodGet, "/", dummyHandler)
+
+	want := []Route{
+		{Method: http.MethodGet, Path: "/"},
+		{Method: http.MethodPost, Path: "/api/users", Group: "api"},
+		{Method: http.MethodGet, Path: "/api/users/{id}", Group: "api"},
+		{Method: http.MethodGet, Path: "/tenants/{tenant}/info", Group: "/tenants/{tenant}"},
+	}
+	got := multi.AllRoutes()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d routes, got %v", len(want), got)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("route %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestMultiRouter_ConcurrentRegistration(t *testing.T) {
+	multi := NewMultiRouter()
+	multi.NewGroup("/api").GET("/ping", dummyHandler)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 50 {
+			prefix := "/tenant" + strconv.Itoa(i)
+			multi.NewGroup(prefix).GET("/info", dummyHandler)
+			multi.Host("t"+strconv.Itoa(i)+".example.com", http.NotFoundHandler())
+			if i%2 == 0 {
+				multi.RemoveGroup(prefix)
+			}
+		}
+		multi.RegisterDefault(http.MethodGet, "/health", dummyHandler)
+	}()
+
+	for i := 0; ; i++ {
+		w := httptest.NewRecorder()
+		multi.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/ping", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200 while registering, got %d", w.Code)
+		}
+		select {
+		case <-done:
+			if routes := multi.Routes(); len(routes) != 26 {
+				t.Errorf("expected 26 groups, got %d", len(routes))
+			}
+			return
+		default:
+		}
+	}
+}
+
+func TestMultiRouter_LongestPrefix(t *testing.T) {
+	respond := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body + " " + r.URL.Path))
+		}
+	}
+	register := map[string]func(multi *MultiRouter){
+		"/api": func(multi *MultiRouter) {
+			multi.NewGroup("/api").GET("/{path...}", respond("api"))
+		},
+		"/api/v2": func(multi *MultiRouter) {
+			v2 := multi.NewGroup("/api/v2")
+			v2.GET("/", respond("v2"))
+			v2.GET("/users", respond("v2"))
+		},
+	}
+
+	for _, order := range [][]string{{"/api", "/api/v2"}, {"/api/v2", "/api"}} {
+		multi := NewMultiRouter()
+		for _, prefix := range order {
+			// Nested groups without overlapping routes do not conflict
+			register[prefix](multi)
+		}
+		multi.RegisterDefault(http.MethodGet, "/{path...}", respond("default"))
+
+		tests := []struct {
+			path, body string
+		}{
+			{"/api/v2/users", "v2 /users"},
+			{"/api/v2", "v2 /"},
+			{"/api/v3/users", "api /v3/users"},
+			{"/api", "api /"},
+			{"/apiv2/users", "default /apiv2/users"},
+		}
+		for _, tt := range tests {
+			w := httptest.NewRecorder()
+			multi.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Body.String() != tt.body {
+				t.Errorf("%v: expected %q for %s, got %q", order, tt.body, tt.path, w.Body.String())
+			}
+		}
+	}
+
+	// A route of the shorter prefix shadowed by the longer one conflicts in
+	// either order
+	for _, order := range [][]string{{"/api", "/api/v2"}, {"/api/v2", "/api"}} {
+		multi := NewMultiRouter()
+		api := New()
+		api.GET("/v2/users", dummyHandler)
+		routers := map[string]*Router{"/api": api, "/api/v2": New()}
+		recv := catchPanic(func() {
+			for _, prefix := range order {
+				multi.Group(prefix, routers[prefix])
+			}
+		})
+		if recv == nil || !strings.Contains(recv.(string), "/api/v2/users") {
+			t.Errorf("%v: expected conflict for /api/v2/users, got %v", order, recv)
+		}
+	}
+
+	// Prefixes matching the same paths are ambiguous
+	multi := NewMultiRouter()
+	multi.NewGroup("/t/{tenant}")
+	recv := catchPanic(func() {
+		multi.NewGroup("/t/{id}")
+	})
+	if recv == nil || !strings.Contains(recv.(string), "matches the same paths") {
+		t.Errorf("expected conflict for ambiguous prefixes, got %v", recv)
+	}
+}
+
+func TestMultiRouter_Rewrite(t *testing.T) {
+	api := New()
+	api.GET("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("user " + r.PathValue("id") + " " + r.URL.RawQuery))
+	})
+	api.GET("/files/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("file " + r.PathValue("path")))
+	})
+
+	var seen string
+	multi := NewMultiRouter()
+	multi.Group("/api", api,
+		WithMiddleware(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = r.URL.Path
+				next.ServeHTTP(w, r)
+			})
+		}),
+		WithRewrite("/v1/{path...}", "/{path...}"),
+		WithRewrite("/members/{id}", "/users/{id}"),
+		WithRewrite("/docs/{year}/{name...}", "/files/archive/{year}/{name...}"),
+	)
+
+	tests := []struct {
+		path, body string
+	}{
+		{"/api/users/1", "user 1 "},
+		{"/api/v1/users/2?x=1", "user 2 x=1"},
+		{"/api/members/3", "user 3 "},
+		{"/api/docs/2024/a/b.txt", "file /archive/2024/a/b.txt"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		w := httptest.NewRecorder()
+		multi.ServeHTTP(w, req)
+		if w.Body.String() != tt.body {
+			t.Errorf("%s: expected %q, got %q", tt.path, tt.body, w.Body.String())
+		}
+		if req.URL.Path != strings.Split(tt.path, "?")[0] {
+			t.Errorf("%s: request path not restored, got %q", tt.path, req.URL.Path)
+		}
+	}
+	if seen != "/docs/2024/a/b.txt" {
+		t.Errorf("expected middlewares to see the original path, got %q", seen)
+	}
+
+	for _, path := range []string{"/api/members/3/x", "/api/members/", "/api/v1"} {
+		w := httptest.NewRecorder()
+		multi.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected no rewrite, got %d %q", path, w.Code, w.Body.String())
+		}
+	}
+
+	for _, rule := range [][2]string{
+		{"v1", "/"},
+		{"/{path...}/x", "/"},
+		{"/a{id}", "/"},
+		{"/{id}", "/{other}"},
+		{"/{id}", "/{id"},
+		{"/v1/{id}", "/v2}/{id}"},
+		{"/v1/{id}", "/v2/{id}}"},
+	} {
+		if recv := catchPanic(func() { WithRewrite(rule[0], rule[1]) }); recv == nil {
+			t.Errorf("expected panic for rule %q -> %q", rule[0], rule[1])
+		}
+	}
+}
+
+func TestMultiRouter_Redirect(t *testing.T) {
+	multi := NewMultiRouter()
+	multi.NewGroup("/admin").GET("/users", dummyHandler)
+	multi.Redirect("/old-admin/", "/admin", http.StatusMovedPermanently)
+	multi.Redirect("/docs", "https://docs.example.com/", http.StatusFound)
+
+	tests := []struct {
+		path, location string
+		code           int
+	}{
+		{"/old-admin", "/admin", http.StatusMovedPermanently},
+		{"/old-admin/", "/admin/", http.StatusMovedPermanently},
+		{"/old-admin/users?page=2", "/admin/users?page=2", http.StatusMovedPermanently},
+		{"/docs/guide", "https://docs.example.com/guide", http.StatusFound},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		multi.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.code || w.Header().Get("Location") != tt.location {
+			t.Errorf("%s: expected %d to %q, got %d to %q", tt.path, tt.code, tt.location, w.Code, w.Header().Get("Location"))
+		}
+	}
+
+	if recv := catchPanic(func() { multi.Redirect("/new", "/admin", http.StatusOK) }); recv == nil {
+		t.Error("expected panic for non-redirect status code")
+	}
+	if recv := catchPanic(func() { multi.Redirect("/t/{tenant}", "/admin", http.StatusFound) }); recv == nil {
+		t.Error("expected panic for parameterized prefix")
+	}
+	if recv := catchPanic(func() { multi.Redirect("/admin/users", "/users", http.StatusFound) }); recv == nil {
+		t.Error("expected conflict for prefix shadowing a route")
+	}
+}
+
+func TestMultiRouter_DefaultRouterNoRequestPanic(t *testing.T) {
+	logger, buf := newTestLogger()
+	multi := NewMultiRouter()
+	multi.WarnOnConflict(logger)
+	multi.NewGroup("/api").GET("/users", dummyHandler)
+
+	def := New()
+	def.GET("/apix", dummyHandler)
+	def.GET("/api/users", dummyHandler) // shadowed, reported at registration
+	multi.Default(def)
+	if !strings.Contains(buf.String(), "/api/users") {
+		t.Errorf("expected conflict to be logged, got %q", buf.String())
+	}
+
+	for _, path := range []string{"/apix", "/api/users"} {
+		recv := catchPanic(func() {
+			w := httptest.NewRecorder()
+			multi.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("%s: expected 200, got %d", path, w.Code)
+			}
+		})
+		if recv != nil {
+			t.Errorf("%s: unexpected panic %v", path, recv)
+		}
+	}
+}
+
+func TestMultiRouter_WithTimeout(t *testing.T) {
+	var status int
+	multi := NewMultiRouter()
+	admin := multi.NewGroup("/admin",
+		WithTimeout(10*time.Millisecond),
+		WithMiddleware(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ww := WrapWriter(w)
+				next.ServeHTTP(ww, r)
+				status = ww.Status()
+			})
+		}),
+	)
+	admin.GET("/report", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		_ = r.URL.Path // must not race with the MultiRouter restoring the path
+	})
+	multi.NewGroup("/api").GET("/users", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); ok {
+			t.Error("unexpected deadline outside of the admin group")
+		}
+	})
+
+	w := httptest.NewRecorder()
+	multi.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/report", nil))
+	if w.Code != http.StatusGatewayTimeout || status != http.StatusGatewayTimeout {
+		t.Errorf("expected 504 seen by middleware, got %d and %d", w.Code, status)
+	}
+
+	w = httptest.NewRecorder()
+	multi.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+}
+
+func TestMultiRouter_NewRouter(t *testing.T) {
+	var created []*Router
+	multi := NewMultiRouter()
+	multi.NewRouter = func() *Router {
+		r := New()
+		r.RedirectTrailingSlash = false
+		r.NotFound = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		})
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("X-Inherited", "1")
+				next.ServeHTTP(w, req)
+			})
+		})
+		created = append(created, r)
+		return r
+	}
+
+	api := multi.NewGroup("/api")
+	api.GET("/users", dummyHandler)
+	multi.RegisterDefault(http.MethodGet, "/home", dummyHandler)
+	if len(created) != 2 || created[0] != api {
+		t.Fatalf("expected group and default router to be created by NewRouter, got %d", len(created))
+	}
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/api/users", http.StatusOK},
+		{"/api/users/", http.StatusTeapot},
+		{"/home/", http.StatusTeapot},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		multi.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.code {
+			t.Errorf("%s: expected %d, got %d", tt.path, tt.code, w.Code)
+		}
+		if tt.code == http.StatusOK && w.Header().Get("X-Inherited") != "1" {
+			t.Errorf("%s: expected inherited middleware to run", tt.path)
+		}
+	}
+}
+
+func TestMultiRouter_Disable(t *testing.T) {
+	multi := NewMultiRouter()
+	multi.NewGroup("/beta").GET("/feature", dummyHandler)
+	multi.NewGroup("/api").GET("/users", dummyHandler)
+	multi.RegisterDefault(http.MethodGet, "/{path...}", dummyHandler)
+
+	serve := func(path string) int {
+		w := httptest.NewRecorder()
+		multi.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+
+	if !multi.Disable("/beta/") {
+		t.Fatal("expected group to be disabled")
+	}
+	if code := serve("/beta/feature"); code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 for disabled group, got %d", code)
+	}
+	if code := serve("/api/users"); code != http.StatusOK {
+		t.Errorf("expected other groups to be unaffected, got %d", code)
+	}
+
+	// Replacing keeps the group disabled
+	replacement := New()
+	replacement.GET("/feature", dummyHandler)
+	multi.ReplaceGroup("/beta", replacement)
+	multi.Disabled = http.NotFoundHandler()
+	if code := serve("/beta/feature"); code != http.StatusNotFound {
+		t.Errorf("expected Disabled handler, got %d", code)
+	}
+
+	if !multi.Enable("/beta") {
+		t.Fatal("expected group to be enabled")
+	}
+	if code := serve("/beta/feature"); code != http.StatusOK {
+		t.Errorf("expected 200 after enabling, got %d", code)
+	}
+	if multi.Disable("/unknown") || multi.Enable("/unknown") {
+		t.Error("expected unknown prefixes to be reported")
+	}
+}
@@ -0,0 +1,386 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OperationDoc documents a route in the OpenAPI document generated by
+// Router.OpenAPI.
+type OperationDoc struct {
+	OperationID string
+	Summary     string
+	Description string
+	Tags        []string
+	Deprecated  bool
+
+	// Value of the type of the JSON request body, e.g. CreateUser{}. Its
+	// schema is derived from the type by reflection, honoring json tags.
+	Request any
+
+	// Value of the type of the JSON response body, sent with ResponseStatus
+	Response any
+
+	// Status code of successful responses, 200 by default
+	ResponseStatus int
+}
+
+// Describe documents the route with the method and path for the OpenAPI
+// document, replacing the summary and description taken from the "summary"
+// and "description" metadata of routes loaded by LoadRoutes:
+//
+//	router.POST("/users", createUser)
+//	router.Describe(http.MethodPost, "/users", httpmux.OperationDoc{
+//		Summary:        "Create a user",
+//		Request:        CreateUserRequest{},
+//		Response:       User{},
+//		ResponseStatus: http.StatusCreated,
+//	})
+func (r *Router) Describe(method, path string, doc OperationDoc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Copy-on-write, so that documents can be generated without the lock
+	operations := maps.Clone(r.operations)
+	if operations == nil {
+		operations = make(map[string]OperationDoc)
+	}
+	operations[method+" "+path] = doc
+	r.operations = operations
+}
+
+// OpenAPIInfo is the info object of an OpenAPI document.
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPIDocument is an OpenAPI 3 document, which marshals to JSON.
+type OpenAPIDocument struct {
+	OpenAPI string                                  `json:"openapi"`
+	Info    OpenAPIInfo                             `json:"info"`
+	Paths   map[string]map[string]*OpenAPIOperation `json:"paths"`
+}
+
+// OpenAPIOperation is an operation of an OpenAPI document.
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId,omitempty"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Deprecated  bool                        `json:"deprecated,omitempty"`
+	Parameters  []OpenAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *OpenAPIBody                `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+}
+
+// OpenAPIParameter is a parameter of an OpenAPI operation.
+type OpenAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required"`
+	Schema   map[string]any `json:"schema"`
+}
+
+// OpenAPIBody is a request body of an OpenAPI operation.
+type OpenAPIBody struct {
+	Required bool                      `json:"required,omitempty"`
+	Content  map[string]map[string]any `json:"content"`
+}
+
+// OpenAPIResponse is a response of an OpenAPI operation.
+type OpenAPIResponse struct {
+	Description string                    `json:"description"`
+	Content     map[string]map[string]any `json:"content,omitempty"`
+}
+
+// openAPIMethods are the methods OpenAPI can describe.
+var openAPIMethods = map[string]string{
+	http.MethodGet:     "get",
+	http.MethodPut:     "put",
+	http.MethodPost:    "post",
+	http.MethodDelete:  "delete",
+	http.MethodOptions: "options",
+	http.MethodHead:    "head",
+	http.MethodPatch:   "patch",
+	http.MethodTrace:   "trace",
+}
+
+// OpenAPI generates an OpenAPI 3 document describing the routes of the
+// router. Path parameters are derived from the routes; a catch-all parameter
+// like {path...} is described as a parameter {path}, as OpenAPI does not
+// support them. Routes of methods OpenAPI cannot describe, like WEBSOCKET
+// routes, are left out. Summaries and schemas are taken from Describe and
+// the route metadata, tags from Describe or the route's Tags.
+func (r *Router) OpenAPI(info OpenAPIInfo) *OpenAPIDocument {
+	doc := &OpenAPIDocument{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   make(map[string]map[string]*OpenAPIOperation),
+	}
+
+	r.mu.Lock()
+	operations := r.operations
+	r.mu.Unlock()
+
+	for method, root := range r.trees.Load().all() {
+		key, ok := openAPIMethods[method]
+		if !ok {
+			continue
+		}
+		root.walkRoutes(func(n *node) {
+			path, params := openAPIPath(n.fullPath)
+			op := &OpenAPIOperation{Responses: make(map[string]*OpenAPIResponse)}
+			for _, name := range params {
+				op.Parameters = append(op.Parameters, OpenAPIParameter{
+					Name: name, In: "path", Required: true, Schema: map[string]any{"type": "string"},
+				})
+			}
+
+			d, documented := operations[method+" "+n.fullPath]
+			if !documented {
+				meta := r.RouteMetadata(method, n.fullPath)
+				d.Summary, d.Description = meta["summary"], meta["description"]
+			}
+			op.OperationID, op.Summary, op.Description = d.OperationID, d.Summary, d.Description
+			op.Tags, op.Deprecated = d.Tags, d.Deprecated
+			if len(op.Tags) == 0 {
+				op.Tags = routeOptionsOf(n.handle).tags
+			}
+			if d.Request != nil {
+				op.RequestBody = &OpenAPIBody{Required: true, Content: jsonContent(d.Request)}
+			}
+			status := d.ResponseStatus
+			if status == 0 {
+				status = http.StatusOK
+			}
+			switch {
+			case d.Response != nil:
+				op.Responses[strconv.Itoa(status)] = &OpenAPIResponse{Description: http.StatusText(status), Content: jsonContent(d.Response)}
+			case documented && d.ResponseStatus != 0:
+				op.Responses[strconv.Itoa(status)] = &OpenAPIResponse{Description: http.StatusText(status)}
+			default:
+				op.Responses["default"] = &OpenAPIResponse{Description: "Default response"}
+			}
+
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = make(map[string]*OpenAPIOperation)
+			}
+			doc.Paths[path][key] = op
+		})
+	}
+	return doc
+}
+
+// openAPIPath returns the route path in OpenAPI syntax and the names of its
+// parameters.
+func openAPIPath(path string) (string, []string) {
+	var params []string
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		if len(seg) > 2 && seg[0] == '{' && seg[len(seg)-1] == '}' {
+			name := strings.TrimSuffix(seg[1:len(seg)-1], "...")
+			params = append(params, name)
+			segs[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segs, "/"), params
+}
+
+func jsonContent(v any) map[string]map[string]any {
+	return map[string]map[string]any{
+		"application/json": {"schema": jsonSchema(reflect.TypeOf(v), nil)},
+	}
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// jsonSchema returns the JSON schema of the values of type t as encoded by
+// encoding/json. Recursive types are described as any value where they
+// recur.
+func jsonSchema(t reflect.Type, seen []reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": jsonSchema(t.Elem(), seen)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": jsonSchema(t.Elem(), seen)}
+	case reflect.Struct:
+		for _, s := range seen {
+			if s == t {
+				return map[string]any{}
+			}
+		}
+		seen = append(seen, t)
+
+		props := make(map[string]any)
+		var required []string
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" && opts == "" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			props[name] = jsonSchema(f.Type, seen)
+			if !strings.Contains(opts, "omitempty") && !strings.Contains(opts, "omitzero") && f.Type.Kind() != reflect.Pointer {
+				required = append(required, name)
+			}
+		}
+		schema := map[string]any{"type": "object", "properties": props}
+		if len(required) > 0 {
+			sort.Strings(required)
+			schema["required"] = required
+		}
+		return schema
+	}
+	return map[string]any{}
+}
+
+// ServeOpenAPI registers a GET route at the path, usually "/openapi.json",
+// serving the OpenAPI document of the router. The document is generated per
+// request, so it includes routes registered later.
+func (r *Router) ServeOpenAPI(path string, info OpenAPIInfo) {
+	r.GET(path, func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(r.OpenAPI(info))
+	})
+}
+
+var swaggerUITemplate = template.Must(template.New("swagger").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>API documentation</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+<script>SwaggerUIBundle({url: {{.}}, dom_id: "#swagger-ui"});</script>
+</body>
+</html>
+`))
+
+// ServeSwaggerUI registers a GET route at the path serving Swagger UI for the
+// OpenAPI document at specURL:
+//
+//	router.ServeOpenAPI("/openapi.json", httpmux.OpenAPIInfo{Title: "Users", Version: "1.0"})
+//	router.ServeSwaggerUI("/docs", "/openapi.json")
+//
+// The page loads Swagger UI from the unpkg CDN.
+func (r *Router) ServeSwaggerUI(path, specURL string) {
+	r.GET(path, func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		swaggerUITemplate.Execute(w, specURL)
+	})
+}
+
+// LoadOpenAPI registers the operations of an OpenAPI 3 document with the
+// handlers keyed by operation ID:
+//
+//	spec, _ := os.ReadFile("openapi.yaml")
+//	err := router.LoadOpenAPI(spec, yaml.Unmarshal, map[string]http.Handler{
+//		"listUsers":  http.HandlerFunc(listUsers),
+//		"createUser": http.HandlerFunc(createUser),
+//	})
+//
+// The document is decoded with the unmarshal function, or as JSON if it is
+// nil. Every operation must have an operation ID with a handler, and every
+// handler must belong to an operation; otherwise nothing is registered and
+// all problems found are reported together. The summaries and descriptions of
+// the operations become route metadata, see RouteMetadata, so that documents
+// generated by OpenAPI keep them. Conflicting routes are reported like by
+// LoadRoutes.
+func (r *Router) LoadOpenAPI(data []byte, unmarshal func([]byte, any) error, handlers map[string]http.Handler) error {
+	if unmarshal == nil {
+		unmarshal = json.Unmarshal
+	}
+	var spec struct {
+		Paths map[string]map[string]any `json:"paths" yaml:"paths"`
+	}
+	if err := unmarshal(data, &spec); err != nil {
+		return err
+	}
+
+	m := new(RouteManifest)
+	reg := NewHandlerRegistry()
+	var errs []error
+	for _, path := range slices.Sorted(maps.Keys(spec.Paths)) {
+		for _, method := range slices.Sorted(maps.Keys(openAPIMethods)) {
+			op, ok := spec.Paths[path][openAPIMethods[method]].(map[string]any)
+			if !ok {
+				continue
+			}
+			id, _ := op["operationId"].(string)
+			handler, ok := handlers[id]
+			switch {
+			case id == "":
+				errs = append(errs, fmt.Errorf("%s %s: missing operationId", method, path))
+				continue
+			case !ok:
+				errs = append(errs, fmt.Errorf("%s %s: operation '%s' is not implemented", method, path, id))
+				continue
+			case reg.handlers[id] != nil:
+				errs = append(errs, fmt.Errorf("%s %s: duplicate operationId '%s'", method, path, id))
+				continue
+			}
+			reg.Handler(id, handler)
+
+			route := RouteSpec{Method: method, Path: path, Handler: id, Metadata: make(map[string]string)}
+			for _, name := range []string{"summary", "description"} {
+				if s, ok := op[name].(string); ok && s != "" {
+					route.Metadata[name] = s
+				}
+			}
+			m.Routes = append(m.Routes, route)
+		}
+	}
+	for _, id := range slices.Sorted(maps.Keys(handlers)) {
+		if reg.handlers[id] == nil {
+			errs = append(errs, fmt.Errorf("handler '%s' has no operation", id))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return r.LoadRoutes(m, reg)
+}
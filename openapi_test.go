@@ -0,0 +1,195 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type openAPIUser struct {
+	ID       int          `json:"id"`
+	Name     string       `json:"name"`
+	Email    string       `json:"email,omitempty"`
+	Created  time.Time    `json:"created"`
+	Manager  *openAPIUser `json:"manager"`
+	Password string       `json:"-"`
+}
+
+func TestRouterOpenAPI(t *testing.T) {
+	router := New()
+	noop := func(http.ResponseWriter, *http.Request) {}
+	router.GET("/users/{id}", noop)
+	router.POST("/users", noop)
+	router.GET("/files/{path...}", noop)
+	router.WEBSOCKET("/ws", noop)
+	router.Describe(http.MethodPost, "/users", OperationDoc{
+		OperationID:    "createUser",
+		Summary:        "Create a user",
+		Request:        openAPIUser{},
+		Response:       &openAPIUser{},
+		ResponseStatus: http.StatusCreated,
+	})
+
+	doc := router.OpenAPI(OpenAPIInfo{Title: "Users", Version: "1.0"})
+	if doc.OpenAPI != "3.0.3" || doc.Info.Title != "Users" {
+		t.Fatalf("unexpected document header: %+v", doc)
+	}
+	if len(doc.Paths) != 3 {
+		t.Fatalf("expected 3 paths, got %v", doc.Paths)
+	}
+
+	show := doc.Paths["/users/{id}"]["get"]
+	if show == nil {
+		t.Fatal("missing GET /users/{id}")
+	}
+	if want := []OpenAPIParameter{{Name: "id", In: "path", Required: true, Schema: map[string]any{"type": "string"}}}; !reflect.DeepEqual(show.Parameters, want) {
+		t.Errorf("unexpected parameters %+v", show.Parameters)
+	}
+	if show.Responses["default"] == nil {
+		t.Errorf("expected a default response, got %v", show.Responses)
+	}
+	if files := doc.Paths["/files/{path}"]["get"]; files == nil || files.Parameters[0].Name != "path" {
+		t.Errorf("catch-all parameter not converted: %v", doc.Paths)
+	}
+
+	create := doc.Paths["/users"]["post"]
+	if create == nil || create.OperationID != "createUser" || create.Summary != "Create a user" {
+		t.Fatalf("unexpected operation %+v", create)
+	}
+	resp := create.Responses["201"]
+	if resp == nil || resp.Description != "Created" {
+		t.Fatalf("unexpected responses %v", create.Responses)
+	}
+	schema := resp.Content["application/json"]["schema"].(map[string]any)
+	props := schema["properties"].(map[string]any)
+	if _, ok := props["Password"]; ok {
+		t.Error("field tagged json:\"-\" in schema")
+	}
+	if got := props["created"]; !reflect.DeepEqual(got, map[string]any{"type": "string", "format": "date-time"}) {
+		t.Errorf("unexpected time schema %v", got)
+	}
+	if got := props["manager"]; !reflect.DeepEqual(got, map[string]any{}) {
+		t.Errorf("unexpected recursive schema %v", got)
+	}
+	if got := schema["required"]; !reflect.DeepEqual(got, []string{"created", "id", "name"}) {
+		t.Errorf("unexpected required fields %v", got)
+	}
+	if create.RequestBody == nil || !create.RequestBody.Required {
+		t.Errorf("missing request body")
+	}
+}
+
+func TestRouterOpenAPIMetadata(t *testing.T) {
+	router := New()
+	reg := newTestRegistry()
+	err := router.LoadRoutes(&RouteManifest{Routes: []RouteSpec{
+		{Method: http.MethodGet, Path: "/health", Handler: "health", Metadata: map[string]string{"summary": "Health check"}},
+	}}, reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := router.OpenAPI(OpenAPIInfo{})
+	if got := doc.Paths["/health"]["get"].Summary; got != "Health check" {
+		t.Errorf("expected summary from metadata, got %q", got)
+	}
+}
+
+func TestRouterServeOpenAPI(t *testing.T) {
+	router := New()
+	router.ServeOpenAPI("/openapi.json", OpenAPIInfo{Title: "API", Version: "1"})
+	router.ServeSwaggerUI("/docs", "/openapi.json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	var doc OpenAPIDocument
+	if err := json.NewDecoder(w.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Paths["/openapi.json"]["get"] == nil || doc.Paths["/docs"]["get"] == nil {
+		t.Errorf("document misses routes: %v", doc.Paths)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	if body := w.Body.String(); !strings.Contains(body, `url: "/openapi.json"`) || !strings.Contains(body, "swagger-ui-bundle.js") {
+		t.Errorf("unexpected Swagger UI page:\n%s", body)
+	}
+}
+
+func TestRouterLoadOpenAPI(t *testing.T) {
+	spec := []byte(`{
+		"openapi": "3.0.3",
+		"paths": {
+			"/users": {
+				"get": {"operationId": "listUsers", "summary": "List users"},
+				"post": {"operationId": "createUser"}
+			},
+			"/users/{id}": {
+				"parameters": [{"name": "id", "in": "path", "required": true}],
+				"get": {"operationId": "showUser"}
+			}
+		}
+	}`)
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(name + req.PathValue("id")))
+		})
+	}
+
+	router := New()
+	err := router.LoadOpenAPI(spec, nil, map[string]http.Handler{
+		"listUsers":  handler("list"),
+		"createUser": handler("create"),
+		"showUser":   handler("show"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct{ method, path, body string }{
+		{http.MethodGet, "/users", "list"},
+		{http.MethodPost, "/users", "create"},
+		{http.MethodGet, "/users/42", "show42"},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Body.String() != tt.body {
+			t.Errorf("%s %s: expected %q, got %q", tt.method, tt.path, tt.body, w.Body.String())
+		}
+	}
+	if got := router.RouteMetadata(http.MethodGet, "/users")["summary"]; got != "List users" {
+		t.Errorf("expected summary metadata, got %q", got)
+	}
+
+	router = New()
+	err = router.LoadOpenAPI(spec, nil, map[string]http.Handler{
+		"listUsers":  handler("list"),
+		"deleteUser": handler("delete"),
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		"POST /users: operation 'createUser' is not implemented",
+		"GET /users/{id}: operation 'showUser' is not implemented",
+		"handler 'deleteUser' has no operation",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+	if routes := router.getPaths(); len(routes) != 0 {
+		t.Errorf("expected no routes after a failed load, got %v", routes)
+	}
+}
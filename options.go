@@ -0,0 +1,102 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Option configures a Router created by New.
+type Option func(*Router)
+
+// WithRedirectTrailingSlash sets RedirectTrailingSlash, enabled by default.
+func WithRedirectTrailingSlash(enabled bool) Option {
+	return func(r *Router) {
+		r.RedirectTrailingSlash = enabled
+	}
+}
+
+// WithRedirectFixedPath sets RedirectFixedPath, enabled by default.
+func WithRedirectFixedPath(enabled bool) Option {
+	return func(r *Router) {
+		r.RedirectFixedPath = enabled
+	}
+}
+
+// WithHandleMethodNotAllowed sets HandleMethodNotAllowed, enabled by default.
+func WithHandleMethodNotAllowed(enabled bool) Option {
+	return func(r *Router) {
+		r.HandleMethodNotAllowed = enabled
+	}
+}
+
+// WithHandleOPTIONS sets HandleOPTIONS, enabled by default.
+func WithHandleOPTIONS(enabled bool) Option {
+	return func(r *Router) {
+		r.HandleOPTIONS = enabled
+	}
+}
+
+// WithGlobalOPTIONS sets the GlobalOPTIONS handler.
+func WithGlobalOPTIONS(h http.Handler) Option {
+	return func(r *Router) {
+		r.GlobalOPTIONS = h
+	}
+}
+
+// WithNotFound sets the NotFound handler.
+func WithNotFound(h http.Handler) Option {
+	return func(r *Router) {
+		r.NotFound = h
+	}
+}
+
+// WithMethodNotAllowed sets the MethodNotAllowed handler.
+func WithMethodNotAllowed(h http.Handler) Option {
+	return func(r *Router) {
+		r.MethodNotAllowed = h
+	}
+}
+
+// WithPanicHandler sets the PanicHandler.
+func WithPanicHandler(f func(http.ResponseWriter, *http.Request, interface{})) Option {
+	return func(r *Router) {
+		r.PanicHandler = f
+	}
+}
+
+// WithSaveMatchedRoutePath sets SaveMatchedRoutePath.
+func WithSaveMatchedRoutePath(enabled bool) Option {
+	return func(r *Router) {
+		r.SaveMatchedRoutePath = enabled
+	}
+}
+
+// WithProfileLabels sets ProfileLabels.
+func WithProfileLabels(enabled bool) Option {
+	return func(r *Router) {
+		r.ProfileLabels = enabled
+	}
+}
+
+// WithLogger sets the logger, see SetLogger. If logRequests is true, every
+// request is logged, see LogRequests.
+func WithLogger(logger *slog.Logger, logRequests bool) Option {
+	return func(r *Router) {
+		r.SetLogger(logger)
+		r.LogRequests = logRequests
+	}
+}
+
+// WithSlowRequests sets SlowRequestThreshold and the SlowRequest function,
+// which may be nil to log slow requests.
+func WithSlowRequests(threshold time.Duration, report func(*http.Request, RequestTiming)) Option {
+	return func(r *Router) {
+		r.SlowRequestThreshold = threshold
+		r.SlowRequest = report
+	}
+}
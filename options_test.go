@@ -0,0 +1,69 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewOptions(t *testing.T) {
+	notFound := http.FileServer(http.Dir("404"))
+	methodNotAllowed := http.FileServer(http.Dir("405"))
+	globalOptions := http.FileServer(http.Dir("options"))
+	logger := slog.New(slog.DiscardHandler)
+
+	router := New(
+		WithRedirectTrailingSlash(false),
+		WithRedirectFixedPath(false),
+		WithHandleMethodNotAllowed(false),
+		WithHandleOPTIONS(false),
+		WithGlobalOPTIONS(globalOptions),
+		WithNotFound(notFound),
+		WithMethodNotAllowed(methodNotAllowed),
+		WithPanicHandler(func(http.ResponseWriter, *http.Request, interface{}) {}),
+		WithSaveMatchedRoutePath(true),
+		WithProfileLabels(true),
+		WithLogger(logger, true),
+		WithSlowRequests(time.Second, nil),
+	)
+
+	if router.RedirectTrailingSlash || router.RedirectFixedPath || router.HandleMethodNotAllowed || router.HandleOPTIONS {
+		t.Error("expected the defaults to be disabled")
+	}
+	if router.GlobalOPTIONS != globalOptions || router.NotFound != notFound || router.MethodNotAllowed != methodNotAllowed {
+		t.Error("handlers not set")
+	}
+	if router.PanicHandler == nil || !router.SaveMatchedRoutePath || !router.ProfileLabels {
+		t.Error("options not applied")
+	}
+	if router.Logger() != logger || !router.LogRequests || router.SlowRequestThreshold != time.Second {
+		t.Error("logging options not applied")
+	}
+
+	if router := New(); !router.RedirectTrailingSlash || !router.RedirectFixedPath || !router.HandleMethodNotAllowed || !router.HandleOPTIONS {
+		t.Error("expected the defaults without options")
+	}
+}
+
+func TestNewPanicHandlerOption(t *testing.T) {
+	var recovered interface{}
+	router := New(WithPanicHandler(func(w http.ResponseWriter, r *http.Request, rcv interface{}) {
+		recovered = rcv
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	router.GET("/panic", func(http.ResponseWriter, *http.Request) {
+		panic("oops")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if recovered != "oops" || w.Code != http.StatusInternalServerError {
+		t.Errorf("expected the panic handler to run, got %v and %d", recovered, w.Code)
+	}
+}
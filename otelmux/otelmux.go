@@ -0,0 +1,120 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+// Package otelmux provides OpenTelemetry tracing for httpmux routers.
+//
+// Generic HTTP instrumentation cannot know which route a request matched, so
+// its spans lack the low-cardinality http.route attribute. The middleware
+// returned by Middleware runs after routing and therefore can supply it:
+//
+//	router := httpmux.New()
+//	router.Use(otelmux.Middleware())
+//	router.GET("/users/{id}", showUser) // span "GET /users/{id}"
+//
+// The middleware only wraps routes registered after the call to Use.
+package otelmux
+
+import (
+	"net/http"
+
+	"github.com/g-h-miles/httpmux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope name of the tracer.
+const ScopeName = "github.com/g-h-miles/httpmux/otelmux"
+
+// Attribute keys following the OpenTelemetry HTTP semantic conventions.
+const (
+	keyRoute      = attribute.Key("http.route")
+	keyMethod     = attribute.Key("http.request.method")
+	keyPath       = attribute.Key("url.path")
+	keyStatusCode = attribute.Key("http.response.status_code")
+)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagators    propagation.TextMapPropagator
+}
+
+// Option configures the middleware.
+type Option func(*config)
+
+// WithTracerProvider sets the TracerProvider used to start spans.
+// The global provider is used by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = tp
+	}
+}
+
+// WithPropagators sets the propagators used to extract a remote span context
+// from the request headers. The global propagators are used by default.
+func WithPropagators(p propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = p
+	}
+}
+
+// Middleware returns a middleware for httpmux.Router.Use which traces every
+// request with a span named after the request method and the matched route.
+//
+// If the request context already carries a recording span, e.g. because the
+// server is wrapped by otelhttp, that span is extended instead: it is renamed
+// and the http.route attribute is set. Otherwise a new server span is started,
+// continuing any trace propagated in the request headers.
+//
+// The route reported is the full pattern returned by httpmux.RoutePattern, so
+// routes of MultiRouter groups include the group prefix.
+func Middleware(opts ...Option) func(http.Handler) http.Handler {
+	cfg := config{
+		tracerProvider: otel.GetTracerProvider(),
+		propagators:    otel.GetTextMapPropagator(),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	tracer := cfg.tracerProvider.Tracer(ScopeName)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := httpmux.RoutePattern(r)
+			name := r.Method + " " + route
+
+			if span := trace.SpanFromContext(r.Context()); span.IsRecording() {
+				span.SetName(name)
+				span.SetAttributes(keyRoute.String(route))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := cfg.propagators.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					keyMethod.String(r.Method),
+					keyRoute.String(route),
+					keyPath.String(r.URL.Path),
+				),
+			)
+			defer span.End()
+
+			ww := httpmux.WrapWriter(w)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttributes(keyStatusCode.Int(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		})
+	}
+}
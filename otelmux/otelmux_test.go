@@ -0,0 +1,117 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package otelmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/g-h-miles/httpmux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func attr(attrs []attribute.KeyValue, key attribute.Key) attribute.Value {
+	for _, kv := range attrs {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestMiddlewareStartsSpan(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	router := httpmux.New()
+	router.Use(Middleware(WithTracerProvider(tp)))
+	router.GET("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if !trace.SpanFromContext(r.Context()).SpanContext().IsValid() {
+			t.Error("expected span in handler context")
+		}
+		if r.PathValue("id") != "42" {
+			t.Errorf("unexpected path value %q", r.PathValue("id"))
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	r, _ := http.NewRequest(http.MethodGet, "/users/42", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /users/{id}" {
+		t.Errorf("unexpected span name %q", span.Name())
+	}
+	if span.SpanKind() != trace.SpanKindServer {
+		t.Errorf("unexpected span kind %v", span.SpanKind())
+	}
+	if route := attr(span.Attributes(), keyRoute).AsString(); route != "/users/{id}" {
+		t.Errorf("unexpected http.route %q", route)
+	}
+	if code := attr(span.Attributes(), keyStatusCode).AsInt64(); code != http.StatusServiceUnavailable {
+		t.Errorf("unexpected status code %d", code)
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("expected error status, got %v", span.Status().Code)
+	}
+}
+
+func TestMiddlewareExtendsSpan(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	router := httpmux.New()
+	router.Use(Middleware(WithTracerProvider(tp)))
+	router.GET("/files/{path...}", func(_ http.ResponseWriter, _ *http.Request) {})
+
+	r, _ := http.NewRequest(http.MethodGet, "/files/a/b", nil)
+	ctx, span := tp.Tracer("outer").Start(r.Context(), "outer")
+	router.ServeHTTP(httptest.NewRecorder(), r.WithContext(ctx))
+	span.End()
+
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected only the outer span, got %d spans", len(spans))
+	}
+	if spans[0].Name() != "GET /files/{path...}" {
+		t.Errorf("unexpected span name %q", spans[0].Name())
+	}
+	if route := attr(spans[0].Attributes(), keyRoute).AsString(); route != "/files/{path...}" {
+		t.Errorf("unexpected http.route %q", route)
+	}
+}
+
+func TestMiddlewareGroupRoute(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	multi := httpmux.NewMultiRouter()
+	api := multi.NewGroup("/api")
+	api.Use(Middleware(WithTracerProvider(tp)))
+	api.GET("/users/{id}", func(_ http.ResponseWriter, _ *http.Request) {})
+
+	r, _ := http.NewRequest(http.MethodGet, "/api/users/42", nil)
+	multi.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Name() != "GET /api/users/{id}" {
+		t.Errorf("unexpected span name %q", spans[0].Name())
+	}
+	if route := attr(spans[0].Attributes(), keyRoute).AsString(); route != "/api/users/{id}" {
+		t.Errorf("unexpected http.route %q", route)
+	}
+}
@@ -164,10 +164,22 @@ func preCleanPath(path string) string {
 	return path
 }
 
+// patternParams returns the names of the parameters of a route's path, in
+// order, without the "..." of a catch-all parameter.
+func patternParams(pattern string) []string {
+	var names []string
+	for _, seg := range strings.Split(pattern, "/") {
+		if len(seg) > 2 && seg[0] == '{' && seg[len(seg)-1] == '}' {
+			names = append(names, strings.TrimSuffix(seg[1:len(seg)-1], "..."))
+		}
+	}
+	return names
+}
+
 // utility functions for getting all paths from the router
 func (r *Router) getPaths() []string {
 	var paths []string
-	for _, tree := range r.trees {
+	for _, tree := range r.trees.Load().all() {
 		if tree != nil {
 			treePaths := r.findRecursiveChildren(tree, "")
 			paths = append(paths, treePaths...)
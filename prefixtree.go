@@ -0,0 +1,102 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import "strings"
+
+// prefixNode is a node of the tree of group prefixes, with one level per path
+// segment. The group of a request is found by walking the segments of its
+// path, so dispatching does not depend on the number of groups.
+type prefixNode struct {
+	static map[string]*prefixNode // Children by static segment
+	param  *prefixNode            // Child for a parameter segment
+	group  *group                 // Group whose prefix ends at this node
+}
+
+// newPrefixTree builds the tree of the given groups. The root group is not
+// included, since it matches requests not matching any other group.
+// If several prefixes match the same paths, e.g. /t/{a} and /t/{b}, the first
+// one is used.
+func newPrefixTree(prefixes []string, groups map[string]*group) *prefixNode {
+	root := &prefixNode{}
+	for _, prefix := range prefixes {
+		if prefix != "/" {
+			root.insert(groups[prefix])
+		}
+	}
+	return root
+}
+
+func (n *prefixNode) insert(g *group) {
+	if n = n.node(g.prefix, true); n.group == nil {
+		n.group = g
+	}
+}
+
+// find returns the group stored for the prefix, which is a different one if
+// an earlier prefix matches the same paths, or nil.
+func (n *prefixNode) find(prefix string) *group {
+	if n = n.node(prefix, false); n == nil {
+		return nil
+	}
+	return n.group
+}
+
+// node returns the node for the prefix. If create is set, missing nodes are
+// added, otherwise nil is returned for them.
+func (n *prefixNode) node(prefix string, create bool) *prefixNode {
+	for path := prefix[1:]; n != nil; {
+		seg, rest, more := strings.Cut(path, "/")
+
+		var child *prefixNode
+		if strings.HasPrefix(seg, "{") {
+			if n.param == nil && create {
+				n.param = &prefixNode{}
+			}
+			child = n.param
+		} else {
+			if child = n.static[seg]; child == nil && create {
+				if n.static == nil {
+					n.static = make(map[string]*prefixNode)
+				}
+				child = &prefixNode{}
+				n.static[seg] = child
+			}
+		}
+		n = child
+
+		if !more {
+			break
+		}
+		path = rest
+	}
+	return n
+}
+
+// lookup returns the group with the longest prefix matching the path, or nil.
+// A prefix only matches whole path segments. Static segments take precedence
+// over parameters: a parameter only matches if no group below the static
+// segment matches.
+func (n *prefixNode) lookup(path string) *group {
+	if path == "" || path[0] != '/' {
+		return n.group
+	}
+	seg, rest := path[1:], ""
+	if i := strings.IndexByte(seg, '/'); i >= 0 {
+		seg, rest = seg[:i], seg[i:]
+	}
+
+	if child := n.static[seg]; child != nil {
+		if g := child.lookup(rest); g != nil {
+			return g
+		}
+	}
+	if n.param != nil && seg != "" {
+		if g := n.param.lookup(rest); g != nil {
+			return g
+		}
+	}
+	return n.group
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestPrefixTreeLookup(t *testing.T) {
+	prefixes := []string{"/api/v2", "/api", "/t/{tenant}/admin", "/t/{tenant}", "/t/{id}"}
+	groups := make(map[string]*group)
+	for _, prefix := range prefixes {
+		groups[prefix] = &group{prefix: prefix}
+	}
+	tree := newPrefixTree(prefixes, groups)
+
+	tests := []struct {
+		path, prefix string
+	}{
+		{"/api", "/api"},
+		{"/api/", "/api"},
+		{"/api/users", "/api"},
+		{"/api/v2", "/api/v2"},
+		{"/api/v2/users", "/api/v2"},
+		{"/api/v3", "/api"},
+		{"/apix", ""},
+		{"/t/acme/admin/users", "/t/{tenant}/admin"},
+		{"/t/acme/users", "/t/{tenant}"},
+		{"/t/", ""},
+		{"/", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		var prefix string
+		if g := tree.lookup(tt.path); g != nil {
+			prefix = g.prefix
+		}
+		if prefix != tt.prefix {
+			t.Errorf("lookup(%q): expected group %q, got %q", tt.path, tt.prefix, prefix)
+		}
+	}
+}
+
+func TestPrefixTreeStaticBeforeParam(t *testing.T) {
+	prefixes := []string{"/t/admin", "/t/{tenant}/settings"}
+	groups := make(map[string]*group)
+	for _, prefix := range prefixes {
+		groups[prefix] = &group{prefix: prefix}
+	}
+	tree := newPrefixTree(prefixes, groups)
+
+	if g := tree.lookup("/t/admin/settings"); g == nil || g.prefix != "/t/admin" {
+		t.Errorf("expected static prefix to take precedence, got %v", g)
+	}
+	if g := tree.lookup("/t/acme/settings"); g == nil || g.prefix != "/t/{tenant}/settings" {
+		t.Errorf("expected param prefix to match, got %v", g)
+	}
+}
+
+func BenchmarkMultiRouterManyGroups(b *testing.B) {
+	multi := NewMultiRouter()
+	for i := range 500 {
+		multi.NewGroup("/tenant"+strconv.Itoa(i)).GET("/info", dummyHandler)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/tenant250/info", nil)
+	w := httptest.NewRecorder()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		multi.ServeHTTP(w, req)
+	}
+}
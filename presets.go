@@ -0,0 +1,97 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"html/template"
+	"net/http"
+	"strconv"
+)
+
+// StrictAPI returns a Router configured for JSON APIs: paths are matched
+// exactly, without trailing slash or fixed path redirects, which clients of
+// an API rarely follow for methods other than GET. Unmatched methods are
+// answered with 405 Method Not Allowed, OPTIONS requests automatically, and
+// errors, including recovered panics, as JSON like RespondError does:
+//
+//	{"error": "404 Not Found", "request_id": "..."}
+//
+// The options are applied afterwards and may change any of these settings.
+func StrictAPI(opts ...Option) *Router {
+	return New(append([]Option{
+		WithRedirectTrailingSlash(false),
+		WithRedirectFixedPath(false),
+		WithHandleMethodNotAllowed(true),
+		WithHandleOPTIONS(true),
+		WithNotFound(jsonErrorHandler(http.StatusNotFound)),
+		WithMethodNotAllowed(jsonErrorHandler(http.StatusMethodNotAllowed)),
+		WithPanicHandler(func(w http.ResponseWriter, req *http.Request, _ interface{}) {
+			jsonErrorHandler(http.StatusInternalServerError).ServeHTTP(w, req)
+		}),
+	}, opts...)...)
+}
+
+// LenientWeb returns a Router configured for web sites visited with browsers:
+// requests with a missing or superfluous trailing slash, or with a path
+// differing in case or containing superfluous elements, are redirected to the
+// registered route. Unmatched methods are answered with 405 Method Not
+// Allowed, and errors, including recovered panics, with small HTML pages.
+//
+// The options are applied afterwards and may change any of these settings.
+func LenientWeb(opts ...Option) *Router {
+	return New(append([]Option{
+		WithRedirectTrailingSlash(true),
+		WithRedirectFixedPath(true),
+		WithHandleMethodNotAllowed(true),
+		WithHandleOPTIONS(true),
+		WithNotFound(htmlErrorHandler(http.StatusNotFound)),
+		WithMethodNotAllowed(htmlErrorHandler(http.StatusMethodNotAllowed)),
+		WithPanicHandler(func(w http.ResponseWriter, req *http.Request, _ interface{}) {
+			htmlErrorHandler(http.StatusInternalServerError).ServeHTTP(w, req)
+		}),
+	}, opts...)...)
+}
+
+// statusError is an error with a status code for RespondError.
+type statusError int
+
+func (e statusError) Error() string {
+	return strconv.Itoa(int(e)) + " " + http.StatusText(int(e))
+}
+
+func (e statusError) StatusCode() int {
+	return int(e)
+}
+
+func jsonErrorHandler(code int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		RespondError(w, req, statusError(code))
+	})
+}
+
+var errorPageTemplate = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Status}}</title>
+</head>
+<body>
+<h1>{{.Status}}</h1>
+{{with .RequestID}}<p>Request ID: {{.}}</p>
+{{end}}</body>
+</html>
+`))
+
+func htmlErrorHandler(code int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(code)
+		errorPageTemplate.Execute(w, struct {
+			Status    string
+			RequestID string
+		}{statusError(code).Error(), RequestID(req)})
+	})
+}
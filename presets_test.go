@@ -0,0 +1,67 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func servePreset(router *Router, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestStrictAPI(t *testing.T) {
+	router := StrictAPI()
+	router.GET("/users", func(http.ResponseWriter, *http.Request) {})
+	router.GET("/panic", func(http.ResponseWriter, *http.Request) { panic("oops") })
+
+	for _, tt := range []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{http.MethodGet, "/users/", http.StatusNotFound, `{"error":"404 Not Found"}`},
+		{http.MethodGet, "/USERS", http.StatusNotFound, `{"error":"404 Not Found"}`},
+		{http.MethodPost, "/users", http.StatusMethodNotAllowed, `{"error":"405 Method Not Allowed"}`},
+		{http.MethodGet, "/panic", http.StatusInternalServerError, `{"error":"500 Internal Server Error"}`},
+	} {
+		w := servePreset(router, tt.method, tt.path)
+		if w.Code != tt.code || strings.TrimSpace(w.Body.String()) != tt.body {
+			t.Errorf("%s %s: expected %d %s, got %d %s", tt.method, tt.path, tt.code, tt.body, w.Code, w.Body.String())
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s %s: unexpected content type %q", tt.method, tt.path, ct)
+		}
+	}
+
+	if router := StrictAPI(WithRedirectTrailingSlash(true)); !router.RedirectTrailingSlash {
+		t.Error("expected options to override the preset")
+	}
+}
+
+func TestLenientWeb(t *testing.T) {
+	router := LenientWeb()
+	router.GET("/about", func(http.ResponseWriter, *http.Request) {})
+
+	if w := servePreset(router, http.MethodGet, "/about/"); w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/about" {
+		t.Errorf("expected a trailing slash redirect, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := servePreset(router, http.MethodGet, "/ABOUT"); w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/about" {
+		t.Errorf("expected a fixed path redirect, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	w := servePreset(router, http.MethodGet, "/missing")
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "<h1>404 Not Found</h1>") {
+		t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"context"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// ProxyOption configures a reverse proxy.
+type ProxyOption func(*httputil.ReverseProxy)
+
+// WithProxyRewrite adds a hook which modifies requests to the upstream
+// server, e.g. to add or remove headers. It is called after the request URL
+// was set to the upstream and the X-Forwarded headers were added.
+func WithProxyRewrite(rewrite func(*httputil.ProxyRequest)) ProxyOption {
+	return func(p *httputil.ReverseProxy) {
+		prev := p.Rewrite
+		p.Rewrite = func(pr *httputil.ProxyRequest) {
+			prev(pr)
+			rewrite(pr)
+		}
+	}
+}
+
+// WithProxyModifyResponse sets a hook which modifies responses of the
+// upstream server, see httputil.ReverseProxy.ModifyResponse.
+func WithProxyModifyResponse(modify func(*http.Response) error) ProxyOption {
+	return func(p *httputil.ReverseProxy) {
+		p.ModifyResponse = modify
+	}
+}
+
+// WithProxyErrorHandler sets the handler for requests which could not be
+// proxied, e.g. because the upstream server is unreachable. By default, they
+// are answered with 502 Bad Gateway, like other errors of the router.
+func WithProxyErrorHandler(handler func(http.ResponseWriter, *http.Request, error)) ProxyOption {
+	return func(p *httputil.ReverseProxy) {
+		p.ErrorHandler = handler
+	}
+}
+
+// newReverseProxy returns a reverse proxy forwarding requests to the target,
+// appending their path to the target's path.
+func newReverseProxy(target *url.URL, opts []ProxyOption) *httputil.ReverseProxy {
+	return newRewriteProxy(func(pr *httputil.ProxyRequest) {
+		pr.SetURL(target)
+	}, opts)
+}
+
+// newRewriteProxy returns a reverse proxy which sets the URL of outgoing
+// requests with setURL.
+func newRewriteProxy(setURL func(*httputil.ProxyRequest), opts []ProxyOption) *httputil.ReverseProxy {
+	p := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			setURL(pr)
+			pr.SetXForwarded()
+		},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			writeError(w, req, "502 bad gateway", http.StatusBadGateway)
+		},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Proxy forwards all requests below the prefix to an upstream server, e.g. to
+// move a part of an application into a separate service:
+//
+//	multi.Proxy("/auth", "http://auth-service:8080")
+//
+// The prefix is stripped from the request path, which is appended to the
+// path of the target, so /auth/login is forwarded to
+// http://auth-service:8080/login. The Host header is set to the target's
+// host and X-Forwarded-For, X-Forwarded-Host and X-Forwarded-Proto headers
+// are added. The proxy is configured by options, e.g. to rewrite headers.
+//
+// The prefix is registered and checked for conflicts like a handler added by
+// Mount. Proxy panics if the target is not an absolute URL.
+func (m *MultiRouter) Proxy(prefix, target string, opts ...ProxyOption) {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		panic("invalid proxy target '" + target + "' for prefix '" + prefix + "'")
+	}
+	m.Mount(prefix, newReverseProxy(u, opts))
+}
+
+var proxyTargetContextKey = &contextKey{"proxy-target"}
+
+// Proxy returns a handler forwarding requests to an upstream server, whose
+// URL may reference path values of the route, e.g. to route to services by
+// name:
+//
+//	router.Handle(http.MethodGet, "/services/{name}/{rest...}",
+//		httpmux.Proxy("http://{name}.internal/{rest}"))
+//
+// A catch-all value like {rest...} may be referenced as {rest} or {rest...};
+// its leading slash is dropped after a slash of the target. If the path of
+// the target references no path values, the request path is appended to it,
+// as with MultiRouter.Proxy. The query of the request is kept.
+//
+// Requests are answered with 400 Bad Request if a value used in the host
+// contains characters other than letters, digits, '-' and '.', or if a value
+// used in the path contains ".." segments, so that clients cannot redirect
+// the request to other hosts or paths. The Host and X-Forwarded headers and
+// the options are handled as by MultiRouter.Proxy.
+//
+// Proxy panics if the target is not an absolute URL, a reference is not
+// terminated or its name is not an identifier.
+func Proxy(target string, opts ...ProxyOption) http.Handler {
+	t := parseProxyTarget(target)
+
+	proxy := newRewriteProxy(func(pr *httputil.ProxyRequest) {
+		u := pr.In.Context().Value(proxyTargetContextKey).(*url.URL)
+		if !t.pathParams {
+			pr.SetURL(u)
+			return
+		}
+		// SetURL joins the paths, which is not wanted here
+		path := u.Path
+		u.Path = ""
+		pr.SetURL(u)
+		pr.Out.URL.Path = path
+		pr.Out.URL.RawPath = ""
+	}, opts)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		u, ok := t.expand(req)
+		if !ok {
+			writeError(w, req, "400 bad request", http.StatusBadRequest)
+			return
+		}
+		ctx := context.WithValue(req.Context(), proxyTargetContextKey, u)
+		proxy.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// proxyTarget is a parsed target of Proxy.
+type proxyTarget struct {
+	scheme     string
+	host       string // May contain references
+	path       string // May contain references
+	rawQuery   string
+	pathParams bool // Whether the path contains references
+}
+
+func parseProxyTarget(target string) *proxyTarget {
+	scheme, rest, ok := strings.Cut(target, "://")
+	if !ok || scheme == "" || rest == "" || rest[0] == '/' {
+		panic("invalid proxy target '" + target + "'")
+	}
+	for refs := target; ; {
+		i := strings.IndexAny(refs, "{}")
+		if i < 0 {
+			break
+		}
+		end := strings.IndexByte(refs[i+1:], '}') + i + 1
+		if refs[i] == '}' || end == i {
+			panic("unterminated reference in proxy target '" + target + "'")
+		}
+		if name := strings.TrimSuffix(refs[i+1:end], "..."); !isIdentifier(name) {
+			panic("invalid reference '" + refs[i:end+1] + "' in proxy target '" + target + "'")
+		}
+		refs = refs[end+1:]
+	}
+
+	t := &proxyTarget{scheme: scheme}
+	rest, t.rawQuery, _ = strings.Cut(rest, "?")
+	if i := strings.IndexByte(rest, '/'); i >= 0 {
+		t.host, t.path = rest[:i], rest[i:]
+	} else {
+		t.host = rest
+	}
+	t.pathParams = strings.Contains(t.path, "{")
+	return t
+}
+
+// isIdentifier reports whether the name of a reference is a Go identifier, as
+// required for the wildcard names of http.ServeMux patterns.
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if !unicode.IsLetter(c) && c != '_' && (i == 0 || !unicode.IsDigit(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// expand returns the target URL for the request, or false if a referenced
+// path value is invalid.
+func (t *proxyTarget) expand(req *http.Request) (*url.URL, bool) {
+	host, ok := expandProxyTemplate(t.host, req, validProxyHost)
+	if !ok {
+		return nil, false
+	}
+	path, ok := expandProxyTemplate(t.path, req, func(v string) bool {
+		return !invalidFilePath(v)
+	})
+	if !ok {
+		return nil, false
+	}
+	return &url.URL{Scheme: t.scheme, Host: host, Path: path, RawQuery: t.rawQuery}, true
+}
+
+// expandProxyTemplate replaces the references in the template with the path
+// values of the request, which must be valid.
+func expandProxyTemplate(tmpl string, req *http.Request, valid func(string) bool) (string, bool) {
+	if !strings.Contains(tmpl, "{") {
+		return tmpl, true
+	}
+
+	var sb strings.Builder
+	for {
+		i := strings.IndexByte(tmpl, '{')
+		if i < 0 {
+			sb.WriteString(tmpl)
+			return sb.String(), true
+		}
+		end := strings.IndexByte(tmpl[i:], '}') + i
+		sb.WriteString(tmpl[:i])
+
+		value := req.PathValue(strings.TrimSuffix(tmpl[i+1:end], "..."))
+		if i > 0 && tmpl[i-1] == '/' {
+			value = strings.TrimPrefix(value, "/")
+		}
+		if !valid(value) {
+			return "", false
+		}
+		sb.WriteString(value)
+		tmpl = tmpl[end+1:]
+	}
+}
+
+// validProxyHost reports whether a path value may be used in the host of a
+// proxy target.
+func validProxyHost(v string) bool {
+	if v == "" {
+		return false
+	}
+	for _, c := range []byte(v) {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,123 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"strings"
+	"testing"
+)
+
+func TestMultiRouterProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Upstream", "1")
+		w.Write([]byte(r.URL.String() + " " + r.Header.Get("X-Forwarded-Host") + " " + r.Header.Get("X-Hook") + " " + r.Header.Get("Cookie")))
+	}))
+	defer upstream.Close()
+
+	multi := NewMultiRouter()
+	multi.NewGroup("/api").GET("/users", dummyHandler)
+	multi.Proxy("/auth", upstream.URL+"/v1",
+		WithProxyRewrite(func(pr *httputil.ProxyRequest) {
+			pr.Out.Header.Set("X-Hook", "rewritten")
+			pr.Out.Header.Del("Cookie")
+		}),
+		WithProxyModifyResponse(func(resp *http.Response) error {
+			resp.Header.Del("X-Upstream")
+			return nil
+		}),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/auth/login?next=/home", nil)
+	req.Header.Set("Cookie", "session=1")
+	w := httptest.NewRecorder()
+	multi.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "/v1/login?next=/home example.com rewritten " {
+		t.Errorf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("X-Upstream") != "" {
+		t.Error("expected response hook to remove the upstream header")
+	}
+
+	if recv := catchPanic(func() { multi.Proxy("/other", "auth-service:8080") }); recv == nil {
+		t.Error("expected panic for relative target")
+	}
+	if recv := catchPanic(func() { multi.Proxy("/api", upstream.URL) }); recv == nil {
+		t.Error("expected conflict for registered prefix")
+	}
+}
+
+func TestMultiRouterProxyError(t *testing.T) {
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	upstream.Close()
+
+	multi := NewMultiRouter()
+	multi.Proxy("/down", upstream.URL)
+	var proxyErr error
+	multi.Proxy("/custom", upstream.URL, WithProxyErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		proxyErr = err
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	w := httptest.NewRecorder()
+	multi.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/down/x", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("expected 502, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	multi.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/custom/x", nil))
+	if w.Code != http.StatusServiceUnavailable || proxyErr == nil {
+		t.Errorf("expected custom error handler, got %d %v", w.Code, proxyErr)
+	}
+}
+
+func TestProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host + " " + r.URL.String()))
+	}))
+	defer upstream.Close()
+	upstreamHost := strings.TrimPrefix(upstream.URL, "http://")
+
+	// Requests to {name}.internal are sent to the test server instead
+	var targetHost string
+	toUpstream := WithProxyRewrite(func(pr *httputil.ProxyRequest) {
+		targetHost = pr.Out.URL.Host
+		pr.Out.URL.Host = upstreamHost
+	})
+
+	router := New()
+	router.Handle(http.MethodGet, "/services/{name}/{rest...}", Proxy("http://{name}.internal/v1/{rest}", toUpstream))
+	router.Handle(http.MethodGet, "/static/{rest...}", Proxy(upstream.URL+"/base"))
+	router.Handle(http.MethodGet, "/query/{id}", Proxy(upstream.URL+"/items/{id}?source=mux"))
+
+	tests := []struct {
+		path, body, host string
+		code             int
+	}{
+		{"/services/users/list?page=2", upstreamHost + " /v1/list?page=2", "users.internal", http.StatusOK},
+		{"/static/app.js", upstreamHost + " /base/static/app.js", "", http.StatusOK},
+		{"/query/42?x=1", upstreamHost + " /items/42?source=mux&x=1", "", http.StatusOK},
+		{"/services/evil.com:80@x/list", "", "", http.StatusBadRequest},
+		{"/services/users/a/../../admin", "", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		targetHost = ""
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.code || tt.body != "" && w.Body.String() != tt.body || targetHost != tt.host {
+			t.Errorf("%s: unexpected response %d %q to host %q", tt.path, w.Code, w.Body.String(), targetHost)
+		}
+	}
+
+	for _, target := range []string{"internal/{rest}", "http:///path", "http://{name.internal/",
+		"http://{name}.internal/x}{y", "http://{name}.internal/}", "http://{}.internal/", "http://{na-me}.internal/", "http://{{name}}.internal/"} {
+		if recv := catchPanic(func() { Proxy(target) }); recv == nil {
+			t.Errorf("%s: expected panic", target)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitOption configures the rate limiter of RateLimit.
+type RateLimitOption func(*rateLimiter)
+
+// RateLimitKey sets the function returning the client a request is counted
+// for, KeyByIP by default. Requests with an empty key share a single bucket.
+func RateLimitKey(key func(*http.Request) string) RateLimitOption {
+	return func(l *rateLimiter) {
+		l.key = key
+	}
+}
+
+// RateLimitAcrossRoutes counts the requests of a client for all routes of the
+// middleware together, instead of for each route separately.
+func RateLimitAcrossRoutes() RateLimitOption {
+	return func(l *rateLimiter) {
+		l.acrossRoutes = true
+	}
+}
+
+// RateLimitExceeded sets the handler for requests exceeding the limit, which
+// are answered with 429 Too Many Requests by default. The Retry-After header
+// is set before the handler is called.
+func RateLimitExceeded(handler http.Handler) RateLimitOption {
+	return func(l *rateLimiter) {
+		l.exceeded = handler
+	}
+}
+
+// KeyByIP returns the IP address of the client from the request's RemoteAddr.
+func KeyByIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// KeyByHeader returns a key function returning the value of the header, e.g.
+// of an API key.
+func KeyByHeader(name string) func(*http.Request) string {
+	return func(req *http.Request) string {
+		return req.Header.Get(name)
+	}
+}
+
+// KeyByPathValue returns a key function returning the path value with the
+// name, e.g. of a tenant.
+func KeyByPathValue(name string) func(*http.Request) string {
+	return func(req *http.Request) string {
+		return req.PathValue(name)
+	}
+}
+
+// RateLimit returns a middleware allowing each client limit requests per
+// period to each route, with a token bucket refilled at a steady rate, so
+// clients may send bursts of up to limit requests:
+//
+//	router.Use(httpmux.RateLimit(100, time.Minute))
+//	router.Handle(http.MethodPost, "/login", httpmux.RateLimit(5, time.Minute)(login))
+//	multi.Group("/api", api, httpmux.WithMiddleware(
+//		httpmux.RateLimit(1000, time.Hour, httpmux.RateLimitKey(httpmux.KeyByHeader("X-API-Key"))),
+//	))
+//
+// Clients are told apart by their IP address, see RateLimitKey. Routes are
+// told apart by the request's Pattern, so the middleware should be added by
+// Router.Use, or wrap single handlers; requests no route matched, e.g. those
+// seen by group middlewares, are counted together. Each middleware returned
+// by RateLimit has its own buckets.
+//
+// Responses carry the RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers. Requests exceeding the limit are answered with 429
+// Too Many Requests and a Retry-After header, see RateLimitExceeded.
+// RateLimit panics if limit or period are not positive.
+func RateLimit(limit int, period time.Duration, opts ...RateLimitOption) func(http.Handler) http.Handler {
+	return newRateLimiter(limit, period, opts).middleware
+}
+
+// rateLimiter holds the token buckets of the clients of a RateLimit
+// middleware.
+type rateLimiter struct {
+	limit        float64
+	rate         float64 // tokens per second
+	period       time.Duration
+	key          func(*http.Request) string
+	acrossRoutes bool
+	exceeded     http.Handler
+	now          func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func newRateLimiter(limit int, period time.Duration, opts []RateLimitOption) *rateLimiter {
+	if limit <= 0 || period <= 0 {
+		panic("rate limit and period must be positive")
+	}
+	l := &rateLimiter{
+		limit:   float64(limit),
+		rate:    float64(limit) / period.Seconds(),
+		period:  period,
+		key:     KeyByIP,
+		now:     time.Now,
+		buckets: make(map[string]*tokenBucket),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+func (l *rateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		key := l.key(req)
+		if !l.acrossRoutes {
+			key = req.Pattern + " " + key
+		}
+		allowed, remaining, reset, retry := l.take(key)
+
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(int(l.limit)))
+		h.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(reset)))
+		if allowed {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		h.Set("Retry-After", strconv.Itoa(ceilSeconds(retry)))
+		if l.exceeded != nil {
+			l.exceeded.ServeHTTP(w, req)
+		} else {
+			writeError(w, req, "429 too many requests", http.StatusTooManyRequests)
+		}
+	})
+}
+
+// take takes a token from the bucket of the key, if there is one. It returns
+// the tokens left, the time until the bucket is full and the time until the
+// next token is available.
+func (l *rateLimiter) take(key string) (allowed bool, remaining int, reset, retry time.Duration) {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Buckets not used for a period are full, so they can be dropped
+	if now.Sub(l.lastSweep) > l.period {
+		for k, b := range l.buckets {
+			if now.Sub(b.updated) > l.period {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b := l.buckets[key]
+	if b == nil {
+		b = &tokenBucket{tokens: l.limit, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.limit, b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+
+	if b.tokens >= 1 {
+		allowed = true
+		b.tokens--
+	} else {
+		retry = l.duration(1 - b.tokens)
+	}
+	return allowed, int(b.tokens), l.duration(l.limit - b.tokens), retry
+}
+
+// duration returns the time it takes to refill the tokens.
+func (l *rateLimiter) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+// ceilSeconds rounds the duration up to whole seconds.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	now := time.Unix(1000, 0)
+	limiter := newRateLimiter(2, time.Minute, nil)
+	limiter.now = func() time.Time { return now }
+
+	router := New()
+	router.Use(limiter.middleware)
+	ok := func(w http.ResponseWriter, _ *http.Request) {}
+	router.GET("/a", ok)
+	router.GET("/b", ok)
+
+	serve := func(path, addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = addr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		advance                 time.Duration
+		path, addr              string
+		code                    int
+		remaining, reset, retry string
+	}{
+		{0, "/a", "10.0.0.1:1234", http.StatusOK, "1", "30", ""},
+		{0, "/a", "10.0.0.1:5678", http.StatusOK, "0", "60", ""},
+		{0, "/a", "10.0.0.1:1234", http.StatusTooManyRequests, "0", "60", "30"},
+		{0, "/b", "10.0.0.1:1234", http.StatusOK, "1", "30", ""},
+		{0, "/a", "10.0.0.2:1234", http.StatusOK, "1", "30", ""},
+		{20 * time.Second, "/a", "10.0.0.1:1234", http.StatusTooManyRequests, "0", "40", "10"},
+		{10 * time.Second, "/a", "10.0.0.1:1234", http.StatusOK, "0", "60", ""},
+	}
+	for i, tt := range tests {
+		now = now.Add(tt.advance)
+		w := serve(tt.path, tt.addr)
+		h := w.Header()
+		if w.Code != tt.code || h.Get("RateLimit-Limit") != "2" ||
+			h.Get("RateLimit-Remaining") != tt.remaining ||
+			h.Get("RateLimit-Reset") != tt.reset || h.Get("Retry-After") != tt.retry {
+			t.Errorf("request %d: got %d, headers %v", i, w.Code, h)
+		}
+	}
+
+	// Idle buckets are dropped
+	now = now.Add(2 * time.Minute)
+	serve("/a", "10.0.0.3:1234")
+	if n := len(limiter.buckets); n != 1 {
+		t.Errorf("got %d buckets after sweep, want 1", n)
+	}
+}
+
+func TestRateLimitOptions(t *testing.T) {
+	exceeded := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	router := New()
+	router.Use(RateLimit(1, time.Hour,
+		RateLimitKey(KeyByHeader("X-API-Key")),
+		RateLimitAcrossRoutes(),
+		RateLimitExceeded(exceeded),
+	))
+	ok := func(w http.ResponseWriter, _ *http.Request) {}
+	router.GET("/a", ok)
+	router.GET("/b", ok)
+
+	tests := []struct {
+		path, key string
+		code      int
+	}{
+		{"/a", "k1", http.StatusOK},
+		{"/b", "k1", http.StatusServiceUnavailable},
+		{"/b", "k2", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("X-API-Key", tt.key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.code {
+			t.Errorf("%s with key %s: got %d, want %d", tt.path, tt.key, w.Code, tt.code)
+		}
+	}
+}
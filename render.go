@@ -0,0 +1,143 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+)
+
+// TemplateOption configures the templates set by SetTemplates.
+type TemplateOption func(*templateSet)
+
+// WithTemplateFuncs adds functions to the templates, e.g. the URL method of
+// Assets.
+func WithTemplateFuncs(funcs template.FuncMap) TemplateOption {
+	return func(s *templateSet) {
+		for name, fn := range funcs {
+			s.funcs[name] = fn
+		}
+	}
+}
+
+// WithTemplateReload parses the templates again for every rendered page, so
+// that changes to the files show up without restarting, e.g. during
+// development. Parse errors are then reported by Render.
+func WithTemplateReload() TemplateOption {
+	return func(s *templateSet) {
+		s.reload = true
+	}
+}
+
+// templateSet holds the templates of a router.
+type templateSet struct {
+	fsys   fs.FS
+	funcs  template.FuncMap
+	reload bool
+	tmpl   *template.Template // Parsed templates, unless reloading
+}
+
+// parse parses all files of the file system with the extension .html, named
+// by their path, like "page.html" or "admin/users.html".
+func (s *templateSet) parse() (*template.Template, error) {
+	root := template.New("").Funcs(s.funcs)
+	err := fs.WalkDir(s.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(name) != ".html" {
+			return err
+		}
+		b, err := fs.ReadFile(s.fsys, name)
+		if err != nil {
+			return err
+		}
+		_, err = root.New(name).Parse(string(b))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// templates returns the parsed templates, parsing them again if reloading is
+// enabled.
+func (s *templateSet) templates() (*template.Template, error) {
+	if !s.reload {
+		return s.tmpl, nil
+	}
+	return s.parse()
+}
+
+// SetTemplates parses the files of the file system with the extension .html
+// as HTML templates, which handlers of the router can execute with Render.
+// Templates are named by their path in the file system and can include each
+// other by that name:
+//
+//	//go:embed templates
+//	var templates embed.FS
+//
+//	views, _ := fs.Sub(templates, "templates")
+//	if err := router.SetTemplates(views, httpmux.WithTemplateFuncs(template.FuncMap{
+//		"asset": assets.URL,
+//	})); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// SetTemplates returns the first parse error. The templates can be replaced
+// while the router serves requests.
+func (r *Router) SetTemplates(fsys fs.FS, opts ...TemplateOption) error {
+	s := &templateSet{fsys: fsys, funcs: make(template.FuncMap)}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	tmpl, err := s.parse()
+	if err != nil {
+		return err
+	}
+	s.tmpl = tmpl
+	r.updateSettings(func(settings *settings) {
+		settings.templates = s
+	})
+	return nil
+}
+
+// Render executes the named template of the router whose route matched the
+// request with the data and writes the result with the content type
+// text/html, unless a Content-Type header was set already:
+//
+//	router.GET("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+//		user := loadUser(r.PathValue("id"))
+//		httpmux.Render(w, r, "users/show.html", user)
+//	})
+//
+// The output is buffered, so that a failing template does not result in a
+// partial page. If the template cannot be executed, or the router has no
+// templates, the request is answered with 500 Internal Server Error and the
+// error is returned.
+func Render(w http.ResponseWriter, r *http.Request, name string, data any) error {
+	s := requestSettings(r).templates
+	if s == nil {
+		writeError(w, r, "500 internal server error", http.StatusInternalServerError)
+		return errors.New("httpmux: no templates set for router")
+	}
+
+	tmpl, err := s.templates()
+	if err == nil {
+		var buf bytes.Buffer
+		if err = tmpl.ExecuteTemplate(&buf, name, data); err == nil {
+			if w.Header().Get("Content-Type") == "" {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			}
+			_, err = buf.WriteTo(w)
+			return err
+		}
+	}
+	writeError(w, r, "500 internal server error", http.StatusInternalServerError)
+	return err
+}
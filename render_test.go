@@ -0,0 +1,100 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRouterRender(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layout.html":     {Data: []byte(`{{define "layout"}}<title>{{.Title}}</title>{{template "content" .}}{{end}}`)},
+		"users/show.html": {Data: []byte(`{{template "layout" .}}{{define "content"}}<p>{{upper .Name}}</p>{{end}}`)},
+		"broken.html":     {Data: []byte(`{{.Missing.Field}}`)},
+		"notes.txt":       {Data: []byte(`{{`)},
+	}
+
+	router := New()
+	err := router.SetTemplates(fsys, WithTemplateFuncs(template.FuncMap{"upper": strings.ToUpper}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.GET("/users/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if err := Render(w, r, "users/show.html", map[string]string{"Title": "User", "Name": r.PathValue("name")}); err != nil {
+			t.Error(err)
+		}
+	})
+	var renderErr error
+	router.GET("/broken", func(w http.ResponseWriter, r *http.Request) {
+		renderErr = Render(w, r, "broken.html", 42)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/<ann>", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "<title>User</title><p>&lt;ANN&gt;</p>" ||
+		w.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Errorf("unexpected response %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/broken", nil))
+	if w.Code != http.StatusInternalServerError || renderErr == nil {
+		t.Errorf("expected 500 and an error, got %d %v", w.Code, renderErr)
+	}
+
+	// Handlers of routers without templates cannot render
+	other := New()
+	other.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		renderErr = Render(w, r, "users/show.html", nil)
+	})
+	w = httptest.NewRecorder()
+	other.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusInternalServerError || renderErr == nil {
+		t.Errorf("expected 500 and an error, got %d %v", w.Code, renderErr)
+	}
+
+	if err := New().SetTemplates(fstest.MapFS{"bad.html": {Data: []byte(`{{`)}}); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestRouterRenderReload(t *testing.T) {
+	fsys := fstest.MapFS{"page.html": {Data: []byte(`v1`)}}
+
+	router := New()
+	if err := router.SetTemplates(fsys, WithTemplateReload()); err != nil {
+		t.Fatal(err)
+	}
+	router.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		Render(w, r, "page.html", nil)
+	})
+
+	for _, version := range []string{"v1", "v2"} {
+		fsys["page.html"] = &fstest.MapFile{Data: []byte(version)}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Body.String() != version {
+			t.Errorf("expected %q, got %q", version, w.Body.String())
+		}
+	}
+
+	// Without reloading, the templates parsed by SetTemplates are used
+	router = New()
+	router.SetTemplates(fsys)
+	router.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		Render(w, r, "page.html", nil)
+	})
+	fsys["page.html"] = &fstest.MapFile{Data: []byte("v3")}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Body.String() != "v2" {
+		t.Errorf("expected cached template, got %q", w.Body.String())
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader is the header from which RequestIDHandler reads incoming
+// request IDs and to which it writes the ID of the response.
+var RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen limits the length of request IDs accepted from clients.
+const maxRequestIDLen = 128
+
+// contextKey is used for values the package stores in a request context.
+type contextKey struct {
+	name string
+}
+
+var requestIDContextKey = &contextKey{"request-id"}
+
+// RequestIDHandler returns a handler which makes sure every request carries a
+// request ID before calling h.
+// An ID sent by the client in the RequestIDHeader is reused if it is
+// well-formed, otherwise a new random ID is generated. The ID is stored in the
+// request context, where it can be retrieved using RequestID, and echoed in the
+// response header.
+//
+//	log.Fatal(http.ListenAndServe(":8080", httpmux.RequestIDHandler(router)))
+func RequestIDHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		ctx := context.WithValue(req.Context(), requestIDContextKey, id)
+		h.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// RequestID returns the request ID assigned by RequestIDHandler.
+// If the request did not pass through RequestIDHandler, an empty string is
+// returned.
+func RequestID(req *http.Request) string {
+	id, _ := req.Context().Value(requestIDContextKey).(string)
+	return id
+}
+
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// Only printable ASCII without spaces is accepted, so that client supplied
+// IDs can safely be written to headers and logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if c := id[i]; c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
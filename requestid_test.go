@@ -0,0 +1,76 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestIDHandler(t *testing.T) {
+	var got string
+	router := New()
+	router.GET("/id", func(_ http.ResponseWriter, req *http.Request) {
+		got = RequestID(req)
+	})
+	h := RequestIDHandler(router)
+
+	// generated ID
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest(http.MethodGet, "/id", nil)
+	h.ServeHTTP(w, r)
+	if len(got) != 32 {
+		t.Fatalf("expected generated request ID, got %q", got)
+	}
+	if hdr := w.Header().Get(RequestIDHeader); hdr != got {
+		t.Errorf("response header %q does not match request ID %q", hdr, got)
+	}
+
+	// propagated ID
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest(http.MethodGet, "/id", nil)
+	r.Header.Set(RequestIDHeader, "abc-123")
+	h.ServeHTTP(w, r)
+	if got != "abc-123" {
+		t.Errorf("expected propagated request ID, got %q", got)
+	}
+
+	// malformed IDs are replaced
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest(http.MethodGet, "/id", nil)
+	r.Header.Set(RequestIDHeader, "bad id")
+	h.ServeHTTP(w, r)
+	if got == "bad id" || got == "" {
+		t.Errorf("expected malformed request ID to be replaced, got %q", got)
+	}
+
+	// without the handler
+	r, _ = http.NewRequest(http.MethodGet, "/id", nil)
+	if id := RequestID(r); id != "" {
+		t.Errorf("expected empty request ID, got %q", id)
+	}
+}
+
+func TestRequestIDErrorResponses(t *testing.T) {
+	router := New()
+	router.POST("/path", func(_ http.ResponseWriter, _ *http.Request) {})
+	h := RequestIDHandler(router)
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		path := "/path"
+		if method == http.MethodPost {
+			path = "/nope"
+		}
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest(method, path, nil)
+		r.Header.Set(RequestIDHeader, "req-42")
+		h.ServeHTTP(w, r)
+		if !strings.Contains(w.Body.String(), "req-42") {
+			t.Errorf("%s %s: expected request ID in body, got %q", method, path, w.Body.String())
+		}
+	}
+}
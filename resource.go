@@ -0,0 +1,115 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ResourceController handles the conventional actions on a collection of
+// resources registered by Router.Resource. Handlers of member actions find
+// the resource ID with req.PathValue("id").
+type ResourceController interface {
+	// GET /articles
+	Index(w http.ResponseWriter, req *http.Request)
+
+	// GET /articles/{id}
+	Show(w http.ResponseWriter, req *http.Request)
+
+	// POST /articles
+	Create(w http.ResponseWriter, req *http.Request)
+
+	// PUT and PATCH /articles/{id}
+	Update(w http.ResponseWriter, req *http.Request)
+
+	// DELETE /articles/{id}
+	Delete(w http.ResponseWriter, req *http.Request)
+}
+
+// ResourceAction is an action of a ResourceController.
+type ResourceAction string
+
+// Actions of a ResourceController.
+const (
+	ActionIndex  ResourceAction = "Index"
+	ActionShow   ResourceAction = "Show"
+	ActionCreate ResourceAction = "Create"
+	ActionUpdate ResourceAction = "Update"
+	ActionDelete ResourceAction = "Delete"
+)
+
+// ResourceOption configures the routes registered by Router.Resource.
+type ResourceOption func(*resourceConfig)
+
+type resourceConfig struct {
+	param string
+	skip  map[ResourceAction]bool
+}
+
+// SkipActions leaves the routes of the actions unregistered, e.g. for read-only
+// resources. Requests to them are answered like requests to other missing
+// routes, e.g. with 405 Method Not Allowed.
+func SkipActions(actions ...ResourceAction) ResourceOption {
+	return func(c *resourceConfig) {
+		for _, action := range actions {
+			c.skip[action] = true
+		}
+	}
+}
+
+// WithResourceParam sets the name of the path parameter holding the resource
+// ID, "id" by default. It must be distinct when resources are nested.
+func WithResourceParam(name string) ResourceOption {
+	return func(c *resourceConfig) {
+		c.param = name
+	}
+}
+
+// Resource registers the actions of the controller at the conventional
+// methods and paths below the path:
+//
+//	router.Resource("/articles", articles, httpmux.SkipActions(httpmux.ActionDelete))
+//
+//	GET    /articles       Index
+//	POST   /articles       Create
+//	GET    /articles/{id}  Show
+//	PUT    /articles/{id}  Update
+//	PATCH  /articles/{id}  Update
+//	DELETE /articles/{id}  Delete
+//
+// The path may contain parameters itself, e.g. to nest resources like
+// /authors/{author}/articles.
+func (r *Router) Resource(path string, controller ResourceController, opts ...ResourceOption) {
+	cfg := &resourceConfig{param: "id", skip: make(map[ResourceAction]bool)}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	path = strings.TrimSuffix(path, "/")
+	member := path + "/{" + cfg.param + "}"
+	if path == "" {
+		path = "/"
+	}
+
+	routes := []struct {
+		action ResourceAction
+		method string
+		path   string
+		handle http.HandlerFunc
+	}{
+		{ActionIndex, http.MethodGet, path, controller.Index},
+		{ActionCreate, http.MethodPost, path, controller.Create},
+		{ActionShow, http.MethodGet, member, controller.Show},
+		{ActionUpdate, http.MethodPut, member, controller.Update},
+		{ActionUpdate, http.MethodPatch, member, controller.Update},
+		{ActionDelete, http.MethodDelete, member, controller.Delete},
+	}
+	for _, route := range routes {
+		if !cfg.skip[route.action] {
+			r.Handle(route.method, route.path, route.handle)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+type testController struct{}
+
+func (testController) Index(w http.ResponseWriter, req *http.Request) {
+	w.Write([]byte("index " + req.PathValue("author")))
+}
+
+func (testController) Show(w http.ResponseWriter, req *http.Request) {
+	w.Write([]byte("show " + req.PathValue("id")))
+}
+
+func (testController) Create(w http.ResponseWriter, req *http.Request) {
+	w.Write([]byte("create"))
+}
+
+func (testController) Update(w http.ResponseWriter, req *http.Request) {
+	w.Write([]byte("update " + req.PathValue("id")))
+}
+
+func (testController) Delete(w http.ResponseWriter, req *http.Request) {
+	w.Write([]byte("delete " + req.PathValue("id")))
+}
+
+func TestRouterResource(t *testing.T) {
+	router := New()
+	router.Resource("/articles", testController{})
+	router.Resource("/authors/{author}/books/", testController{}, SkipActions(ActionCreate, ActionDelete))
+
+	for _, tt := range []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{http.MethodGet, "/articles", http.StatusOK, "index "},
+		{http.MethodPost, "/articles", http.StatusOK, "create"},
+		{http.MethodGet, "/articles/7", http.StatusOK, "show 7"},
+		{http.MethodPut, "/articles/7", http.StatusOK, "update 7"},
+		{http.MethodPatch, "/articles/7", http.StatusOK, "update 7"},
+		{http.MethodDelete, "/articles/7", http.StatusOK, "delete 7"},
+		{http.MethodGet, "/authors/ann/books", http.StatusOK, "index ann"},
+		{http.MethodGet, "/authors/ann/books/3", http.StatusOK, "show 3"},
+		{http.MethodPost, "/authors/ann/books", http.StatusMethodNotAllowed, ""},
+		{http.MethodDelete, "/authors/ann/books/3", http.StatusMethodNotAllowed, ""},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.code {
+			t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.path, tt.code, w.Code)
+		}
+		if tt.code == http.StatusOK && w.Body.String() != tt.body {
+			t.Errorf("%s %s: expected body %q, got %q", tt.method, tt.path, tt.body, w.Body.String())
+		}
+	}
+}
+
+func TestRouterResourceParam(t *testing.T) {
+	router := New()
+	router.Resource("/articles", testController{}, WithResourceParam("slug"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/articles/hello", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if got := w.Body.String(); got != "show " {
+		t.Errorf("expected the ID in parameter slug, got %q", got)
+	}
+	if !slices.Contains(router.getPaths(), "/articles/{slug}") {
+		t.Error("expected route /articles/{slug}")
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// encoder encodes response values in a media type.
+type encoder struct {
+	mediaType string
+	encode    func(io.Writer, any) error
+}
+
+// encoderSet holds the encoders of a router in the order they were set. It is
+// replaced, not modified, by SetEncoder.
+type encoderSet struct {
+	encoders []encoder
+}
+
+// SetEncoder makes Respond encode values in the media type with the function
+// if requests of the router accept it, e.g. XML or MessagePack:
+//
+//	router.SetEncoder("application/xml", func(w io.Writer, v any) error {
+//		return xml.NewEncoder(w).Encode(v)
+//	})
+//	router.SetEncoder("application/msgpack", func(w io.Writer, v any) error {
+//		return msgpack.NewEncoder(w).Encode(v)
+//	})
+//
+// Setting an encoder for "application/json" replaces the default JSON
+// encoder. A nil function removes the encoder of the media type.
+func (r *Router) SetEncoder(mediaType string, encode func(io.Writer, any) error) {
+	r.updateSettings(func(s *settings) {
+		var encoders []encoder
+		if s.encoders != nil {
+			encoders = slices.Clone(s.encoders.encoders)
+		}
+		encoders = slices.DeleteFunc(encoders, func(e encoder) bool {
+			return e.mediaType == mediaType
+		})
+		if encode != nil {
+			encoders = append(encoders, encoder{mediaType, encode})
+		}
+
+		s.encoders = nil
+		if len(encoders) > 0 {
+			s.encoders = &encoderSet{encoders}
+		}
+	})
+}
+
+func encodeJSON(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// negotiate returns the encoder of the media type most preferred by the
+// Accept header among the available ones, JSON if none is acceptable.
+func (s *encoderSet) negotiate(accept string) encoder {
+	available := []encoder{{"application/json", encodeJSON}}
+	if s != nil {
+		for _, e := range s.encoders {
+			if e.mediaType == "application/json" {
+				available[0] = e
+			} else {
+				available = append(available, e)
+			}
+		}
+	}
+	if accept == "" || len(available) == 1 {
+		return available[0]
+	}
+
+	best, bestQ := available[0], 0.0
+	for _, e := range available {
+		if q := acceptQuality(accept, e.mediaType); q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+	return best
+}
+
+// acceptQuality returns the quality the Accept header assigns to the media
+// type, 0 if it is not acceptable. The most specific matching range counts.
+func acceptQuality(accept, mediaType string) float64 {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	q, specificity := 0.0, -1
+	for _, r := range strings.Split(accept, ",") {
+		rangeType, params, err := mime.ParseMediaType(strings.TrimSpace(r))
+		if err != nil {
+			continue
+		}
+
+		var s int
+		switch {
+		case rangeType == mediaType:
+			s = 2
+		case rangeType == typ+"/*":
+			s = 1
+		case rangeType == "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s <= specificity {
+			continue
+		}
+		specificity, q = s, 1
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+	}
+	return q
+}
+
+// Respond writes the value with the status code, encoded in the media type
+// the request accepts:
+//
+//	router.GET("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+//		user, err := loadUser(r.PathValue("id"))
+//		if err != nil {
+//			httpmux.RespondError(w, r, err)
+//			return
+//		}
+//		httpmux.Respond(w, r, http.StatusOK, user)
+//	})
+//
+// Values are encoded as JSON, unless the Accept header prefers a media type
+// with an encoder set by SetEncoder; requests accepting none of them get JSON
+// as well. The response is buffered to set Content-Type and Content-Length,
+// and Vary: Accept if the router has encoders. Responses with status 204 No
+// Content or 304 Not Modified have no body, and a nil value writes only the
+// status.
+//
+// If the value cannot be encoded, the request is answered with 500 Internal
+// Server Error instead and the error is returned.
+func Respond(w http.ResponseWriter, r *http.Request, status int, v any) error {
+	s := requestSettings(r).encoders
+	if s != nil {
+		w.Header().Add("Vary", "Accept")
+	}
+	if v == nil || status == http.StatusNoContent || status == http.StatusNotModified {
+		w.WriteHeader(status)
+		return nil
+	}
+
+	e := s.negotiate(r.Header.Get("Accept"))
+	var buf bytes.Buffer
+	if err := e.encode(&buf, v); err != nil {
+		writeError(w, r, "500 internal server error", http.StatusInternalServerError)
+		return err
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", e.mediaType)
+	h.Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
+	_, err := buf.WriteTo(w)
+	return err
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type respondUser struct {
+	XMLName xml.Name `json:"-" xml:"user"`
+	Name    string   `json:"name" xml:"name"`
+}
+
+func TestRespond(t *testing.T) {
+	router := New()
+	router.SetEncoder("application/xml", func(w io.Writer, v any) error {
+		return xml.NewEncoder(w).Encode(v)
+	})
+	router.SetEncoder("text/plain", func(w io.Writer, v any) error {
+		_, err := fmt.Fprint(w, v.(respondUser).Name)
+		return err
+	})
+	router.GET("/user", func(w http.ResponseWriter, req *http.Request) {
+		Respond(w, req, http.StatusOK, respondUser{Name: "ann"})
+	})
+
+	for _, tt := range []struct {
+		accept, contentType, body string
+	}{
+		{"", "application/json", `{"name":"ann"}` + "\n"},
+		{"application/json", "application/json", `{"name":"ann"}` + "\n"},
+		{"application/xml", "application/xml", `<user><name>ann</name></user>`},
+		{"text/html, application/xml;q=0.9, */*;q=0.1", "application/xml", `<user><name>ann</name></user>`},
+		{"application/xml;q=0.5, text/*", "text/plain", `ann`},
+		{"application/*;q=0.8, application/xml;q=0", "application/json", `{"name":"ann"}` + "\n"},
+		{"image/png", "application/json", `{"name":"ann"}` + "\n"},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/user", nil)
+		req.Header.Set("Accept", tt.accept)
+		router.ServeHTTP(w, req)
+
+		if ct := w.Header().Get("Content-Type"); ct != tt.contentType {
+			t.Errorf("Accept %q: expected content type %q, got %q", tt.accept, tt.contentType, ct)
+		}
+		if w.Body.String() != tt.body {
+			t.Errorf("Accept %q: expected body %q, got %q", tt.accept, tt.body, w.Body.String())
+		}
+		if cl := w.Header().Get("Content-Length"); cl != fmt.Sprint(len(tt.body)) {
+			t.Errorf("Accept %q: unexpected content length %q", tt.accept, cl)
+		}
+		if vary := w.Header().Get("Vary"); vary != "Accept" {
+			t.Errorf("Accept %q: expected Vary: Accept, got %q", tt.accept, vary)
+		}
+	}
+}
+
+func TestRespondWithoutEncoders(t *testing.T) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Accept", "application/xml")
+	Respond(w, req, http.StatusCreated, map[string]int{"id": 1})
+	if w.Code != http.StatusCreated || w.Body.String() != `{"id":1}`+"\n" {
+		t.Errorf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if vary := w.Header().Get("Vary"); vary != "" {
+		t.Errorf("unexpected Vary %q", vary)
+	}
+
+	w = httptest.NewRecorder()
+	Respond(w, req, http.StatusNoContent, map[string]int{"id": 1})
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	err := Respond(w, req, http.StatusOK, func() {})
+	if err == nil || w.Code != http.StatusInternalServerError {
+		t.Errorf("expected an encoding error and status 500, got %v and %d", err, w.Code)
+	}
+}
+
+func TestSetEncoderReplace(t *testing.T) {
+	router := New()
+	router.SetEncoder("application/json", func(w io.Writer, v any) error {
+		return errors.New("custom")
+	})
+	router.GET("/", func(w http.ResponseWriter, req *http.Request) {
+		if err := Respond(w, req, http.StatusOK, 1); err == nil || err.Error() != "custom" {
+			t.Errorf("expected the custom JSON encoder, got %v", err)
+		}
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	router.SetEncoder("application/json", nil)
+	if router.settings.Load().encoders != nil {
+		t.Error("expected no encoders after removing the last one")
+	}
+	if q := acceptQuality("text/*;q=0.3", "text/plain"); q != 0.3 {
+		t.Errorf("expected quality 0.3 for a matching range, got %v", q)
+	}
+}
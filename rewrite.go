@@ -0,0 +1,147 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// rewriteRule rewrites paths matching a pattern to a template.
+type rewriteRule struct {
+	from []string // Segments of the pattern, without the leading slash
+	to   string
+}
+
+// newRewriteRule parses a rewrite rule and panics if it is invalid.
+// Parameters must span whole path segments, a catch-all parameter must be the
+// last segment and the template may only use parameters of the pattern, with
+// braces only around parameters.
+func newRewriteRule(from, to string) rewriteRule {
+	if !strings.HasPrefix(from, "/") || !strings.HasPrefix(to, "/") {
+		panic("rewrite pattern and template must begin with '/' in rule '" + from + "' -> '" + to + "'")
+	}
+
+	rule := rewriteRule{from: strings.Split(from[1:], "/"), to: to}
+	var names []string
+	for i, seg := range rule.from {
+		name, isParam, catchAll := rewriteParam(seg)
+		if !isParam {
+			if strings.ContainsAny(seg, "{}") {
+				panic("parameters in rewrite patterns must span whole path segments in pattern '" + from + "'")
+			}
+			continue
+		}
+		if name == "" || catchAll && i != len(rule.from)-1 {
+			panic("invalid parameter '" + seg + "' in rewrite pattern '" + from + "'")
+		}
+		names = append(names, name)
+	}
+
+	for rest := to; ; {
+		i := strings.IndexByte(rest, '{')
+		literal := rest
+		if i >= 0 {
+			literal = rest[:i]
+		}
+		if strings.IndexByte(literal, '}') >= 0 {
+			panic("unmatched '}' in rewrite template '" + to + "'")
+		}
+		if i < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[i:], '}') + i
+		if end < i {
+			panic("unterminated parameter in rewrite template '" + to + "'")
+		}
+		if name, _, _ := rewriteParam(rest[i : end+1]); !slices.Contains(names, name) {
+			panic("rewrite template '" + to + "' uses parameter '" + name + "' missing in pattern '" + from + "'")
+		}
+		rest = rest[end+1:]
+	}
+	return rule
+}
+
+// rewriteParam returns the name of the parameter in a segment like "{id}" or
+// "{path...}".
+func rewriteParam(seg string) (name string, isParam, catchAll bool) {
+	if len(seg) < 2 || seg[0] != '{' || seg[len(seg)-1] != '}' {
+		return "", false, false
+	}
+	name = seg[1 : len(seg)-1]
+	name, catchAll = strings.CutSuffix(name, "...")
+	return name, true, catchAll
+}
+
+// rewrite returns the rewritten path if the path matches the pattern.
+// Catch-all parameters capture the rest of the path without its leading
+// slash, so "/v1/{path...}" -> "/{path...}" strips the first segment.
+func (rule *rewriteRule) rewrite(path string) (string, bool) {
+	if path == "" || path[0] != '/' {
+		return "", false
+	}
+
+	var values []string
+	rest := path[1:]
+	for i, seg := range rule.from {
+		name, isParam, catchAll := rewriteParam(seg)
+		if catchAll {
+			values = append(values, name, rest)
+			rest = ""
+			break
+		}
+
+		value, next, more := strings.Cut(rest, "/")
+		if isParam && value != "" {
+			values = append(values, name, value)
+		} else if isParam || value != seg {
+			return "", false
+		}
+
+		// The path must have as many segments as the pattern
+		if more != (i < len(rule.from)-1) {
+			return "", false
+		}
+		rest = next
+	}
+
+	var sb strings.Builder
+	for tmpl := rule.to; ; {
+		i := strings.IndexByte(tmpl, '{')
+		if i < 0 {
+			sb.WriteString(tmpl)
+			break
+		}
+		end := strings.IndexByte(tmpl[i:], '}') + i
+		sb.WriteString(tmpl[:i])
+		name, _, _ := rewriteParam(tmpl[i : end+1])
+		for j := 0; j < len(values); j += 2 {
+			if values[j] == name {
+				sb.WriteString(values[j+1])
+				break
+			}
+		}
+		tmpl = tmpl[end+1:]
+	}
+	return sb.String(), true
+}
+
+// rewriteHandler rewrites the request path with the first matching rule
+// before calling next.
+func rewriteHandler(rules []rewriteRule, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := range rules {
+			if path, ok := rules[i].rewrite(r.URL.Path); ok {
+				originalPath := r.URL.Path
+				r.URL.Path = path
+				next.ServeHTTP(w, r)
+				r.URL.Path = originalPath
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import "net/http"
+
+// RouteOption overrides a behavior of the router for a single route:
+//
+//	router.GET("/files/", listFiles, httpmux.StrictSlash())
+//	router.POST("/hooks/{id}", receiveHook, httpmux.NoAutoOptions())
+type RouteOption func(*routeOptions)
+
+type routeOptions struct {
+	noAutoOptions bool
+	strictSlash   bool
+	tags          []string // sorted
+	name          string
+}
+
+// NoAutoOptions disables the automatic replies to OPTIONS requests of
+// HandleOPTIONS for the path of the route. Such requests are answered like
+// other requests for methods the path has no route for, with 405 Method Not
+// Allowed if HandleMethodNotAllowed is set. Routes registered for the OPTIONS
+// method are not affected.
+func NoAutoOptions() RouteOption {
+	return func(o *routeOptions) {
+		o.noAutoOptions = true
+	}
+}
+
+// StrictSlash disables the redirects of RedirectTrailingSlash and
+// RedirectFixedPath to the route which only add or remove a trailing slash,
+// so the route only matches its path exactly. Note that unlike the option of
+// the same name of gorilla/mux, it disables the redirects.
+func StrictSlash() RouteOption {
+	return func(o *routeOptions) {
+		o.strictSlash = true
+	}
+}
+
+// Name names the route, e.g. "user.show", for the functions generated by
+// GenerateURLs. Names are reported by Routes and MultiRouter.AllRoutes.
+func Name(name string) RouteOption {
+	return func(o *routeOptions) {
+		o.name = name
+	}
+}
+
+// routeHandler is stored in the tree instead of the handle of routes with
+// options.
+type routeHandler struct {
+	http.Handler
+	opts routeOptions
+}
+
+func newRouteHandler(handle http.Handler, opts []RouteOption) http.Handler {
+	rh := &routeHandler{Handler: handle}
+	for _, opt := range opts {
+		opt(&rh.opts)
+	}
+	return rh
+}
+
+// routeOptionsOf returns the options of the route with the handle.
+func routeOptionsOf(handle http.Handler) routeOptions {
+	if rh, ok := handle.(*routeHandler); ok {
+		return rh.opts
+	}
+	return routeOptions{}
+}
+
+// strictSlash reports whether the route of the root matching path has the
+// StrictSlash option.
+func strictSlash(root *node, path string) bool {
+	handle, _ := root.getValue(path, nil, nil)
+	return routeOptionsOf(handle).strictSlash
+}
+
+// autoOptions reports whether OPTIONS requests for the path may be answered
+// automatically, i.e. no route matching it has the NoAutoOptions option.
+func (t *methodTrees) autoOptions(path string) bool {
+	if t == nil || !t.routeOptions || path == "*" {
+		return true
+	}
+	for method, root := range t.all() {
+		if method == http.MethodOptions {
+			continue
+		}
+		if handle, _ := root.getValue(path, nil, nil); routeOptionsOf(handle).noAutoOptions {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteOptions(t *testing.T) {
+	ok := func(w http.ResponseWriter, _ *http.Request) {}
+
+	router := New()
+	router.RedirectFixedPath = true
+	router.GET("/strict", ok, StrictSlash())
+	router.GET("/strict/dir/", ok, StrictSlash())
+	router.GET("/loose", ok)
+	router.POST("/hooks/{id}", ok, NoAutoOptions())
+	router.PUT("/hooks/{id}", ok)
+	router.POST("/items", ok)
+
+	tests := []struct {
+		method, path string
+		code         int
+		location     string
+	}{
+		{http.MethodGet, "/strict", http.StatusOK, ""},
+		{http.MethodGet, "/strict/", http.StatusNotFound, ""},
+		{http.MethodGet, "/strict/dir", http.StatusNotFound, ""},
+		{http.MethodGet, "/STRICT", http.StatusMovedPermanently, "/strict"},
+		{http.MethodGet, "/STRICT/", http.StatusNotFound, ""},
+		{http.MethodGet, "/loose/", http.StatusMovedPermanently, "/loose"},
+		{http.MethodOptions, "/hooks/1", http.StatusMethodNotAllowed, ""},
+		{http.MethodOptions, "/items", http.StatusOK, ""},
+		{http.MethodOptions, "*", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.code || w.Header().Get("Location") != tt.location {
+			t.Errorf("%s %s: got %d %q, want %d %q", tt.method, tt.path,
+				w.Code, w.Header().Get("Location"), tt.code, tt.location)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hooks/1", nil))
+	if allow := w.Header().Get("Allow"); allow != "POST, PUT" {
+		t.Errorf("Allow = %q, want %q", allow, "POST, PUT")
+	}
+}
+
+func TestRouteOptionsFlatten(t *testing.T) {
+	multi := NewMultiRouter()
+	api := multi.NewGroup("/api", WithMiddleware(func(next http.Handler) http.Handler {
+		return next
+	}))
+	api.GET("/users", func(w http.ResponseWriter, _ *http.Request) {}, StrictSlash())
+
+	flat, err := multi.Flatten()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	flat.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("got %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
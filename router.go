@@ -78,9 +78,15 @@
 package httpmux
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // MatchedRoutePathParam is the Param name under which the path of the matched
@@ -97,10 +103,14 @@ func MatchedRoutePath(req *http.Request) string {
 // Router is a http.Handler which can be used to dispatch requests to different
 // handler functions via configurable routes
 type Router struct {
-	trees map[string]*node
+	// Snapshot of the route trees, replaced on every registration
+	trees atomic.Pointer[methodTrees]
 
-	// paramsPool sync.Pool
-	// maxParams  uint16
+	// Serializes registrations
+	mu sync.Mutex
+
+	// Buffers for path parameters exceeding the lookup's stack buffer
+	paramsPool sync.Pool
 
 	// If enabled, adds the matched route path onto the http.Request context
 	// before invoking the handler.
@@ -144,9 +154,6 @@ type Router struct {
 	// The "Allowed" header is set before calling the handler.
 	GlobalOPTIONS http.Handler
 
-	// Cached value of global (*) allowed methods
-	globalAllowed string
-
 	// Configurable http.Handler which is called when no matching route is
 	// found. If it is not set, http.NotFound is used.
 	NotFound http.Handler
@@ -164,6 +171,130 @@ type Router struct {
 	// The handler can be used to keep your server from crashing because of
 	// unrecovered panics.
 	PanicHandler func(http.ResponseWriter, *http.Request, interface{})
+
+	// If enabled, every request is logged to the logger set by SetLogger
+	// after it was served.
+	LogRequests bool
+
+	// If enabled, handlers run with a pprof label (see ProfileLabelKey) set to
+	// the registered path of the route, so that CPU and goroutine profiles can
+	// be broken down by route. Labeling costs a few allocations per request.
+	// The label is only added to handlers of routes that were registered when
+	// this option was enabled.
+	ProfileLabels bool
+
+	// If set, requests to registered routes taking at least this long are
+	// reported to SlowRequest.
+	SlowRequestThreshold time.Duration
+
+	// Function called for requests exceeding the SlowRequestThreshold, with
+	// the time spent matching the route and in the handler.
+	// If it is not set, slow requests are logged as warnings to the logger set
+	// by SetLogger.
+	SlowRequest func(*http.Request, RequestTiming)
+
+	// Logger set by SetLogger, nil if logging is disabled.
+	logger *slog.Logger
+
+	// Middlewares added by Use, outermost first
+	middlewares []func(http.Handler) http.Handler
+
+	// Per-route request counters, nil if statistics are disabled
+	stats *statsRegistry
+
+	// Per-route latency histograms, nil if latency recording is disabled
+	latency *latencyRegistry
+
+	// Handler set by Fallback, nil if none is set
+	fallback http.Handler
+
+	// Settings applied while serving, nil if none are set
+	settings atomic.Pointer[settings]
+
+	// Metadata of the routes loaded by LoadRoutes, keyed by method and path
+	metadata atomic.Pointer[map[string]map[string]string]
+
+	// Documentation of the routes set by Describe, keyed by method and path
+	operations map[string]OperationDoc
+}
+
+// settings holds the settings of a router which are applied to every request.
+// They are replaced as a whole, never modified, so that they can be changed
+// while the router serves requests.
+type settings struct {
+	// Templates set by SetTemplates, nil if none are set
+	templates *templateSet
+
+	// Validator set by SetValidator, nil if none is set
+	validator *validator
+
+	// Encoders set by SetEncoder, nil if none are set
+	encoders *encoderSet
+
+	// Authenticator set by SetAuthenticator, nil if none is set
+	authenticator *authenticator
+
+	// Breaker set by SetBreaker, nil if none is set
+	breaker Breaker
+
+	// Logger set by SetLogger, for errors answered by RespondError
+	logger *slog.Logger
+}
+
+// settingsContextKey holds the settings of the router which matched the
+// request.
+var settingsContextKey = &contextKey{"settings"}
+
+// scoped reports whether handlers need the settings, i.e. whether they must be
+// stored in the context of matched requests.
+func (s *settings) scoped() bool {
+	return s.templates != nil || s.validator != nil || s.encoders != nil || s.logger != nil
+}
+
+// withScope returns the request with the settings, if handlers need them, and
+// the group, if not nil, in its context.
+func withScope(req *http.Request, s *settings, g *group) *http.Request {
+	ctx := req.Context()
+	if s.scoped() {
+		ctx = context.WithValue(ctx, settingsContextKey, s)
+	}
+	if g != nil {
+		ctx = context.WithValue(ctx, matchedGroupContextKey, g)
+	}
+	return req.WithContext(ctx)
+}
+
+// requestSettings returns the settings of the router which matched the
+// request.
+func requestSettings(req *http.Request) *settings {
+	if s, ok := req.Context().Value(settingsContextKey).(*settings); ok {
+		return s
+	}
+	return &noSettings
+}
+
+// noSettings are the settings of routers for which none were set.
+var noSettings settings
+
+// loadSettings returns the current settings of the router.
+func (r *Router) loadSettings() *settings {
+	if s := r.settings.Load(); s != nil {
+		return s
+	}
+	return &noSettings
+}
+
+// updateSettings publishes a copy of the router's settings modified by
+// update. Concurrent requests keep the settings they started with.
+func (r *Router) updateSettings(update func(s *settings)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := new(settings)
+	if old := r.settings.Load(); old != nil {
+		*s = *old
+	}
+	update(s)
+	r.settings.Store(s)
 }
 
 // Make sure the Router conforms with the http.Handler interface
@@ -171,67 +302,70 @@ var _ http.Handler = New()
 
 // New returns a new initialized Router.
 // Path auto-correction, including trailing slashes, is enabled by default.
-func New() *Router {
-	return &Router{
+// Options change the defaults at construction:
+//
+//	router := httpmux.New(
+//		httpmux.WithRedirectTrailingSlash(false),
+//		httpmux.WithNotFound(notFoundPage),
+//	)
+func New(opts ...Option) *Router {
+	r := &Router{
 		RedirectTrailingSlash:  true,
 		RedirectFixedPath:      true,
 		HandleMethodNotAllowed: true,
 		HandleOPTIONS:          true,
-		trees:                  make(map[string]*node),
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 // just an alias for New() aligning with stdlib http.ServeMux
-func NewServeMux() *Router {
-	return &Router{
-		RedirectTrailingSlash:  true,
-		RedirectFixedPath:      true,
-		HandleMethodNotAllowed: true,
-		HandleOPTIONS:          true,
-		trees:                  make(map[string]*node),
-	}
+func NewServeMux(opts ...Option) *Router {
+	return New(opts...)
 }
 
-func (r *Router) saveMatchedRoutePath(path string, handle http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, req *http.Request) {
+func (r *Router) saveMatchedRoutePath(path string, handle http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		req.SetPathValue(MatchedRoutePathParam, path)
-		handle(w, req)
-	}
+		handle.ServeHTTP(w, req)
+	})
 }
 
 // GET is a shortcut for router.HandleFunc("GET", path, handler)
-func (r *Router) GET(path string, handle http.HandlerFunc) {
-	r.handle(http.MethodGet, path, handle)
+func (r *Router) GET(path string, handle http.HandlerFunc, opts ...RouteOption) {
+	r.handle(http.MethodGet, path, handle, opts...)
 }
 
 // HEAD is a shortcut for router.HandleFunc("HEAD", path, handler)
-func (r *Router) HEAD(path string, handle http.HandlerFunc) {
-	r.handle(http.MethodHead, path, handle)
+func (r *Router) HEAD(path string, handle http.HandlerFunc, opts ...RouteOption) {
+	r.handle(http.MethodHead, path, handle, opts...)
 }
 
 // OPTIONS is a shortcut for router.Handle(http.MethodOptions, path, handle)
-func (r *Router) OPTIONS(path string, handle http.HandlerFunc) {
-	r.handle(http.MethodOptions, path, handle)
+func (r *Router) OPTIONS(path string, handle http.HandlerFunc, opts ...RouteOption) {
+	r.handle(http.MethodOptions, path, handle, opts...)
 }
 
 // POST is a shortcut for router.Handle(http.MethodPost, path, handle)
-func (r *Router) POST(path string, handle http.HandlerFunc) {
-	r.handle(http.MethodPost, path, handle)
+func (r *Router) POST(path string, handle http.HandlerFunc, opts ...RouteOption) {
+	r.handle(http.MethodPost, path, handle, opts...)
 }
 
 // PUT is a shortcut for router.Handle(http.MethodPut, path, handle)
-func (r *Router) PUT(path string, handle http.HandlerFunc) {
-	r.handle(http.MethodPut, path, handle)
+func (r *Router) PUT(path string, handle http.HandlerFunc, opts ...RouteOption) {
+	r.handle(http.MethodPut, path, handle, opts...)
 }
 
 // PATCH is a shortcut for router.Handle(http.MethodPatch, path, handle)
-func (r *Router) PATCH(path string, handle http.HandlerFunc) {
-	r.handle(http.MethodPatch, path, handle)
+func (r *Router) PATCH(path string, handle http.HandlerFunc, opts ...RouteOption) {
+	r.handle(http.MethodPatch, path, handle, opts...)
 }
 
 // DELETE is a shortcut for router.Handle(http.MethodDelete, path, handle)
-func (r *Router) DELETE(path string, handle http.HandlerFunc) {
-	r.handle(http.MethodDelete, path, handle)
+func (r *Router) DELETE(path string, handle http.HandlerFunc, opts ...RouteOption) {
+	r.handle(http.MethodDelete, path, handle, opts...)
 }
 
 // Handle registers a new request handle with the given path and method.
@@ -244,7 +378,7 @@ func (r *Router) DELETE(path string, handle http.HandlerFunc) {
 // communication with a proxy).
 
 // Made internal because the public functions are covered by HandleFunc
-func (r *Router) handle(method, path string, handle http.HandlerFunc) {
+func (r *Router) handle(method, path string, handle http.Handler, opts ...RouteOption) {
 	varsCount := uint16(0)
 
 	if method == "" {
@@ -253,42 +387,95 @@ func (r *Router) handle(method, path string, handle http.HandlerFunc) {
 	if len(path) < 1 || path[0] != '/' {
 		panic("path must begin with '/' in path '" + path + "'")
 	}
-	if handle == nil {
+	if f, ok := handle.(http.HandlerFunc); handle == nil || ok && f == nil {
 		panic("handle must not be nil")
 	}
 
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Wrappers below keep the pattern for the lifetime of the route
+	path = intern(path)
+
+	r.warnRegistration(method, path)
+
+	if len(r.middlewares) > 0 {
+		handle = r.applyMiddlewares(handle)
+	}
+
+	if r.stats != nil {
+		handle = r.stats.count(method, path, handle)
+	}
+
+	if r.latency != nil {
+		handle = r.latency.measure(method, path, handle)
+	}
+
+	if r.ProfileLabels {
+		handle = profileLabels(path, handle)
+	}
+
 	if r.SaveMatchedRoutePath {
 		varsCount++
 		handle = r.saveMatchedRoutePath(path, handle)
 	}
 
-	if r.trees == nil {
-		r.trees = make(map[string]*node)
+	if len(opts) > 0 {
+		handle = newRouteHandler(handle, opts)
 	}
 
-	root := r.trees[method]
-	if root == nil {
-		root = new(node)
-		r.trees[method] = root
+	// Copy-on-write: the new route is added to a copy of the tree, which is
+	// published afterwards, so requests are served without locking.
+	trees := r.trees.Load().clone()
+	trees.compacted = false
 
-		r.globalAllowed = r.allowed("*", "")
+	var root node
+	if old := trees.get(method); old != nil {
+		root = *old
 	}
-
 	root.addRoute(path, handle)
+	trees.maxParams = max(trees.maxParams, countParams(path))
+	if _, ok := handle.(*routeHandler); ok {
+		trees.routeOptions = true
+	}
+
+	isNew := trees.get(method) == nil
+	trees.set(method, &root)
+	if isNew {
+		trees.globalAllowed = r.allowedIn(trees, "*", "")
+	}
+
+	r.trees.Store(trees)
 }
 
-// Handle is an adapter which allows the usage of an http.Handler as a
-// request handle.
+// Handle registers an http.Handler as a request handle. The handler is stored
+// as is, so handlers with state need no wrapper.
 // Renamed to Handle to align with stdlib http.ServeMux
-func (r *Router) Handle(method, path string, handler http.Handler) {
-	r.handle(method, path, handler.ServeHTTP)
+func (r *Router) Handle(method, path string, handler http.Handler, opts ...RouteOption) {
+	r.handle(method, path, handler, opts...)
 }
 
 // HandleFunc	 is an adapter which allows the usage of an http.HandlerFunc as a
 // request handle.
 // Renamed to HandleFunc to align with stdlib http.ServeMux
-func (r *Router) HandleFunc(method, path string, handler http.HandlerFunc) {
-	r.handle(method, path, handler)
+func (r *Router) HandleFunc(method, path string, handler http.HandlerFunc, opts ...RouteOption) {
+	r.handle(method, path, handler, opts...)
+}
+
+// Use appends middlewares to the router's middleware stack.
+// The middlewares wrap the handlers of all routes registered after the call,
+// the first middleware being the outermost one. They are invoked after the
+// route was matched, so the path values and the request's Pattern are already
+// set:
+//
+//	router.Use(authenticate, compress)
+//	router.GET("/users/{id}", showUser)
+func (r *Router) Use(middlewares ...func(http.Handler) http.Handler) {
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+func (r *Router) applyMiddlewares(handle http.Handler) http.Handler {
+	return Middlewares(r.middlewares).Handler(handle)
 }
 
 // ServeFiles serves files from the given file system root.
@@ -302,12 +489,14 @@ func (r *Router) HandleFunc(method, path string, handler http.HandlerFunc) {
 // use http.Dir:
 //
 //	router.ServeFiles("/src/{filepath...}", http.Dir("/var/www"))
-func (r *Router) ServeFiles(path string, root http.FileSystem) {
+//
+// Options, e.g. NoDirectoryListing, configure how files are served.
+func (r *Router) ServeFiles(path string, root http.FileSystem, opts ...StaticOption) {
 	if len(path) < 14 || path[len(path)-14:] != "/{filepath...}" {
 		panic("path must end with /{filepath...} in path '" + path + "'")
 	}
 
-	fileServer := http.FileServer(root)
+	fileServer := newFileServer(root, opts)
 
 	r.GET(path, func(w http.ResponseWriter, req *http.Request) {
 		req.URL.Path = req.PathValue("filepath")
@@ -315,8 +504,28 @@ func (r *Router) ServeFiles(path string, root http.FileSystem) {
 	})
 }
 
+// getParams returns a buffer for the path parameters of routes with more than
+// maxStackParams parameters.
+func (r *Router) getParams(maxParams uint16) *[]string {
+	// Key and value of each parameter
+	size := 2 * int(maxParams-maxStackParams)
+	ps, _ := r.paramsPool.Get().(*[]string)
+	if ps == nil || cap(*ps) < size {
+		s := make([]string, 0, size)
+		ps = &s
+	}
+	return ps
+}
+
+func (r *Router) putParams(ps *[]string) {
+	// Do not keep request paths alive
+	clear((*ps)[:cap(*ps)])
+	r.paramsPool.Put(ps)
+}
+
 func (r *Router) recv(w http.ResponseWriter, req *http.Request) {
 	if rcv := recover(); rcv != nil {
+		r.logPanic(req, rcv)
 		r.PanicHandler(w, req, rcv)
 	}
 }
@@ -327,144 +536,324 @@ func (r *Router) recv(w http.ResponseWriter, req *http.Request) {
 // Otherwise the second return value indicates whether a redirection to
 // the same path with an extra / without the trailing slash should be performed.
 func (r *Router) Lookup(method, path string) (http.HandlerFunc, bool) {
-	if root := r.trees[method]; root != nil {
-		handle, tsr := root.getValue(path, nil)
+	if root := r.trees.Load().get(method); root != nil {
+		handle, tsr := root.getValue(path, nil, nil)
 		if handle == nil {
 			return nil, tsr
 		}
-		return handle, tsr
+		if f, ok := handle.(http.HandlerFunc); ok {
+			return f, tsr
+		}
+		return handle.ServeHTTP, tsr
 	}
 	return nil, false
 }
 
-func (r *Router) allowed(path, reqMethod string) (allow string) {
-	allowed := make([]string, 0, 9)
+func (r *Router) allowed(path, reqMethod string) string {
+	return r.allowedIn(r.trees.Load(), path, reqMethod)
+}
+
+// allowedIn is like allowed, but uses the given snapshot of the trees.
+func (r *Router) allowedIn(trees *methodTrees, path, reqMethod string) (allow string) {
+	// Standard methods are collected in a bitmask, which maps to a
+	// precomputed header value. Only non-standard methods require building
+	// the list, since 405 and OPTIONS responses should not allocate.
+	var set methodSet
+	var custom []string
 
 	if path == "*" { // server-wide
 		// empty method is used for internal calls to refresh the cache
-		if reqMethod == "" {
-			for method := range r.trees {
-				if method == http.MethodOptions {
-					continue
-				}
-				// Add request method to list of allowed methods
-				allowed = append(allowed, method)
+		if reqMethod != "" {
+			if trees == nil {
+				return ""
+			}
+			return trees.globalAllowed
+		}
+		for method := range trees.all() {
+			if method == http.MethodOptions {
+				continue
+			}
+			if method == methodWebSocket {
+				method = http.MethodGet
+			}
+			// Add request method to list of allowed methods
+			if i := methodIndex(method); i >= 0 {
+				set |= 1 << i
+			} else {
+				custom = append(custom, method)
 			}
-		} else {
-			return r.globalAllowed
 		}
 	} else { // specific path
-		for method := range r.trees {
-			// Skip the requested method - we already tried this one
-			if method == reqMethod || method == http.MethodOptions {
+		for method, root := range trees.all() {
+			if method == methodWebSocket {
+				// GET requests are only routed to it if they are upgrades
+				if reqMethod == http.MethodGet {
+					continue
+				}
+				method = http.MethodGet
+			} else if method == reqMethod || method == http.MethodOptions {
+				// Skip the requested method - we already tried this one
 				continue
 			}
 
-			handle, _ := r.trees[method].getValue(path, nil)
+			handle, _ := root.getValue(path, nil, nil)
 			if handle != nil {
 				// Add request method to list of allowed methods
-				allowed = append(allowed, method)
+				if i := methodIndex(method); i >= 0 {
+					set |= 1 << i
+				} else {
+					custom = append(custom, method)
+				}
 			}
 		}
 	}
 
-	if len(allowed) > 0 {
-		// Add request method to list of allowed methods
-		if r.HandleOPTIONS {
-			allowed = append(allowed, http.MethodOptions)
-		}
+	if set == 0 && len(custom) == 0 {
+		return allow
+	}
 
-		// Sort allowed methods.
-		// sort.Strings(allowed) unfortunately causes unnecessary allocations
-		// due to allowed being moved to the heap and interface conversion
-		for i, l := 1, len(allowed); i < l; i++ {
-			for j := i; j > 0 && allowed[j] < allowed[j-1]; j-- {
-				allowed[j], allowed[j-1] = allowed[j-1], allowed[j]
-			}
-		}
+	// Add request method to list of allowed methods
+	if r.HandleOPTIONS && trees.autoOptions(path) {
+		set |= 1 << methodOptions
+	}
+
+	if len(custom) == 0 {
+		return allowValues[set]
+	}
 
-		// return as comma separated list
-		return strings.Join(allowed, ", ")
+	allowed := custom
+	for i, method := range standardMethods {
+		if set&(1<<i) != 0 {
+			allowed = append(allowed, method)
+		}
 	}
+	sort.Strings(allowed)
 
-	return allow
+	// return as comma separated list
+	return strings.Join(allowed, ", ")
 }
 
 // ServeHTTP makes the router implement the http.Handler interface.
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.serve(w, req, nil)
+}
+
+// serve serves the request. g is the MultiRouter group the request was
+// dispatched to without adding it to the context, see
+// MultiRouter.groupRequest, or nil.
+func (r *Router) serve(w http.ResponseWriter, req *http.Request, g *group) {
+	trees := r.trees.Load()
+
+	path := req.URL.Path
+
+	settings := r.loadSettings()
+
+	if r.LogRequests && r.logger != nil {
+		ww := WrapWriter(w)
+		w = ww
+		defer r.logRequest(ww, req, path, time.Now())
+	}
+
 	if r.PanicHandler != nil {
 		defer r.recv(w, req)
 	}
 
-	path := req.URL.Path
+	root, wsRoot := roots(trees, req)
+	if root != nil || wsRoot != nil {
+		var start, matched time.Time
+		if r.SlowRequestThreshold > 0 {
+			start = time.Now()
+		}
+
+		var params *[]string
+		if trees.maxParams > maxStackParams {
+			params = r.getParams(trees.maxParams)
+		}
+		handle, tsr := lookupRoots(root, wsRoot, path, req, params, nil)
+		if params != nil {
+			r.putParams(params)
+		}
+		if r.SlowRequestThreshold > 0 {
+			matched = time.Now()
+		}
 
-	if root := r.trees[req.Method]; root != nil {
-		if handle, tsr := root.getValue(path, req); handle != nil {
-			handle(w, req)
+		if handle != nil {
+			if settings.scoped() || g != nil {
+				req = withScope(req, settings, g)
+			}
+			if settings.authenticator != nil {
+				var ok bool
+				if req, ok = settings.authenticator.authenticate(w, req, handle); !ok {
+					return
+				}
+			}
+			if settings.breaker != nil {
+				route := req.Method + " " + req.Pattern
+				if !settings.breaker.Allow(route) {
+					writeError(w, req, "503 service unavailable", http.StatusServiceUnavailable)
+					return
+				}
+				ww := WrapWriter(w)
+				w = ww
+				defer recordResult(settings.breaker, route, ww)
+			}
+			if r.SlowRequestThreshold > 0 {
+				r.serveTimed(w, req, handle, start, matched)
+			} else {
+				handle.ServeHTTP(w, req)
+			}
 			return
-		} else if req.Method != http.MethodConnect && path != "/" {
+		}
+
+		if target, ok := r.redirectTarget(trees, root, req.Method, path, tsr); ok {
 			// Moved Permanently, request with GET method
 			code := http.StatusMovedPermanently
 			if req.Method != http.MethodGet {
 				// Permanent Redirect, request with same method
 				code = http.StatusPermanentRedirect
 			}
-
-			if tsr && r.RedirectTrailingSlash {
-				if len(path) > 1 && path[len(path)-1] == '/' {
-					req.URL.Path = path[:len(path)-1]
-				} else {
-					req.URL.Path = path + "/"
-				}
-				http.Redirect(w, req, req.URL.String(), code)
-				return
-			}
-
-			// Try to fix the request path
-			if r.RedirectFixedPath {
-				fixedPath, found := root.findCaseInsensitivePath(
-					CleanPath(path),
-					r.RedirectTrailingSlash,
-				)
-				if found {
-					req.URL.Path = fixedPath
-					http.Redirect(w, req, req.URL.String(), code)
-					return
-				}
-			}
+			req.URL.Path = target
+			http.Redirect(w, req, req.URL.String(), code)
+			return
 		}
 	}
 
-	if req.Method == http.MethodOptions && r.HandleOPTIONS {
+	if g != nil {
+		req = withScope(req, &noSettings, g)
+	}
+	switch outcome, allow := r.unmatched(trees, req.Method, path); outcome {
+	case OutcomeOptions:
 		// Handle OPTIONS requests
-		if allow := r.allowed(path, http.MethodOptions); allow != "" {
-			w.Header().Set("Allow", allow)
-			if r.GlobalOPTIONS != nil {
-				r.GlobalOPTIONS.ServeHTTP(w, req)
-			}
-			return
+		w.Header().Set("Allow", allow)
+		if r.GlobalOPTIONS != nil {
+			r.GlobalOPTIONS.ServeHTTP(w, req)
 		}
-	} else if r.HandleMethodNotAllowed { // Handle 405
-		if allow := r.allowed(path, req.Method); allow != "" {
-			w.Header().Set("Allow", allow)
-			if r.MethodNotAllowed != nil {
-				r.MethodNotAllowed.ServeHTTP(w, req)
-			} else {
-				http.Error(w,
-					http.StatusText(http.StatusMethodNotAllowed),
-					http.StatusMethodNotAllowed,
-				)
-			}
-			return
+		return
+
+	case OutcomeMethodNotAllowed:
+		// Handle 405
+		w.Header().Set("Allow", allow)
+		if h, ok := req.Context().Value(methodNotAllowedContextKey).(http.Handler); ok {
+			h.ServeHTTP(w, req)
+		} else if r.MethodNotAllowed != nil {
+			r.MethodNotAllowed.ServeHTTP(w, req)
+		} else {
+			writeError(w, req,
+				http.StatusText(http.StatusMethodNotAllowed),
+				http.StatusMethodNotAllowed,
+			)
 		}
+		return
 	}
 
 	// Handle 404
-	if r.NotFound != nil {
+	r.notFound(w, req)
+}
+
+// roots returns the trees the request is matched against: the tree of its
+// method and, for WebSocket upgrades, the tree of the WEBSOCKET routes.
+// Clients sending the pseudo method must not reach them without upgrade.
+func roots(trees *methodTrees, req *http.Request) (root, wsRoot *node) {
+	if req.Method != methodWebSocket {
+		root = trees.get(req.Method)
+	}
+	if req.Method == http.MethodGet && trees.webSocket() != nil && isWebSocketUpgrade(req) {
+		wsRoot = trees.webSocket()
+	}
+	return root, wsRoot
+}
+
+// lookupRoots looks up the path in the trees returned by roots. WebSocket
+// upgrades try the WEBSOCKET routes before the GET routes.
+func lookupRoots(root, wsRoot *node, path string, req *http.Request, params *[]string, e *Explanation) (handle http.Handler, tsr bool) {
+	if wsRoot != nil {
+		if handle, _ = wsRoot.lookup(path, req, params, e); handle != nil {
+			return handle, false
+		}
+		if e != nil && root != nil {
+			// The trace continues with the GET routes
+			e.Steps, e.Failure = e.Steps[:0], ""
+		}
+	}
+	if root != nil {
+		return root.lookup(path, req, params, e)
+	}
+	return nil, false
+}
+
+// redirectTarget returns the path a request for path which matched no route
+// is redirected to, if any.
+func (r *Router) redirectTarget(trees *methodTrees, root *node, method, path string, tsr bool) (string, bool) {
+	if method == http.MethodConnect || path == "/" {
+		return "", false
+	}
+
+	if tsr && r.RedirectTrailingSlash {
+		target := path + "/"
+		if len(path) > 1 && path[len(path)-1] == '/' {
+			target = path[:len(path)-1]
+		}
+		if !trees.routeOptions || !strictSlash(root, target) {
+			return target, true
+		}
+	}
+
+	// Try to fix the request path
+	if r.RedirectFixedPath && root != nil {
+		fixedPath, found := root.findCaseInsensitivePath(
+			CleanPath(path),
+			r.RedirectTrailingSlash,
+		)
+		if found && trees.routeOptions && strictSlash(root, fixedPath) &&
+			strings.HasSuffix(fixedPath, "/") != strings.HasSuffix(path, "/") {
+			found = false
+		}
+		if found {
+			return fixedPath, true
+		}
+	}
+	return "", false
+}
+
+// unmatched reports how a request for path which matched no route and is not
+// redirected is answered: with OutcomeOptions or OutcomeMethodNotAllowed and
+// the value of the Allow header, or with OutcomeNotFound.
+func (r *Router) unmatched(trees *methodTrees, method, path string) (outcome, allow string) {
+	if method == http.MethodOptions && r.HandleOPTIONS && trees.autoOptions(path) {
+		if allow = r.allowedIn(trees, path, http.MethodOptions); allow != "" {
+			return OutcomeOptions, allow
+		}
+	} else if r.HandleMethodNotAllowed {
+		if allow = r.allowedIn(trees, path, method); allow != "" {
+			return OutcomeMethodNotAllowed, allow
+		}
+	}
+	return OutcomeNotFound, ""
+}
+
+// notFound answers a request no route matched.
+func (r *Router) notFound(w http.ResponseWriter, req *http.Request) {
+	if r.fallback != nil && r.serveFallback(w, req) {
+		return
+	}
+
+	if fallback, ok := req.Context().Value(notFoundFallbackContextKey).(http.Handler); ok {
+		fallback.ServeHTTP(w, req)
+	} else if r.NotFound != nil {
 		r.NotFound.ServeHTTP(w, req)
 	} else {
-		http.NotFound(w, req)
+		writeError(w, req, "404 page not found", http.StatusNotFound)
+	}
+}
+
+// writeError replies to the request with the given error message and HTTP
+// code, like http.Error. If the request carries a request ID, it is appended
+// to the message so that clients can report it.
+func writeError(w http.ResponseWriter, req *http.Request, error string, code int) {
+	if id := RequestID(req); id != "" {
+		error += " (request id: " + id + ")"
 	}
+	http.Error(w, error, code)
 }
 
 // RouteError represents a routing configuration error
@@ -45,6 +45,7 @@ func loadHttpMux(routes []route) http.Handler {
 			router.HandleFunc(route.method, path, httpRouterHandle)
 		}
 	}
+	router.Freeze()
 
 	return router
 }
@@ -65,6 +66,7 @@ func loadHttpMuxMulti(routes []route) http.Handler {
 			router.HandleFunc(route.method, path, httpRouterHandle)
 		}
 	}
+	router.Freeze()
 
 	multi.Default(router)
 
@@ -105,7 +107,9 @@ func init() {
 	githubHttpMuxMulti = loadHttpMuxMulti(githubAPIStd)
 
 	// Calculate memory usage if being tested
-	calcMem("HttpRouterGM", func() {})
+	if isTested("HttpRouterGM") {
+		println("   HttpRouterGM:", githubHttpMux.(*Router).TreeStats().Memory, "Bytes (estimated)")
+	}
 	calcMem("PureMux", func() {})
 	calcMem("HttpRouterGM Multi", func() {})
 	println()
@@ -182,6 +186,67 @@ func benchRoutes(b *testing.B, router http.Handler, routes []route) {
 	}
 }
 
+// assertZeroAllocs fails if serving the routes, including the capture of all
+// path parameters, allocates.
+// The request is reused like in benchRoutes, so the storage net/http keeps
+// for path values is only allocated once.
+func assertZeroAllocs(tb testing.TB, router http.Handler, routes []route) {
+	w := new(mockResponseWriter)
+	r, _ := http.NewRequest("GET", "/", nil)
+	u := r.URL
+
+	for _, route := range routes {
+		r.Method = route.method
+		r.RequestURI = route.path
+		u.Path = route.path
+		router.ServeHTTP(w, r)
+	}
+
+	for _, route := range routes {
+		allocs := testing.AllocsPerRun(10, func() {
+			r.Method = route.method
+			r.RequestURI = route.path
+			u.Path = route.path
+			router.ServeHTTP(w, r)
+		})
+		if allocs > 0 {
+			tb.Fatalf("%s %s: expected zero allocations, got %v", route.method, route.path, allocs)
+		}
+	}
+}
+
+func TestHttpMux_GithubAllZeroAllocs(t *testing.T) {
+	assertZeroAllocs(t, githubHttpMux, githubAPIStd)
+}
+
+// loadHttpMuxStats is like loadHttpMux with EnableStats.
+func loadHttpMuxStats(routes []route) *Router {
+	router := New()
+	router.EnableStats()
+	for _, route := range routes {
+		router.HandleFunc(route.method, route.path, httpRouterHandle)
+	}
+	router.Freeze()
+	return router
+}
+
+// Counting requests costs the allocation of the response writer wrapper, see
+// EnableStats.
+func TestHttpMux_GithubAllStatsAllocs(t *testing.T) {
+	router := loadHttpMuxStats(githubAPIStd)
+	w := new(mockResponseWriter)
+	for _, route := range githubAPIStd {
+		r, _ := http.NewRequest(route.method, route.path, nil)
+		if allocs := testing.AllocsPerRun(10, func() { router.ServeHTTP(w, r) }); allocs > 1 {
+			t.Errorf("%s %s: expected at most 1 allocation, got %v", route.method, route.path, allocs)
+		}
+		ww := WrapWriter(w)
+		if allocs := testing.AllocsPerRun(10, func() { router.ServeHTTP(ww, r) }); allocs != 0 {
+			t.Errorf("%s %s: expected no allocations for a wrapped writer, got %v", route.method, route.path, allocs)
+		}
+	}
+}
+
 // githubAPI represents the GitHub API routes
 // var githubAPI = []route{
 // 	// OAuth Authorizations
@@ -641,6 +706,7 @@ var githubAPIStd = []route{
 // BenchmarkStdMux_GithubAll benchmarks our router with all GitHub API routes
 
 func BenchmarkHttpMux_GithubAll(b *testing.B) {
+	assertZeroAllocs(b, githubHttpMux, githubAPIStd)
 	benchRoutes(b, githubHttpMux, githubAPIStd)
 }
 
@@ -653,5 +719,34 @@ func BenchmarkPureServeMux_GithubAll(b *testing.B) {
 
 // Add the new benchmark
 func BenchmarkHttpMuxMulti_GithubAll(b *testing.B) {
+	assertZeroAllocs(b, githubHttpMuxMulti, githubAPIStd)
 	benchRoutes(b, githubHttpMuxMulti, githubAPIStd)
 }
+
+// BenchmarkHttpMux_GithubRegister measures building the tree of all GitHub API
+// routes, which is where longestCommonPrefix is used.
+func BenchmarkHttpMux_GithubRegister(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		router := New()
+		for _, route := range githubAPIStd {
+			router.HandleFunc(route.method, route.path, httpRouterHandle)
+		}
+	}
+}
+
+// BenchmarkHttpMux_GithubRepos looks up the routes below /repos/{owner}/{repo},
+// which share long prefixes with their siblings.
+func BenchmarkHttpMux_GithubRepos(b *testing.B) {
+	var repos []route
+	for _, route := range githubAPIStd {
+		if strings.HasPrefix(route.path, "/repos/") {
+			repos = append(repos, route)
+		}
+	}
+	benchRoutes(b, githubHttpMux, repos)
+}
+
+func BenchmarkHttpMux_GithubAllStats(b *testing.B) {
+	benchRoutes(b, loadHttpMuxStats(githubAPIStd), githubAPIStd)
+}
@@ -7,9 +7,12 @@ package httpmux
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -198,6 +201,13 @@ func TestRouterInvalidInput(t *testing.T) {
 		t.Fatal("registering nil handler did not panic")
 	}
 
+	recv = catchPanic(func() {
+		router.Handle(http.MethodGet, "/", nil)
+	})
+	if recv == nil {
+		t.Fatal("registering nil http.Handler did not panic")
+	}
+
 	recv = catchPanic(func() {
 		router.GET("/{...}", handle)
 	})
@@ -283,6 +293,29 @@ func BenchmarkAllowed(b *testing.B) {
 	})
 }
 
+func BenchmarkParams(b *testing.B) {
+	handlerFunc := func(_ http.ResponseWriter, _ *http.Request) {}
+
+	router := New()
+	router.GET("/{a}", handlerFunc)
+	router.GET("/{a}/{b}/{c}/{d}/{e}", handlerFunc)
+	router.GET("/{a}/{b}/{c}/{d}/{e}/{f}/{g}/{h}/{i}/{j}", handlerFunc)
+
+	for _, path := range []string{"/1", "/1/2/3/4/5", "/1/2/3/4/5/6/7/8/9/10"} {
+		b.Run(strconv.Itoa(strings.Count(path, "/")), func(b *testing.B) {
+			w := new(mockResponseWriter)
+			base, _ := http.NewRequest(http.MethodGet, path, nil)
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				// Path values are stored per request
+				req := *base
+				router.ServeHTTP(w, &req)
+			}
+		})
+	}
+}
+
 func TestRouterOPTIONS(t *testing.T) {
 	handlerFunc := func(_ http.ResponseWriter, _ *http.Request) {}
 
@@ -714,3 +747,244 @@ func TestRouterServeFiles(t *testing.T) {
 		t.Error("serving file failed")
 	}
 }
+
+func TestRouterPattern(t *testing.T) {
+	router := New()
+
+	var pattern string
+	handlerFunc := func(_ http.ResponseWriter, r *http.Request) {
+		pattern = r.Pattern
+	}
+	router.GET("/", handlerFunc)
+	router.GET("/user/{name}", handlerFunc)
+	router.GET("/user/{name}/about", handlerFunc)
+	router.GET("/files/{filepath...}", handlerFunc)
+	router.GET("/exact/{$}", handlerFunc)
+
+	for path, want := range map[string]string{
+		"/":                   "/",
+		"/user/gopher":        "/user/{name}",
+		"/user/gopher/about":  "/user/{name}/about",
+		"/files/dir/file.txt": "/files/{filepath...}",
+		"/exact/":             "/exact/",
+	} {
+		pattern = ""
+		r, _ := http.NewRequest(http.MethodGet, path, nil)
+		router.ServeHTTP(httptest.NewRecorder(), r)
+		if pattern != want {
+			t.Errorf("path %s: expected pattern %q, got %q", path, want, pattern)
+		}
+	}
+}
+
+func TestRouterUse(t *testing.T) {
+	router := New()
+
+	var calls []string
+	mw := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name+":"+r.Pattern+":"+r.PathValue("name"))
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	router.GET("/before", func(_ http.ResponseWriter, _ *http.Request) {
+		calls = append(calls, "before")
+	})
+	router.Use(mw("first"), mw("second"))
+	router.GET("/user/{name}", func(_ http.ResponseWriter, _ *http.Request) {
+		calls = append(calls, "handler")
+	})
+
+	r, _ := http.NewRequest(http.MethodGet, "/user/gopher", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	r, _ = http.NewRequest(http.MethodGet, "/before", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	want := []string{"first:/user/{name}:gopher", "second:/user/{name}:gopher", "handler", "before"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("unexpected middleware calls: want %v, got %v", want, calls)
+	}
+}
+
+func TestRouterFailedLookupLeavesPathValues(t *testing.T) {
+	router := New()
+	router.GET("/user/{name}", func(_ http.ResponseWriter, _ *http.Request) {})
+	router.GET("/user/{name}/{a}/{b}/{c}/{d}/{e}/{f}/{g}/{h}/{i}", func(_ http.ResponseWriter, r *http.Request) {
+		for _, name := range []string{"name", "a", "b", "c", "d", "e", "f", "g", "h", "i"} {
+			if r.PathValue(name) != name {
+				t.Errorf("wrong value for %s: %q", name, r.PathValue(name))
+			}
+		}
+	})
+
+	r, _ := http.NewRequest(http.MethodGet, "/user/name/a/b/c/d/e/f/g/h/i", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	r, _ = http.NewRequest(http.MethodGet, "/user/gopher/extra", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	if v := r.PathValue("name"); v != "" {
+		t.Errorf("expected no path value after failed lookup, got %q", v)
+	}
+	if r.Pattern != "" {
+		t.Errorf("expected no pattern after failed lookup, got %q", r.Pattern)
+	}
+}
+
+func TestRouterAllowedAllocs(t *testing.T) {
+	handlerFunc := func(_ http.ResponseWriter, _ *http.Request) {}
+
+	router := New()
+	router.POST("/path", handlerFunc)
+	router.GET("/path", handlerFunc)
+	router.DELETE("/other", handlerFunc)
+
+	for _, path := range []string{"*", "/path"} {
+		allocs := testing.AllocsPerRun(100, func() {
+			_ = router.allowed(path, http.MethodOptions)
+		})
+		if allocs > 0 {
+			t.Errorf("%s: expected no allocations, got %v", path, allocs)
+		}
+	}
+
+	if allow := router.allowed("/path", http.MethodPut); allow != "GET, OPTIONS, POST" {
+		t.Errorf("unexpected Allow value %q", allow)
+	}
+
+	// non-standard methods are sorted in
+	router.HandleFunc("PURGE", "/path", handlerFunc)
+	router.HandleFunc("ACL", "/path", handlerFunc)
+	if allow := router.allowed("/path", http.MethodPut); allow != "ACL, GET, OPTIONS, POST, PURGE" {
+		t.Errorf("unexpected Allow value %q", allow)
+	}
+	if allow := router.allowed("*", http.MethodOptions); allow != "ACL, DELETE, GET, OPTIONS, POST, PURGE" {
+		t.Errorf("unexpected global Allow value %q", allow)
+	}
+}
+
+func TestRouterCustomMethod(t *testing.T) {
+	var served string
+	// the zero value must be usable, too
+	router := &Router{}
+	router.HandleFunc("PURGE", "/cache/{key}", func(_ http.ResponseWriter, req *http.Request) {
+		served = "PURGE " + req.PathValue("key")
+	})
+	router.GET("/cache/{key}", func(_ http.ResponseWriter, req *http.Request) {
+		served = "GET " + req.PathValue("key")
+	})
+
+	for _, method := range []string{"PURGE", http.MethodGet} {
+		served = ""
+		r, _ := http.NewRequest(method, "/cache/foo", nil)
+		router.ServeHTTP(httptest.NewRecorder(), r)
+		if want := method + " foo"; served != want {
+			t.Errorf("expected %q to be served, got %q", want, served)
+		}
+	}
+
+	r, _ := http.NewRequest("BAN", "/cache/foo", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unregistered method, got %d", w.Code)
+	}
+}
+
+func TestRouterManyParamsAllocs(t *testing.T) {
+	var a, j, rest string
+	router := New()
+	router.GET("/{a}/{b}/{c}/{d}/{e}/{f}/{g}/{h}/{i}/{j}/{rest...}", func(_ http.ResponseWriter, req *http.Request) {
+		a, j, rest = req.PathValue("a"), req.PathValue("j"), req.PathValue("rest")
+	})
+
+	w := new(mockResponseWriter)
+	req, _ := http.NewRequest(http.MethodGet, "/1/2/3/4/5/6/7/8/9/10/x/y", nil)
+	router.ServeHTTP(w, req)
+	if a != "1" || j != "10" || rest != "/x/y" {
+		t.Fatalf("unexpected path values a=%q j=%q rest=%q", a, j, rest)
+	}
+
+	// Parameters exceeding the stack buffer use pooled buffers
+	allocs := testing.AllocsPerRun(100, func() {
+		router.ServeHTTP(w, req)
+	})
+	if allocs > 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
+
+type countingHandler struct {
+	hits int
+}
+
+func (h *countingHandler) ServeHTTP(_ http.ResponseWriter, _ *http.Request) {
+	h.hits++
+}
+
+func TestRouterHandleStoresHandler(t *testing.T) {
+	h := new(countingHandler)
+	router := New()
+	router.Handle(http.MethodGet, "/count", h)
+
+	if stored, _ := router.trees.Load().get(http.MethodGet).getValue("/count", nil, nil); stored != http.Handler(h) {
+		t.Fatalf("expected the handler to be stored as is, got %T", stored)
+	}
+
+	r, _ := http.NewRequest(http.MethodGet, "/count", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	if h.hits != 1 {
+		t.Errorf("expected 1 hit, got %d", h.hits)
+	}
+}
+
+func TestRouterSettingsWhileServing(t *testing.T) {
+	router := New()
+	router.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		Respond(w, r, http.StatusOK, "ok")
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			router.SetEncoder("text/plain", func(w io.Writer, v any) error {
+				_, err := fmt.Fprint(w, v)
+				return err
+			})
+			router.SetValidator(func(any) error { return nil })
+			router.SetBreaker(nil)
+			router.SetAuthenticator(nil)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d", w.Code)
+		}
+	}
+	<-done
+}
+
+func TestRouterSettingsAllocs(t *testing.T) {
+	router := New()
+	router.SetValidator(func(any) error { return nil })
+	router.SetEncoder("text/plain", func(io.Writer, any) error { return nil })
+	router.GET("/", func(http.ResponseWriter, *http.Request) {})
+	router.NotFound = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	w := httptest.NewRecorder()
+
+	// The settings are added to the context of matched requests only, all at
+	// once
+	notFound := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	if allocs := testing.AllocsPerRun(100, func() { router.ServeHTTP(w, notFound) }); allocs != 0 {
+		t.Errorf("unmatched request: %v allocations, want 0", allocs)
+	}
+	matched := httptest.NewRequest(http.MethodGet, "/", nil)
+	if allocs := testing.AllocsPerRun(100, func() { router.ServeHTTP(w, matched) }); allocs > 2 {
+		t.Errorf("matched request: %v allocations, want at most 2", allocs)
+	}
+}
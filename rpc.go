@@ -0,0 +1,126 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+var (
+	contextType = reflect.TypeFor[context.Context]()
+	errorType   = reflect.TypeFor[error]()
+)
+
+// StatusError is implemented by errors which determine the status code of the
+// error response, e.g. of methods exposed by Router.Service.
+type StatusError interface {
+	error
+	StatusCode() int
+}
+
+// Service exposes the exported methods of the service as JSON RPC endpoints
+// at POST /{name}/{method}, for RPC over HTTP between internal services:
+//
+//	type Users struct{ db *sql.DB }
+//
+//	func (u *Users) Get(ctx context.Context, in *GetUserRequest) (*User, error) { ... }
+//	func (u *Users) Purge(ctx context.Context) error { ... }
+//
+//	router.Service("users", &Users{db: db}) // POST /users/Get, POST /users/Purge
+//
+// Methods must take a context.Context, optionally followed by an input, and
+// return an error, optionally preceded by an output; other methods are
+// ignored. If name is empty, the name of the service's type is used.
+//
+// The request body is decoded as JSON into the input, an empty body leaving it
+// zero, or pointing to a zero value for pointer inputs, and validated by the
+// validator set by SetValidator, if any. The output is encoded as JSON in a
+// 200 OK response, methods without output answer with 204 No Content. Errors
+// are answered by RespondError: undecodable bodies with 400 Bad Request,
+// invalid inputs with 422 Unprocessable Entity and errors returned by the
+// method with 500 Internal Server Error, or with the status of a StatusError.
+// The request's context is passed to the method.
+//
+// Service panics if the service has no suitable method.
+func (r *Router) Service(name string, service any) {
+	sv := reflect.ValueOf(service)
+	if name == "" {
+		name = reflect.Indirect(sv).Type().Name()
+	}
+
+	registered := 0
+	for i := range sv.NumMethod() {
+		m := sv.Type().Method(i)
+		handler := rpcHandler(sv.Method(i))
+		if handler == nil {
+			continue
+		}
+		r.Handle(http.MethodPost, "/"+name+"/"+m.Name, handler)
+		registered++
+	}
+	if registered == 0 {
+		panic("service '" + name + "' has no method of the form func(context.Context[, In]) ([Out, ]error)")
+	}
+}
+
+// rpcHandler returns a handler calling the method, or nil if the method is
+// not of the form accepted by Service.
+func rpcHandler(method reflect.Value) http.Handler {
+	mt := method.Type()
+	if !isRPCMethod(mt) {
+		return nil
+	}
+	hasIn, hasOut := mt.NumIn() == 2, mt.NumOut() == 2
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		args := []reflect.Value{reflect.ValueOf(req.Context())}
+		if hasIn {
+			in := newRPCInput(mt.In(1))
+			if err := json.NewDecoder(req.Body).Decode(in.Interface()); err != nil && !errors.Is(err, io.EOF) {
+				RespondError(w, req, &BindError{Errors: []FieldError{{Source: "body", Message: err.Error()}}})
+				return
+			}
+			if err := validateRequestValue(req, in.Elem().Interface()); err != nil {
+				RespondError(w, req, err)
+				return
+			}
+			args = append(args, in.Elem())
+		}
+
+		results := method.Call(args)
+		if err, _ := results[len(results)-1].Interface().(error); err != nil {
+			RespondError(w, req, err)
+			return
+		}
+		if !hasOut {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(results[0].Interface())
+	})
+}
+
+// isRPCMethod reports whether the function type is of the form
+// func(context.Context[, In]) ([Out, ]error).
+func isRPCMethod(mt reflect.Type) bool {
+	return mt.NumIn() >= 1 && mt.NumIn() <= 2 && mt.In(0) == contextType &&
+		mt.NumOut() >= 1 && mt.NumOut() <= 2 && mt.Out(mt.NumOut()-1) == errorType
+}
+
+// newRPCInput returns a pointer to a new input of the type, which points to a
+// zero value itself for pointer types.
+func newRPCInput(t reflect.Type) reflect.Value {
+	in := reflect.New(t)
+	if t.Kind() == reflect.Pointer {
+		in.Elem().Set(reflect.New(t.Elem()))
+	}
+	return in
+}
@@ -0,0 +1,109 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type rpcGreeting struct {
+	Name string `json:"name"`
+}
+
+type rpcNotFound struct{}
+
+func (rpcNotFound) Error() string   { return "no such user" }
+func (rpcNotFound) StatusCode() int { return http.StatusNotFound }
+
+type rpcService struct {
+	purged bool
+}
+
+func (s *rpcService) Greet(ctx context.Context, in *rpcGreeting) (*rpcGreeting, error) {
+	if in.Name == "" {
+		in.Name = "nobody"
+	}
+	return &rpcGreeting{Name: "hello " + in.Name}, nil
+}
+
+func (s *rpcService) Find(ctx context.Context, id int) (string, error) {
+	if id != 1 {
+		return "", rpcNotFound{}
+	}
+	return "one", nil
+}
+
+func (s *rpcService) Fail(ctx context.Context) (int, error) {
+	return 0, errors.New("boom")
+}
+
+func (s *rpcService) Purge(ctx context.Context) error {
+	s.purged = true
+	return nil
+}
+
+// Not of the form accepted by Service
+func (s *rpcService) Helper(n int) int {
+	return n
+}
+
+func TestRouterService(t *testing.T) {
+	svc := new(rpcService)
+	router := New()
+	router.Service("", svc)
+
+	for _, tt := range []struct {
+		path, body string
+		code       int
+		resp       string
+	}{
+		{"/rpcService/Greet", `{"name":"ann"}`, http.StatusOK, `{"name":"hello ann"}`},
+		{"/rpcService/Greet", ``, http.StatusOK, `{"name":"hello nobody"}`},
+		{"/rpcService/Greet", `{"name":`, http.StatusBadRequest, `{"error":"invalid request: body: unexpected EOF","fields":[{"source":"body","message":"unexpected EOF"}]}`},
+		{"/rpcService/Find", `1`, http.StatusOK, `"one"`},
+		{"/rpcService/Find", `2`, http.StatusNotFound, `{"error":"no such user"}`},
+		{"/rpcService/Fail", ``, http.StatusInternalServerError, `{"error":"Internal Server Error"}`},
+		{"/rpcService/Purge", ``, http.StatusNoContent, ``},
+		{"/rpcService/Helper", `1`, http.StatusNotFound, "404 page not found"},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+		if w.Code != tt.code || strings.TrimSpace(w.Body.String()) != tt.resp {
+			t.Errorf("%s %s: expected %d %s, got %d %s", tt.path, tt.body, tt.code, tt.resp, w.Code, w.Body.String())
+		}
+	}
+	if !svc.purged {
+		t.Error("Purge was not called")
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/rpcService/Greet", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405 for GET, got %d", w.Code)
+	}
+}
+
+func TestRouterServiceName(t *testing.T) {
+	router := New()
+	router.Service("greeter", new(rpcService))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/greeter/Greet", strings.NewReader(`{"name":"bo"}`)))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+
+	recv := catchPanic(func() {
+		New().Service("empty", struct{}{})
+	})
+	if recv == nil {
+		t.Error("expected a panic for a service without methods")
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// ServerOption configures the server started by Serve.
+type ServerOption func(*serverConfig)
+
+type serverConfig struct {
+	gracePeriod  time.Duration
+	configure    []func(*http.Server)
+	onShutdown   []func()
+	certManager  CertManager
+	redirectAddr string
+}
+
+// WithGracePeriod sets how long Serve waits for active requests to complete
+// on shutdown, 30 seconds by default.
+func WithGracePeriod(d time.Duration) ServerOption {
+	return func(c *serverConfig) {
+		c.gracePeriod = d
+	}
+}
+
+// WithServerConfig modifies the http.Server before it starts, e.g. to change
+// its timeouts or set an ErrorLog.
+func WithServerConfig(configure func(*http.Server)) ServerOption {
+	return func(c *serverConfig) {
+		c.configure = append(c.configure, configure)
+	}
+}
+
+// WithOnShutdown adds a function called when the shutdown begins, before the
+// server stops accepting connections, e.g. Health.Drain.
+func WithOnShutdown(fn func()) ServerOption {
+	return func(c *serverConfig) {
+		c.onShutdown = append(c.onShutdown, fn)
+	}
+}
+
+// CertManager obtains TLS certificates on demand, e.g. from Let's Encrypt.
+// It is implemented by autocert.Manager of golang.org/x/crypto/acme/autocert:
+//
+//	m := &autocert.Manager{
+//		Prompt:     autocert.AcceptTOS,
+//		HostPolicy: autocert.HostWhitelist("example.com"),
+//		Cache:      autocert.DirCache("certs"),
+//	}
+//	err := httpmux.ServeTLS(":443", router, "", "", httpmux.WithAutocert(m), httpmux.WithHTTPRedirect(":80"))
+type CertManager interface {
+	// TLSConfig returns the TLS configuration of the server.
+	TLSConfig() *tls.Config
+
+	// HTTPHandler returns a handler answering the HTTP-01 challenges of the
+	// certificate authority and passing other requests to fallback.
+	HTTPHandler(fallback http.Handler) http.Handler
+}
+
+// WithAutocert makes ServeTLS obtain certificates from the manager instead of
+// loading them from files.
+func WithAutocert(m CertManager) ServerOption {
+	return func(c *serverConfig) {
+		c.certManager = m
+	}
+}
+
+// WithHTTPRedirect makes ServeTLS serve plain HTTP on the given address as
+// well, redirecting requests to HTTPS. With WithAutocert, the HTTP-01
+// challenges of the certificate authority are answered there.
+func WithHTTPRedirect(addr string) ServerOption {
+	return func(c *serverConfig) {
+		c.redirectAddr = addr
+	}
+}
+
+// Serve serves the handler on the TCP address until the process receives
+// SIGINT or SIGTERM, then shuts the server down gracefully:
+//
+//	if err := httpmux.Serve(":8080", router); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// The server limits the time to read request headers to 5 seconds, to read a
+// request to 30 seconds, to write a response to 60 seconds, and keeps idle
+// connections for 2 minutes. WithServerConfig changes these defaults.
+//
+// On shutdown, the server stops accepting connections and waits for active
+// requests to complete for the grace period, after which the remaining
+// connections are closed. Serve returns nil if the shutdown completed in
+// time, or an error if the server failed or the grace period was exceeded.
+func Serve(addr string, handler http.Handler, opts ...ServerOption) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv, cfg := newServer(addr, handler, opts)
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+	return serveUntilDone(ctx, cfg, []*http.Server{srv}, []func() error{func() error {
+		return srv.Serve(ln)
+	}})
+}
+
+// ListenAndServe serves the router on the TCP address, see Serve.
+func (r *Router) ListenAndServe(addr string, opts ...ServerOption) error {
+	return Serve(addr, r, opts...)
+}
+
+// ServeTLS is like Serve, but serves HTTPS with the certificate and key
+// files, or with certificates obtained by a CertManager set by WithAutocert,
+// in which case the file names must be empty. With WithHTTPRedirect, plain
+// HTTP requests are redirected to HTTPS; both servers are shut down together.
+func ServeTLS(addr string, handler http.Handler, certFile, keyFile string, opts ...ServerOption) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return serveTLS(ctx, addr, handler, certFile, keyFile, opts)
+}
+
+// ListenAndServeTLS serves the router on the TCP address with HTTPS, see
+// ServeTLS.
+func (r *Router) ListenAndServeTLS(addr, certFile, keyFile string, opts ...ServerOption) error {
+	return ServeTLS(addr, r, certFile, keyFile, opts...)
+}
+
+func serveTLS(ctx context.Context, addr string, handler http.Handler, certFile, keyFile string, opts []ServerOption) error {
+	srv, cfg := newServer(addr, handler, opts)
+	if cfg.certManager != nil && (certFile != "" || keyFile != "") {
+		return errors.New("httpmux: certificate files cannot be used with a CertManager")
+	}
+	if cfg.certManager == nil && (certFile == "" || keyFile == "") {
+		return errors.New("httpmux: missing certificate or key file")
+	}
+
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+	servers := []*http.Server{srv}
+	serve := []func() error{func() error {
+		return srv.ServeTLS(ln, certFile, keyFile)
+	}}
+
+	if cfg.redirectAddr != "" {
+		var redirect http.Handler = httpsRedirect(srv.Addr)
+		if cfg.certManager != nil {
+			redirect = cfg.certManager.HTTPHandler(redirect)
+		}
+		redirectSrv, _ := newServer(cfg.redirectAddr, redirect, nil)
+		redirectLn, err := net.Listen("tcp", redirectSrv.Addr)
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		servers = append(servers, redirectSrv)
+		serve = append(serve, func() error {
+			return redirectSrv.Serve(redirectLn)
+		})
+	}
+	return serveUntilDone(ctx, cfg, servers, serve)
+}
+
+// httpsRedirect returns a handler redirecting requests to the same URL with
+// HTTPS on the port of the TLS address.
+func httpsRedirect(tlsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(tlsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		host := req.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+
+		code := http.StatusMovedPermanently
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			code = http.StatusPermanentRedirect
+		}
+		http.Redirect(w, req, "https://"+host+req.URL.RequestURI(), code)
+	})
+}
+
+// newServer returns a server with the defaults of Serve and the options
+// applied.
+func newServer(addr string, handler http.Handler, opts []ServerOption) (*http.Server, *serverConfig) {
+	cfg := &serverConfig{gracePeriod: 30 * time.Second}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+	if cfg.certManager != nil {
+		srv.TLSConfig = cfg.certManager.TLSConfig()
+	}
+	for _, configure := range cfg.configure {
+		configure(srv)
+	}
+	return srv, cfg
+}
+
+// serveUntilDone runs the servers until one of them fails or the context is
+// done, in which case they are shut down gracefully.
+func serveUntilDone(ctx context.Context, cfg *serverConfig, servers []*http.Server, serve []func() error) error {
+	errc := make(chan error, len(serve))
+	for _, fn := range serve {
+		go func() {
+			errc <- fn()
+		}()
+	}
+
+	var serveErr error
+	select {
+	case serveErr = <-errc:
+	case <-ctx.Done():
+		for _, fn := range cfg.onShutdown {
+			fn()
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.gracePeriod)
+	defer cancel()
+	var shutdownErr error
+	for _, srv := range servers {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			srv.Close()
+			shutdownErr = fmt.Errorf("httpmux: shutdown incomplete after %s: %w", cfg.gracePeriod, err)
+		}
+	}
+
+	// Collect the results of the servers still running
+	remaining := len(serve)
+	if serveErr != nil {
+		remaining--
+	}
+	for range remaining {
+		if err := <-errc; serveErr == nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr = err
+		}
+	}
+	if serveErr != nil {
+		return serveErr
+	}
+	return shutdownErr
+}
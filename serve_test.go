@@ -0,0 +1,301 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// startTestServer serves the handler like Serve until ctx is done and
+// returns the address and the result of the server.
+func startTestServer(t *testing.T, ctx context.Context, handler http.Handler, opts ...ServerOption) (string, <-chan error) {
+	t.Helper()
+	srv, cfg := newServer("127.0.0.1:0", handler, opts)
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- serveUntilDone(ctx, cfg, []*http.Server{srv}, []func() error{func() error { return srv.Serve(ln) }})
+	}()
+	return ln.Addr().String(), done
+}
+
+func TestServeGracefulShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	router := New()
+	router.GET("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	shutdownCalled := make(chan struct{})
+	addr, done := startTestServer(t, ctx, router, WithOnShutdown(func() { close(shutdownCalled) }))
+
+	bodyc := make(chan string, 1)
+	go func() {
+		res, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			bodyc <- err.Error()
+			return
+		}
+		defer res.Body.Close()
+		b, _ := io.ReadAll(res.Body)
+		bodyc <- string(b)
+	}()
+	<-started
+	cancel()
+	<-shutdownCalled
+
+	// The active request completes, new connections are refused
+	time.Sleep(10 * time.Millisecond)
+	if _, err := net.Dial("tcp", addr); err == nil {
+		t.Error("expected new connections to be refused")
+	}
+	close(release)
+	if body := <-bodyc; body != "done" {
+		t.Errorf("unexpected body %q", body)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestServeGracePeriodExceeded(t *testing.T) {
+	started := make(chan struct{})
+	router := New()
+	router.GET("/stuck", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	addr, done := startTestServer(t, ctx, router, WithGracePeriod(10*time.Millisecond))
+	go http.Get("http://" + addr + "/stuck")
+	<-started
+	cancel()
+
+	err := <-done
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "shutdown incomplete") {
+		t.Errorf("expected shutdown error, got %v", err)
+	}
+}
+
+func TestServeOptions(t *testing.T) {
+	srv, cfg := newServer(":8080", New(), []ServerOption{
+		WithGracePeriod(time.Second),
+		WithServerConfig(func(s *http.Server) { s.WriteTimeout = 0 }),
+	})
+	if cfg.gracePeriod != time.Second || srv.WriteTimeout != 0 || srv.ReadHeaderTimeout != 5*time.Second {
+		t.Errorf("unexpected configuration %+v", srv)
+	}
+
+	// Errors of the listener are returned
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if err := Serve(ln.Addr().String(), New()); err == nil {
+		t.Error("expected error for address in use")
+	}
+}
+
+func TestServeSignal(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	router := New()
+	router.GET("/", dummyHandler)
+	done := make(chan error, 1)
+	go func() {
+		done <- router.ListenAndServe(addr)
+	}()
+	for i := 0; ; i++ {
+		if res, err := http.Get("http://" + addr + "/"); err == nil {
+			res.Body.Close()
+			break
+		} else if i == 100 {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	p, _ := os.FindProcess(os.Getpid())
+	if err := p.Signal(syscall.SIGTERM); err != nil {
+		t.Skipf("cannot send signal: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+}
+
+// writeTestCert writes a self-signed certificate for 127.0.0.1 and its key to
+// the directory and returns the certificate.
+func writeTestCert(t *testing.T, dir string) (tls.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, certFile, keyFile
+}
+
+// freeAddr returns a local address which is currently not in use.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// testCertManager serves a fixed certificate and answers a single challenge.
+type testCertManager struct {
+	cert tls.Certificate
+}
+
+func (m *testCertManager) TLSConfig() *tls.Config {
+	return &tls.Config{GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return &m.cert, nil
+	}}
+}
+
+func (m *testCertManager) HTTPHandler(fallback http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/.well-known/acme-challenge/token" {
+			w.Write([]byte("challenge"))
+			return
+		}
+		fallback.ServeHTTP(w, r)
+	})
+}
+
+func TestServeTLS(t *testing.T) {
+	cert, certFile, keyFile := writeTestCert(t, t.TempDir())
+	router := New()
+	router.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secure"))
+	})
+
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	get := func(url string) (int, string, string) {
+		t.Helper()
+		for i := 0; ; i++ {
+			res, err := client.Get(url)
+			if err == nil {
+				defer res.Body.Close()
+				b, _ := io.ReadAll(res.Body)
+				return res.StatusCode, string(b), res.Header.Get("Location")
+			}
+			if i == 100 {
+				t.Fatal(err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	for _, opts := range [][]ServerOption{nil, {WithAutocert(&testCertManager{cert})}} {
+		files := []string{certFile, keyFile}
+		if opts != nil {
+			files = []string{"", ""}
+		}
+		addr, httpAddr := freeAddr(t), freeAddr(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- serveTLS(ctx, addr, router, files[0], files[1], append(opts, WithHTTPRedirect(httpAddr)))
+		}()
+
+		if code, body, _ := get("https://" + addr + "/"); code != http.StatusOK || body != "secure" {
+			t.Errorf("unexpected HTTPS response %d %q", code, body)
+		}
+		_, port, _ := net.SplitHostPort(addr)
+		if code, _, location := get("http://" + httpAddr + "/a?b=c"); code != http.StatusMovedPermanently || location != "https://127.0.0.1:"+port+"/a?b=c" {
+			t.Errorf("unexpected redirect %d %q", code, location)
+		}
+		code, body, _ := get("http://" + httpAddr + "/.well-known/acme-challenge/token")
+		if opts != nil && (code != http.StatusOK || body != "challenge") {
+			t.Errorf("unexpected challenge response %d %q", code, body)
+		}
+
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+
+	if err := serveTLS(context.Background(), freeAddr(t), router, "", "", nil); err == nil {
+		t.Error("expected error without certificate")
+	}
+	if err := serveTLS(context.Background(), freeAddr(t), router, certFile, keyFile, []ServerOption{WithAutocert(&testCertManager{cert})}); err == nil {
+		t.Error("expected error for certificate files with a CertManager")
+	}
+}
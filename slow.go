@@ -0,0 +1,61 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// RequestTiming describes how long the router spent on a request.
+type RequestTiming struct {
+	// The registered path of the matched route
+	Route string
+
+	// Time spent looking up the route in the tree
+	MatchTime time.Duration
+
+	// Time spent after the lookup: in the router's authenticator and breaker,
+	// if any, and in the route's handler, including its middlewares
+	HandlerTime time.Duration
+}
+
+// Total returns the total time spent on the request.
+func (t RequestTiming) Total() time.Duration {
+	return t.MatchTime + t.HandlerTime
+}
+
+const (
+	logKeyMatchTime   = "match_time"
+	logKeyHandlerTime = "handler_time"
+)
+
+// serveTimed serves a request matched by the route lookup which started and
+// ended at the given times, reporting it if it exceeds the
+// SlowRequestThreshold.
+func (r *Router) serveTimed(w http.ResponseWriter, req *http.Request, handle http.Handler, start, matched time.Time) {
+	handle.ServeHTTP(w, req)
+
+	timing := RequestTiming{
+		Route:       req.Pattern,
+		MatchTime:   matched.Sub(start),
+		HandlerTime: time.Since(matched),
+	}
+	if timing.Total() < r.SlowRequestThreshold {
+		return
+	}
+
+	if r.SlowRequest != nil {
+		r.SlowRequest(req, timing)
+	} else if r.logger != nil {
+		r.logger.LogAttrs(req.Context(), slog.LevelWarn, "httpmux: slow request",
+			append(requestAttrs(req, req.URL.Path),
+				slog.Duration(logKeyMatchTime, timing.MatchTime),
+				slog.Duration(logKeyHandlerTime, timing.HandlerTime),
+			)...,
+		)
+	}
+}
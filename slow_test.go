@@ -0,0 +1,89 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRouterSlowRequest(t *testing.T) {
+	router := New()
+	router.SlowRequestThreshold = 5 * time.Millisecond
+
+	var reported []RequestTiming
+	router.SlowRequest = func(_ *http.Request, timing RequestTiming) {
+		reported = append(reported, timing)
+	}
+	router.GET("/fast", func(_ http.ResponseWriter, _ *http.Request) {})
+	router.GET("/slow/{id}", func(_ http.ResponseWriter, _ *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+	})
+
+	for _, path := range []string{"/fast", "/slow/1"} {
+		r, _ := http.NewRequest(http.MethodGet, path, nil)
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	if len(reported) != 1 {
+		t.Fatalf("expected 1 slow request, got %d", len(reported))
+	}
+	timing := reported[0]
+	if timing.Route != "/slow/{id}" {
+		t.Errorf("unexpected route %q", timing.Route)
+	}
+	if timing.HandlerTime < 10*time.Millisecond || timing.Total() < timing.HandlerTime {
+		t.Errorf("unexpected timing %+v", timing)
+	}
+}
+
+func TestRouterSlowRequestAuthTime(t *testing.T) {
+	router := New()
+	router.SlowRequestThreshold = 5 * time.Millisecond
+	router.SetAuthenticator(AuthenticatorFunc(func(_ *http.Request) (any, error) {
+		time.Sleep(10 * time.Millisecond)
+		return "user", nil
+	}))
+
+	var reported []RequestTiming
+	router.SlowRequest = func(_ *http.Request, timing RequestTiming) {
+		reported = append(reported, timing)
+	}
+	router.GET("/me", func(_ http.ResponseWriter, _ *http.Request) {})
+
+	r, _ := http.NewRequest(http.MethodGet, "/me", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	if len(reported) != 1 {
+		t.Fatalf("expected 1 slow request, got %d", len(reported))
+	}
+	// The authenticator runs after the lookup
+	if timing := reported[0]; timing.MatchTime >= 10*time.Millisecond || timing.HandlerTime < 10*time.Millisecond {
+		t.Errorf("unexpected timing %+v", timing)
+	}
+}
+
+func TestRouterSlowRequestLog(t *testing.T) {
+	logger, buf := newTestLogger()
+	router := New()
+	router.SetLogger(logger)
+	router.SlowRequestThreshold = time.Nanosecond
+	router.GET("/user/{name}", func(_ http.ResponseWriter, _ *http.Request) {
+		time.Sleep(time.Millisecond)
+	})
+
+	r, _ := http.NewRequest(http.MethodGet, "/user/gopher", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	out := buf.String()
+	for _, want := range []string{"slow request", "route=/user/{name}", "match_time=", "handler_time="} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in log, got %q", want, out)
+		}
+	}
+}
@@ -0,0 +1,564 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServeFS serves files from the given file system, like ServeFiles.
+// It accepts any fs.FS, e.g. an embed.FS or a fstest.MapFS, so that no
+// conversion with http.FS is needed:
+//
+//	//go:embed static
+//	var static embed.FS
+//
+//	assets, _ := fs.Sub(static, "static")
+//	router.ServeFS("/assets/{filepath...}", assets)
+func (r *Router) ServeFS(path string, fsys fs.FS, opts ...StaticOption) {
+	r.ServeFiles(path, http.FS(fsys), opts...)
+}
+
+// StaticOption configures how ServeFiles and ServeFS serve files.
+type StaticOption func(*staticConfig)
+
+type staticConfig struct {
+	noDirectoryListing bool
+	cache              *CachePolicy
+	precompressed      bool
+	noSymlinkEscape    bool
+	hostRoots          []hostRoot
+	hooks              *StaticHooks
+}
+
+type hostRoot struct {
+	host string
+	root http.FileSystem
+}
+
+// NoDirectoryListing answers requests for directories without an index.html
+// with 404 Not Found, instead of listing their contents. Directories with an
+// index.html are still served with it.
+func NoDirectoryListing() StaticOption {
+	return func(c *staticConfig) {
+		c.noDirectoryListing = true
+	}
+}
+
+// NoSymlinkEscape refuses to serve files through symbolic links pointing
+// outside of the root directory, e.g. links created by users uploading to
+// the served directory. The root must be a http.Dir; it is opened with
+// os.OpenRoot when the files are registered, which panics if it fails.
+func NoSymlinkEscape() StaticOption {
+	return func(c *staticConfig) {
+		c.noSymlinkEscape = true
+	}
+}
+
+// WithHostRoot serves the files of root instead for requests to the given
+// host, so that one router can serve the assets of several sites. Like with
+// MultiRouter.Host, a leading "*." matches all subdomains of a domain, exact
+// host names take precedence over wildcards, and hosts are matched
+// case-insensitively, ignoring the port. Requests to other hosts are served
+// from the root passed to ServeFiles or ServeFS:
+//
+//	router.ServeFiles("/assets/{filepath...}", http.Dir("sites/default"),
+//		httpmux.WithHostRoot("shop.example.com", http.Dir("sites/shop")),
+//		httpmux.WithHostRoot("*.blog.example.com", http.Dir("sites/blog")))
+//
+// The other options apply to all roots. Registering the same host twice
+// panics.
+func WithHostRoot(host string, root http.FileSystem) StaticOption {
+	return func(c *staticConfig) {
+		c.hostRoots = append(c.hostRoots, hostRoot{host: host, root: root})
+	}
+}
+
+// StaticHooks are callbacks reporting the requests served by ServeFiles and
+// ServeFS, e.g. to record asset traffic in the same metrics as the routes of
+// the router. The callbacks run after the response was written. Unset
+// callbacks are skipped.
+type StaticHooks struct {
+	// Called for requests answered with a file, including partial and not
+	// modified responses and redirects, with the number of body bytes written
+	OnHit func(r *http.Request, name string, bytes int64)
+
+	// Called for requests answered with 404 Not Found
+	OnMiss func(r *http.Request, name string)
+}
+
+// WithStaticHooks sets callbacks for served files. The name passed to them is
+// the cleaned path of the requested file below the root, like "/css/app.css":
+//
+//	router.ServeFS("/assets/{filepath...}", assets, httpmux.WithStaticHooks(httpmux.StaticHooks{
+//		OnHit: func(r *http.Request, name string, bytes int64) {
+//			assetBytes.Add(bytes)
+//		},
+//		OnMiss: func(r *http.Request, name string) {
+//			slog.Warn("missing asset", "name", name)
+//		},
+//	}))
+func WithStaticHooks(hooks StaticHooks) StaticOption {
+	return func(c *staticConfig) {
+		c.hooks = &hooks
+	}
+}
+
+// Precompressed serves precompressed siblings of files, e.g. app.js.br or
+// app.js.gz for app.js, to clients accepting their encoding, so that large
+// assets need not be compressed per request. Brotli is preferred over gzip.
+// Responses carry a Vary: Accept-Encoding header and the content type of the
+// uncompressed file. Files without a sibling are served uncompressed.
+func Precompressed() StaticOption {
+	return func(c *staticConfig) {
+		c.precompressed = true
+	}
+}
+
+// precompressedEncodings are the content encodings served by Precompressed,
+// in order of preference, with the extension of their files.
+var precompressedEncodings = []struct {
+	name, ext string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// CachePolicy controls the caching headers of served files.
+type CachePolicy struct {
+	// Max age of files by extension including the dot, e.g. ".css"
+	MaxAge map[string]time.Duration
+
+	// Max age of files whose extension is not in MaxAge. If it is zero, no
+	// Cache-Control header is sent for them.
+	DefaultMaxAge time.Duration
+
+	// If enabled, fingerprinted files, whose name contains a hash of at least
+	// 8 hexadecimal digits like app.3f9a2c1b.js or app-3f9a2c1b.js, are
+	// cached for a year and marked as immutable.
+	Immutable bool
+
+	// If enabled, responses carry an ETag derived from the size and
+	// modification time of the file, so that clients can revalidate them
+	// with If-None-Match.
+	ETag bool
+}
+
+// WithCachePolicy sets the Cache-Control and ETag headers of served files
+// according to the policy:
+//
+//	router.ServeFS("/assets/{filepath...}", assets, httpmux.WithCachePolicy(httpmux.CachePolicy{
+//		MaxAge:    map[string]time.Duration{".html": 0, ".css": time.Hour},
+//		Immutable: true,
+//		ETag:      true,
+//	}))
+func WithCachePolicy(policy CachePolicy) StaticOption {
+	return func(c *staticConfig) {
+		c.cache = &policy
+	}
+}
+
+// fingerprinted matches names of files containing a content hash.
+var fingerprinted = regexp.MustCompile(`[.-][0-9a-fA-F]{8,}\.[^./]+$`)
+
+// setHeaders sets the caching headers for the file.
+func (p *CachePolicy) setHeaders(h http.Header, name string, info fs.FileInfo) {
+	if p.Immutable && fingerprinted.MatchString(name) {
+		h.Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else if maxAge, ok := p.MaxAge[path.Ext(name)]; ok || p.DefaultMaxAge > 0 {
+		if !ok {
+			maxAge = p.DefaultMaxAge
+		}
+		h.Set("Cache-Control", "public, max-age="+strconv.Itoa(int(maxAge.Seconds())))
+	}
+
+	if p.ETag {
+		h.Set("ETag", `"`+strconv.FormatInt(info.ModTime().UnixNano(), 16)+"-"+strconv.FormatInt(info.Size(), 16)+`"`)
+	}
+}
+
+// fileServer is a http.FileServer for root configured by StaticOptions.
+// It serves the file at the request path.
+type fileServer struct {
+	root    http.FileSystem
+	handler http.Handler
+	staticConfig
+
+	// File servers of the roots set by WithHostRoot
+	hosts         map[string]http.Handler
+	wildcardHosts []hostHandler
+}
+
+func newFileServer(root http.FileSystem, opts []StaticOption) *fileServer {
+	var c staticConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	hostRoots := c.hostRoots
+	c.hostRoots = nil
+
+	s := c.newFileServer(root)
+	for _, hr := range hostRoots {
+		host := strings.ToLower(hr.host)
+		suffix, wildcard := strings.CutPrefix(host, "*")
+		if host == "" || wildcard && (len(suffix) < 2 || suffix[0] != '.') {
+			panic("invalid static host '" + hr.host + "'")
+		}
+		registered := false
+		if wildcard {
+			registered = slices.ContainsFunc(s.wildcardHosts, func(h hostHandler) bool { return h.suffix == suffix })
+		} else {
+			_, registered = s.hosts[host]
+		}
+		if registered {
+			panic("static host '" + hr.host + "' is already registered")
+		}
+
+		if wildcard {
+			s.wildcardHosts = append(s.wildcardHosts, hostHandler{suffix: suffix, handler: c.newFileServer(hr.root)})
+			continue
+		}
+		if s.hosts == nil {
+			s.hosts = make(map[string]http.Handler)
+		}
+		s.hosts[host] = c.newFileServer(hr.root)
+	}
+	sort.SliceStable(s.wildcardHosts, func(i, j int) bool {
+		return len(s.wildcardHosts[i].suffix) > len(s.wildcardHosts[j].suffix)
+	})
+	return s
+}
+
+// newFileServer returns a file server for root with the configuration.
+func (c staticConfig) newFileServer(root http.FileSystem) *fileServer {
+	s := &fileServer{root: root, staticConfig: c}
+
+	if s.noSymlinkEscape {
+		dir, ok := root.(http.Dir)
+		if !ok {
+			panic("NoSymlinkEscape requires a http.Dir root")
+		}
+		if dir == "" {
+			dir = "."
+		}
+		osRoot, err := os.OpenRoot(string(dir))
+		if err != nil {
+			panic("cannot open root directory: " + err.Error())
+		}
+		s.root = http.FS(rootFS{osRoot.FS()})
+	}
+
+	s.handler = http.FileServer(s.root)
+	return s
+}
+
+func (s *fileServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if s.hosts != nil || s.wildcardHosts != nil {
+		if h := matchHost(requestHost(req), s.hosts, s.wildcardHosts); h != nil {
+			h.ServeHTTP(w, req)
+			return
+		}
+	}
+
+	if s.hooks == nil {
+		s.serve(w, req)
+		return
+	}
+	name := path.Clean("/" + req.URL.Path)
+	ww := WrapWriter(w)
+	s.serve(ww, req)
+	switch status := ww.Status(); {
+	case status == http.StatusNotFound:
+		if s.hooks.OnMiss != nil {
+			s.hooks.OnMiss(req, name)
+		}
+	case status < 400:
+		if s.hooks.OnHit != nil {
+			s.hooks.OnHit(req, name, ww.BytesWritten())
+		}
+	}
+}
+
+// serve serves the file at the request path from the root.
+func (s *fileServer) serve(w http.ResponseWriter, req *http.Request) {
+	// The file systems prevent traversal as well, but a request trying it is
+	// not worth a file system access
+	if invalidFilePath(req.URL.Path) {
+		writeError(w, req, "400 bad request", http.StatusBadRequest)
+		return
+	}
+
+	if !s.noDirectoryListing && s.cache == nil && !s.precompressed {
+		s.handler.ServeHTTP(w, req)
+		return
+	}
+
+	name := path.Clean("/" + req.URL.Path)
+	info := s.stat(name)
+	if info == nil {
+		s.handler.ServeHTTP(w, req)
+		return
+	}
+
+	if info.IsDir() {
+		if s.noDirectoryListing && s.stat(path.Join(name, "index.html")) == nil {
+			writeError(w, req, "404 page not found", http.StatusNotFound)
+			return
+		}
+		s.handler.ServeHTTP(w, req)
+		return
+	}
+
+	var variant http.File
+	var encoding string
+	if s.precompressed {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if variant, info, encoding = s.precompressedVariant(req, name, info); variant != nil {
+			defer variant.Close()
+		}
+	}
+
+	if s.cache != nil {
+		s.cache.setHeaders(w.Header(), name, info)
+	}
+
+	if variant == nil {
+		s.handler.ServeHTTP(w, req)
+		return
+	}
+	w.Header().Set("Content-Encoding", encoding)
+	w.Header().Set("Content-Type", s.contentType(name))
+	http.ServeContent(w, req, name, info.ModTime(), variant)
+}
+
+// precompressedVariant opens the preferred precompressed sibling of the named
+// file accepted by the client. It returns the file, its information and its
+// encoding, or nil and the given information if there is none.
+func (s *fileServer) precompressedVariant(req *http.Request, name string, info fs.FileInfo) (http.File, fs.FileInfo, string) {
+	accept := req.Header.Get("Accept-Encoding")
+	for _, enc := range precompressedEncodings {
+		if !acceptsEncoding(accept, enc.name) {
+			continue
+		}
+		f, err := s.root.Open(name + enc.ext)
+		if err != nil {
+			continue
+		}
+		if fi, err := f.Stat(); err == nil && !fi.IsDir() {
+			return f, fi, enc.name
+		}
+		f.Close()
+	}
+	return nil, info, ""
+}
+
+// contentType returns the content type of the named file, by its extension
+// or its content.
+func (s *fileServer) contentType(name string) string {
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		return ctype
+	}
+	f, err := s.root.Open(name)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer f.Close()
+	var buf [512]byte
+	n, _ := io.ReadFull(f, buf[:])
+	return http.DetectContentType(buf[:n])
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header value allows the
+// encoding. An explicit entry for the encoding takes precedence over "*".
+func acceptsEncoding(accept, encoding string) bool {
+	star := false
+	for _, part := range strings.Split(accept, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.TrimSpace(coding)
+		if !strings.EqualFold(coding, encoding) && coding != "*" {
+			continue
+		}
+
+		accepted := true
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				accepted = false
+			}
+		}
+		if coding != "*" {
+			return accepted
+		}
+		star = accepted
+	}
+	return star
+}
+
+// rootFS reports files which an os.Root refuses to open, e.g. because of a
+// symbolic link escaping the root, as not existing, so that they are answered
+// with 404 Not Found instead of 500 Internal Server Error.
+type rootFS struct {
+	fs.FS
+}
+
+func (f rootFS) Open(name string) (fs.File, error) {
+	file, err := f.FS.Open(name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, fs.ErrPermission) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return file, err
+}
+
+// invalidFilePath reports whether the decoded request path contains ".."
+// segments, backslashes or NUL bytes, which are never part of a valid request
+// for a served file. Encoded attempts like %2e%2e%2f are decoded by then.
+func invalidFilePath(p string) bool {
+	if strings.ContainsAny(p, "\\\x00") {
+		return true
+	}
+	for seg := range strings.SplitSeq(p, "/") {
+		if seg == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// stat returns information about the named file, or nil if it cannot be
+// opened.
+func (s *fileServer) stat(name string) fs.FileInfo {
+	f, err := s.root.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil
+	}
+	return info
+}
+
+// ServeSPA serves a single-page application from the given file system below
+// the prefix. Existing files are served as by ServeFS. Other paths are
+// answered with the file system's index.html, so that the application can
+// route them on the client side, unless their last segment has a file
+// extension: missing assets like /app/main.js are answered with 404 Not
+// Found instead of the index page.
+//
+//	router.ServeSPA("/app", dist)
+func (r *Router) ServeSPA(prefix string, fsys fs.FS) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	fileServer := http.FileServerFS(fsys)
+
+	r.GET(prefix+"/{filepath...}", func(w http.ResponseWriter, req *http.Request) {
+		if invalidFilePath(req.PathValue("filepath")) {
+			writeError(w, req, "400 bad request", http.StatusBadRequest)
+			return
+		}
+		name := strings.TrimPrefix(path.Clean(req.PathValue("filepath")), "/")
+		if name != "" {
+			if info, err := fs.Stat(fsys, name); err == nil && !info.IsDir() {
+				req.URL.Path = "/" + name
+				fileServer.ServeHTTP(w, req)
+				return
+			}
+			if path.Ext(name) != "" {
+				writeError(w, req, "404 page not found", http.StatusNotFound)
+				return
+			}
+		}
+		http.ServeFileFS(w, req, fsys, "index.html")
+	})
+}
+
+// ServeDownload serves the named file of fsys as an attachment, which
+// browsers save instead of displaying it. The client is offered downloadName
+// as the file name, or the base name of the file if it is empty. Non-ASCII
+// names are encoded as defined by RFC 2231.
+//
+// The content type is derived from the extension of the file name or sniffed
+// from its content. Range and conditional requests are supported if the file
+// implements io.Seeker, as the files of os.DirFS, embed.FS and fstest.MapFS
+// do. Missing files are answered with 404 Not Found:
+//
+//	router.GET("/invoices/{id}", func(w http.ResponseWriter, r *http.Request) {
+//		id := r.PathValue("id")
+//		httpmux.ServeDownload(w, r, invoices, id+".pdf", "invoice-"+id+".pdf")
+//	})
+func ServeDownload(w http.ResponseWriter, r *http.Request, fsys fs.FS, name, downloadName string) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		serveFileError(w, r, err)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		serveFileError(w, r, err)
+		return
+	}
+	if info.IsDir() {
+		writeError(w, r, "404 page not found", http.StatusNotFound)
+		return
+	}
+
+	if downloadName == "" {
+		downloadName = path.Base(name)
+	}
+	h := w.Header()
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": downloadName})
+	if disposition == "" {
+		// The name cannot be encoded, let the client choose one
+		disposition = "attachment"
+	}
+	h.Set("Content-Disposition", disposition)
+	h.Set("X-Content-Type-Options", "nosniff")
+
+	if rs, ok := f.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, downloadName, info.ModTime(), rs)
+		return
+	}
+
+	// Without seeking, the content type can only be sniffed from the start
+	// of the file, and ranges cannot be served
+	ctype := mime.TypeByExtension(path.Ext(downloadName))
+	var buf [512]byte
+	n, _ := io.ReadFull(f, buf[:])
+	if ctype == "" {
+		ctype = http.DetectContentType(buf[:n])
+	}
+	h.Set("Content-Type", ctype)
+	h.Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		w.Write(buf[:n])
+		io.Copy(w, f)
+	}
+}
+
+// serveFileError answers a request for a file which could not be opened.
+func serveFileError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, fs.ErrInvalid):
+		writeError(w, r, "404 page not found", http.StatusNotFound)
+	case errors.Is(err, fs.ErrPermission):
+		writeError(w, r, "403 Forbidden", http.StatusForbidden)
+	default:
+		writeError(w, r, "500 Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,441 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestRouterServeFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":          {Data: []byte("console.log(1)")},
+		"css/style.css":   {Data: []byte("body{}")},
+		"docs/index.html": {Data: []byte("<h1>docs</h1>")},
+	}
+	router := New()
+	router.ServeFS("/assets/{filepath...}", fsys)
+
+	tests := []struct {
+		path, body string
+		code       int
+	}{
+		{"/assets/app.js", "console.log(1)", http.StatusOK},
+		{"/assets/css/style.css", "body{}", http.StatusOK},
+		{"/assets/docs/", "<h1>docs</h1>", http.StatusOK},
+		{"/assets/missing.js", "404 page not found\n", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.code || w.Body.String() != tt.body {
+			t.Errorf("%s: expected %d %q, got %d %q", tt.path, tt.code, tt.body, w.Code, w.Body.String())
+		}
+	}
+
+	if recv := catchPanic(func() { router.ServeFS("/static", fsys) }); recv == nil {
+		t.Error("registering path not ending with /{filepath...} did not panic")
+	}
+}
+
+func TestRouterServeSPA(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":    {Data: []byte("<div id=app>")},
+		"assets/app.js": {Data: []byte("mount()")},
+		"docs/a.txt":    {Data: []byte("a")},
+	}
+	router := New()
+	router.GET("/api/users", dummyHandler)
+	router.ServeSPA("/app/", fsys)
+
+	tests := []struct {
+		path, body string
+		code       int
+	}{
+		{"/app/", "<div id=app>", http.StatusOK},
+		{"/app/dashboard/settings", "<div id=app>", http.StatusOK},
+		{"/app/docs", "<div id=app>", http.StatusOK},
+		{"/app/assets/app.js", "mount()", http.StatusOK},
+		{"/app/assets/../assets/app.js", "", http.StatusBadRequest},
+		{"/app/assets/missing.js", "404 page not found\n", http.StatusNotFound},
+		{"/app/index.html", "", http.StatusMovedPermanently},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.URL.Path = tt.path
+		router.ServeHTTP(w, req)
+		if w.Code != tt.code || tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s: expected %d %q, got %d %q", tt.path, tt.code, tt.body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRouterServeFSNoDirectoryListing(t *testing.T) {
+	fsys := fstest.MapFS{
+		"private/secret.txt": {Data: []byte("secret")},
+		"docs/index.html":    {Data: []byte("<h1>docs</h1>")},
+	}
+	router := New()
+	router.ServeFS("/listed/{filepath...}", fsys)
+	router.ServeFS("/static/{filepath...}", fsys, NoDirectoryListing())
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/listed/private/", http.StatusOK},
+		{"/static/private/", http.StatusNotFound},
+		{"/static/private", http.StatusNotFound},
+		{"/static/", http.StatusNotFound},
+		{"/static/private/secret.txt", http.StatusOK},
+		{"/static/docs/", http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.code {
+			t.Errorf("%s: expected %d, got %d", tt.path, tt.code, w.Code)
+		}
+	}
+}
+
+func TestRouterServeFSCachePolicy(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"index.html":          {Data: []byte("<html>"), ModTime: modTime},
+		"app.3f9a2c1b.js":     {Data: []byte("app()"), ModTime: modTime},
+		"vendor-0badc0de.css": {Data: []byte("body{}"), ModTime: modTime},
+		"style.css":           {Data: []byte("p{}"), ModTime: modTime},
+		"logo.png":            {Data: []byte("png"), ModTime: modTime},
+	}
+	router := New()
+	router.ServeFS("/assets/{filepath...}", fsys, WithCachePolicy(CachePolicy{
+		MaxAge:        map[string]time.Duration{".html": 0, ".css": time.Hour},
+		DefaultMaxAge: time.Minute,
+		Immutable:     true,
+		ETag:          true,
+	}))
+	router.ServeFS("/plain/{filepath...}", fsys, WithCachePolicy(CachePolicy{
+		MaxAge: map[string]time.Duration{".css": time.Hour},
+	}))
+
+	tests := []struct {
+		path, cacheControl string
+	}{
+		{"/assets/index.html", "public, max-age=0"},
+		{"/assets/app.3f9a2c1b.js", "public, max-age=31536000, immutable"},
+		{"/assets/vendor-0badc0de.css", "public, max-age=31536000, immutable"},
+		{"/assets/style.css", "public, max-age=3600"},
+		{"/assets/logo.png", "public, max-age=60"},
+		{"/assets/missing.png", ""},
+		{"/plain/logo.png", ""},
+		{"/plain/app.3f9a2c1b.js", ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if got := w.Header().Get("Cache-Control"); got != tt.cacheControl {
+			t.Errorf("%s: expected Cache-Control %q, got %q", tt.path, tt.cacheControl, got)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/assets/style.css", nil))
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected ETag")
+	}
+	req := httptest.NewRequest(http.MethodGet, "/assets/style.css", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for matching ETag, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/plain/style.css", nil))
+	if w.Header().Get("ETag") != "" {
+		t.Error("expected no ETag if disabled")
+	}
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	tests := []struct {
+		accept, encoding string
+		want             bool
+	}{
+		{"gzip, deflate, br", "br", true},
+		{"gzip, deflate, br", "gzip", true},
+		{"deflate", "gzip", false},
+		{"", "gzip", false},
+		{"GZIP;q=0.5", "gzip", true},
+		{"gzip;q=0", "gzip", false},
+		{"*", "br", true},
+		{"*;q=0, gzip", "gzip", true},
+		{"gzip;q=0, *", "gzip", false},
+	}
+	for _, tt := range tests {
+		if got := acceptsEncoding(tt.accept, tt.encoding); got != tt.want {
+			t.Errorf("acceptsEncoding(%q, %q) = %v, expected %v", tt.accept, tt.encoding, got, tt.want)
+		}
+	}
+}
+
+func TestRouterServeFSPrecompressed(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":      {Data: []byte("uncompressed")},
+		"app.js.gz":   {Data: []byte("gzipped")},
+		"app.js.br":   {Data: []byte("brotli")},
+		"style.css":   {Data: []byte("p{}")},
+		"data":        {Data: []byte("<html>plain")},
+		"data.gz":     {Data: []byte("gzipped data")},
+		"dir/a.txt":   {Data: []byte("a")},
+		"dir.gz/x.js": {Data: []byte("x")},
+	}
+	router := New()
+	router.ServeFS("/assets/{filepath...}", fsys, Precompressed(), WithCachePolicy(CachePolicy{ETag: true}))
+
+	tests := []struct {
+		path, accept, body, encoding, ctype string
+	}{
+		{"/assets/app.js", "gzip, br", "brotli", "br", "text/javascript; charset=utf-8"},
+		{"/assets/app.js", "gzip", "gzipped", "gzip", "text/javascript; charset=utf-8"},
+		{"/assets/app.js", "br;q=0, gzip", "gzipped", "gzip", "text/javascript; charset=utf-8"},
+		{"/assets/app.js", "", "uncompressed", "", "text/javascript; charset=utf-8"},
+		{"/assets/style.css", "gzip, br", "p{}", "", "text/css; charset=utf-8"},
+		{"/assets/data", "gzip", "gzipped data", "gzip", "text/html; charset=utf-8"},
+	}
+	etags := make(map[string]string)
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept-Encoding", tt.accept)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		h := w.Header()
+		if w.Body.String() != tt.body || h.Get("Content-Encoding") != tt.encoding || h.Get("Content-Type") != tt.ctype {
+			t.Errorf("%s (%s): got %q, encoding %q, type %q", tt.path, tt.accept, w.Body.String(), h.Get("Content-Encoding"), h.Get("Content-Type"))
+		}
+		if h.Get("Vary") != "Accept-Encoding" {
+			t.Errorf("%s (%s): expected Vary header, got %q", tt.path, tt.accept, h.Get("Vary"))
+		}
+		etags[tt.path+" "+tt.encoding] = h.Get("ETag")
+	}
+	if etags["/assets/app.js br"] == etags["/assets/app.js gzip"] || etags["/assets/app.js gzip"] == etags["/assets/app.js "] {
+		t.Errorf("expected distinct ETags per encoding, got %v", etags)
+	}
+}
+
+func TestRouterServeFilesTraversal(t *testing.T) {
+	fsys := fstest.MapFS{"app.js": {Data: []byte("app()")}}
+	router := New()
+	router.ServeFS("/assets/{filepath...}", fsys)
+
+	for _, target := range []string{
+		"/assets/../secret",
+		"/assets/..%2fsecret",
+		"/assets/%2e%2e/secret",
+		"/assets/a/%2e%2e%2f%2e%2e%2fsecret",
+		"/assets/..%5csecret",
+		"/assets/app.js%00",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		u, err := url.Parse(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.URL = u
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, w.Code)
+		}
+	}
+}
+
+func TestRouterServeFilesNoSymlinkEscape(t *testing.T) {
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "public.txt"), []byte("public"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(root, "secret.txt")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	if err := os.Symlink("public.txt", filepath.Join(root, "alias.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	router := New()
+	router.ServeFiles("/open/{filepath...}", http.Dir(root))
+	router.ServeFiles("/safe/{filepath...}", http.Dir(root), NoSymlinkEscape())
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/open/secret.txt", http.StatusOK},
+		{"/safe/secret.txt", http.StatusNotFound},
+		{"/safe/public.txt", http.StatusOK},
+		{"/safe/alias.txt", http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.code {
+			t.Errorf("%s: expected %d, got %d", tt.path, tt.code, w.Code)
+		}
+	}
+
+	if recv := catchPanic(func() { router.ServeFS("/fs/{filepath...}", fstest.MapFS{}, NoSymlinkEscape()) }); recv == nil {
+		t.Error("expected panic for root other than http.Dir")
+	}
+}
+
+// unseekableFS hides the Seek method of the files of a fs.FS.
+type unseekableFS struct {
+	fs.FS
+}
+
+func (f unseekableFS) Open(name string) (fs.File, error) {
+	file, err := f.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return struct{ fs.File }{file}, nil
+}
+
+func TestServeDownload(t *testing.T) {
+	fsys := fstest.MapFS{
+		"reports/2024.csv": {Data: []byte("a,b\n1,2\n")},
+		"blob":             {Data: []byte("%PDF-1.7 document")},
+		"dir/x":            {Data: []byte("x")},
+	}
+
+	serve := func(fsys fs.FS, name, downloadName, rangeHeader string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/download", nil)
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		w := httptest.NewRecorder()
+		ServeDownload(w, req, fsys, name, downloadName)
+		return w
+	}
+
+	w := serve(fsys, "reports/2024.csv", "", "")
+	if w.Code != http.StatusOK || w.Body.String() != "a,b\n1,2\n" ||
+		w.Header().Get("Content-Disposition") != `attachment; filename=2024.csv` ||
+		w.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+		t.Errorf("unexpected response %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	w = serve(fsys, "blob", "Bericht März.pdf", "bytes=0-3")
+	if w.Code != http.StatusPartialContent || w.Body.String() != "%PDF" ||
+		w.Header().Get("Content-Disposition") != `attachment; filename*=utf-8''Bericht%20M%C3%A4rz.pdf` {
+		t.Errorf("unexpected range response %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	for _, name := range []string{"a\xffb.pdf", "line\nbreak.pdf", "\x00"} {
+		if cd := serve(fsys, "blob", name, "").Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment") {
+			t.Errorf("%q: unexpected Content-Disposition %q", name, cd)
+		}
+	}
+
+	w = serve(unseekableFS{fsys}, "blob", "", "bytes=0-3")
+	if w.Code != http.StatusOK || w.Body.String() != "%PDF-1.7 document" || w.Header().Get("Content-Type") != "application/pdf" {
+		t.Errorf("unexpected unseekable response %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	for _, name := range []string{"missing.csv", "dir", "../etc/passwd"} {
+		if w := serve(fsys, name, "", ""); w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", name, w.Code)
+		}
+	}
+}
+
+func TestRouterServeFSHostRoot(t *testing.T) {
+	router := New()
+	router.ServeFS("/assets/{filepath...}", fstest.MapFS{"logo.svg": {Data: []byte("default")}},
+		WithHostRoot("shop.example.com", http.FS(fstest.MapFS{"logo.svg": {Data: []byte("shop")}})),
+		WithHostRoot("*.blog.example.com", http.FS(fstest.MapFS{"logo.svg": {Data: []byte("blog")}})),
+		NoDirectoryListing())
+
+	tests := []struct {
+		host, body string
+	}{
+		{"shop.example.com", "shop"},
+		{"SHOP.example.com:8080", "shop"},
+		{"alice.blog.example.com", "blog"},
+		{"blog.example.com", "default"},
+		{"example.com", "default"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/assets/logo.svg", nil)
+		req.Host = tt.host
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Body.String() != tt.body {
+			t.Errorf("%s: expected %q, got %d %q", tt.host, tt.body, w.Code, w.Body.String())
+		}
+	}
+
+	for _, opts := range [][]StaticOption{
+		{WithHostRoot("a.example.com", http.Dir(".")), WithHostRoot("A.example.com", http.Dir("."))},
+		{WithHostRoot("*.example.com", http.Dir(".")), WithHostRoot("*.example.com", http.Dir("."))},
+		{WithHostRoot("*example.com", http.Dir("."))},
+		{WithHostRoot("", http.Dir("."))},
+	} {
+		recv := catchPanic(func() {
+			New().ServeFiles("/{filepath...}", http.Dir("."), opts...)
+		})
+		if recv == nil {
+			t.Errorf("expected panic for invalid or duplicate host roots")
+		}
+	}
+}
+
+func TestRouterServeFSHooks(t *testing.T) {
+	var hits, misses []string
+	var served int64
+	router := New()
+	router.ServeFS("/assets/{filepath...}", fstest.MapFS{"app.js": {Data: []byte("console.log(1)")}},
+		WithStaticHooks(StaticHooks{
+			OnHit: func(r *http.Request, name string, bytes int64) {
+				hits = append(hits, name)
+				served += bytes
+			},
+			OnMiss: func(r *http.Request, name string) {
+				misses = append(misses, name)
+			},
+		}))
+
+	for _, p := range []string{"/assets/app.js", "/assets/missing.js", "/assets/app.js", "/assets/../x"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, p, nil))
+	}
+	req := httptest.NewRequest(http.MethodGet, "/assets/app.js", nil)
+	req.Header.Set("Range", "bytes=0-6")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(hits) != 3 || hits[0] != "/app.js" || served != 2*14+7 {
+		t.Errorf("unexpected hits %v with %d bytes", hits, served)
+	}
+	if len(misses) != 1 || misses[0] != "/missing.js" {
+		t.Errorf("unexpected misses %v", misses)
+	}
+}
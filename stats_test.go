@@ -0,0 +1,87 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRouterPublishExpvar(t *testing.T) {
+	router := New()
+	router.PanicHandler = func(_ http.ResponseWriter, _ *http.Request, _ interface{}) {}
+	router.GET("/uncounted", func(_ http.ResponseWriter, _ *http.Request) {})
+	router.PublishExpvar("httpmux_test_routes")
+	router.GET("/user/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("name") == "nobody" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	router.POST("/panic", func(_ http.ResponseWriter, _ *http.Request) {
+		panic("oops!")
+	})
+
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, "/uncounted"},
+		{http.MethodGet, "/user/gopher"},
+		{http.MethodGet, "/user/nobody"},
+		{http.MethodPost, "/panic"},
+		{http.MethodGet, "/nope"},
+	} {
+		r, _ := http.NewRequest(req.method, req.path, nil)
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	var got map[string]map[string]uint64
+	if err := json.Unmarshal([]byte(expvar.Get("httpmux_test_routes").String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]uint64{
+		"GET /user/{name}": {"hits": 2, "errors_4xx": 1, "errors_5xx": 0},
+		"POST /panic":      {"hits": 1, "errors_4xx": 0, "errors_5xx": 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected stats: want %v, got %v", want, got)
+	}
+}
+
+func TestRouterStats(t *testing.T) {
+	router := New()
+	if stats := router.Stats(); stats != nil {
+		t.Errorf("expected nil stats when disabled, got %v", stats)
+	}
+
+	router.EnableStats()
+	router.GET("/a", func(_ http.ResponseWriter, _ *http.Request) {})
+	router.POST("/a", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	router.GET("/b/{id}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, "/a"},
+		{http.MethodGet, "/a"},
+		{http.MethodPost, "/a"},
+		{http.MethodGet, "/b/1"},
+	} {
+		r, _ := http.NewRequest(req.method, req.path, nil)
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	want := []RouteStats{
+		{Method: http.MethodGet, Path: "/a", Hits: 2},
+		{Method: http.MethodPost, Path: "/a", Hits: 1, ServerErrors: 1},
+		{Method: http.MethodGet, Path: "/b/{id}", Hits: 1, ClientErrors: 1},
+	}
+	if got := router.Stats(); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected stats: want %v, got %v", want, got)
+	}
+}
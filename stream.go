@@ -0,0 +1,183 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// StreamOption configures a response streamed by Stream.
+type StreamOption func(*StreamWriter)
+
+// WithFlushInterval flushes written data at most once per interval instead of
+// after every write, which saves packets for streams of many small writes.
+// Data is still flushed within the interval if the stream goes idle.
+func WithFlushInterval(d time.Duration) StreamOption {
+	return func(s *StreamWriter) {
+		s.interval = d
+	}
+}
+
+// StreamWriter writes the body of a response streamed by Stream. It is safe
+// for concurrent use.
+type StreamWriter struct {
+	ctx      context.Context
+	w        http.ResponseWriter
+	rc       *http.ResponseController
+	interval time.Duration
+
+	mu    sync.Mutex
+	dirty bool  // Whether data was written since the last flush
+	err   error // First error writing to the client
+}
+
+// Context returns the context of the request, which is canceled when the
+// client disconnects.
+func (s *StreamWriter) Context() context.Context {
+	return s.ctx
+}
+
+// Write writes data to the client. Unless a flush interval is set, the data
+// is flushed immediately. Once the client disconnected or a write failed,
+// Write returns an error.
+func (s *StreamWriter) Write(p []byte) (int, error) {
+	if err := s.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return 0, s.err
+	}
+	n, err := s.w.Write(p)
+	if err != nil {
+		s.err = err
+		return n, err
+	}
+	if s.interval > 0 {
+		s.dirty = true
+		return n, nil
+	}
+	return n, s.flush()
+}
+
+// WriteJSON writes v as a single line of JSON, as used by NDJSON streams.
+func (s *StreamWriter) WriteJSON(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = s.Write(append(b, '\n'))
+	return err
+}
+
+// Flush sends all written data to the client.
+func (s *StreamWriter) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	return s.flush()
+}
+
+// flush flushes the response. s.mu must be held.
+func (s *StreamWriter) flush() error {
+	s.dirty = false
+	if err := s.rc.Flush(); err != nil {
+		s.err = err
+	}
+	return s.err
+}
+
+// Stream streams the response body written by fn to the client, e.g. for
+// long polling or NDJSON endpoints:
+//
+//	router.GET("/events", func(w http.ResponseWriter, r *http.Request) {
+//		w.Header().Set("Content-Type", "application/x-ndjson")
+//		httpmux.Stream(w, r, func(s *httpmux.StreamWriter) error {
+//			for {
+//				select {
+//				case ev := <-events:
+//					if err := s.WriteJSON(ev); err != nil {
+//						return err
+//					}
+//				case <-s.Context().Done():
+//					return nil
+//				}
+//			}
+//		})
+//	})
+//
+// Stream sends the response header with status 200 before calling fn, so
+// headers must be set beforehand. It removes any Content-Length header, so
+// that HTTP/1.1 responses use chunked encoding, disables caching and proxy
+// buffering unless a Cache-Control header was set, and clears the write
+// deadline of the connection, so that the server's WriteTimeout does not cut
+// off the stream.
+//
+// Stream returns the error of fn. If the ResponseWriter cannot flush, e.g.
+// within a group with a timeout, the request is answered with 500 Internal
+// Server Error and an error is returned without calling fn.
+func Stream(w http.ResponseWriter, r *http.Request, fn func(*StreamWriter) error, opts ...StreamOption) error {
+	s := &StreamWriter{ctx: r.Context(), w: w, rc: http.NewResponseController(w)}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	h := w.Header()
+	h.Del("Content-Length")
+	if h.Get("Cache-Control") == "" {
+		h.Set("Cache-Control", "no-cache")
+		h.Set("X-Accel-Buffering", "no")
+	}
+	s.rc.SetWriteDeadline(time.Time{})
+
+	// Flushing sends the header, so that clients see the response start
+	if err := s.rc.Flush(); err != nil {
+		writeError(w, r, "500 internal server error", http.StatusInternalServerError)
+		return fmt.Errorf("httpmux: cannot stream response: %w", err)
+	}
+
+	if s.interval > 0 {
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(s.interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					s.mu.Lock()
+					if s.dirty && s.err == nil {
+						s.flush()
+					}
+					s.mu.Unlock()
+				case <-done:
+					return
+				}
+			}
+		}()
+		defer func() {
+			close(done)
+			wg.Wait()
+			s.mu.Lock()
+			if s.dirty && s.err == nil {
+				s.flush()
+			}
+			s.mu.Unlock()
+		}()
+	}
+
+	return fn(s)
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flushCounter counts the flushes of a ResponseRecorder.
+type flushCounter struct {
+	*httptest.ResponseRecorder
+	flushes atomic.Int32
+}
+
+func (w *flushCounter) Flush() {
+	w.flushes.Add(1)
+	w.ResponseRecorder.Flush()
+}
+
+func TestStream(t *testing.T) {
+	w := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+	w.Header().Set("Content-Length", "10")
+	err := Stream(w, httptest.NewRequest(http.MethodGet, "/", nil), func(s *StreamWriter) error {
+		if err := s.WriteJSON(map[string]int{"n": 1}); err != nil {
+			return err
+		}
+		_, err := io.WriteString(s, "done\n")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Body.String() != "{\"n\":1}\ndone\n" || w.Code != http.StatusOK {
+		t.Errorf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Length") != "" || w.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("unexpected headers %v", w.Header())
+	}
+	// Header and both writes
+	if n := w.flushes.Load(); n != 3 {
+		t.Errorf("expected 3 flushes, got %d", n)
+	}
+}
+
+func TestStreamFlushInterval(t *testing.T) {
+	w := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+	err := Stream(w, httptest.NewRequest(http.MethodGet, "/", nil), func(s *StreamWriter) error {
+		for range 100 {
+			io.WriteString(s, "x")
+		}
+		// Written data is flushed while the stream is idle
+		deadline := time.Now().Add(time.Second)
+		for w.flushes.Load() < 2 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		io.WriteString(s, "y")
+		return nil
+	}, WithFlushInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := w.flushes.Load(); n < 3 || n > 10 {
+		t.Errorf("unexpected number of flushes %d", n)
+	}
+	if len(w.Body.String()) != 101 {
+		t.Errorf("unexpected body %q", w.Body.String())
+	}
+}
+
+func TestStreamCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	err := Stream(httptest.NewRecorder(), req, func(s *StreamWriter) error {
+		cancel()
+		_, err := s.Write([]byte("x"))
+		return err
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestStreamOverHTTP(t *testing.T) {
+	release := make(chan struct{})
+	router := New()
+	router.GET("/events", func(w http.ResponseWriter, r *http.Request) {
+		Stream(w, r, func(s *StreamWriter) error {
+			s.WriteJSON("first")
+			<-release
+			return s.WriteJSON("second")
+		})
+	})
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if len(res.TransferEncoding) != 1 || res.TransferEncoding[0] != "chunked" {
+		t.Errorf("expected chunked encoding, got %v", res.TransferEncoding)
+	}
+
+	// The first line arrives before the handler returns
+	br := bufio.NewReader(res.Body)
+	if line, err := br.ReadString('\n'); err != nil || line != "\"first\"\n" {
+		t.Errorf("unexpected first line %q, %v", line, err)
+	}
+	close(release)
+	if line, err := br.ReadString('\n'); err != nil || line != "\"second\"\n" {
+		t.Errorf("unexpected second line %q, %v", line, err)
+	}
+}
+
+func TestStreamNotSupported(t *testing.T) {
+	router := New()
+	var streamErr error
+	router.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		streamErr = Stream(w, r, func(s *StreamWriter) error {
+			t.Error("fn must not be called")
+			return nil
+		})
+	})
+	multi := NewMultiRouter()
+	multi.Group("/slow", router, WithTimeout(time.Second))
+
+	w := httptest.NewRecorder()
+	multi.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow/", nil))
+	if w.Code != http.StatusInternalServerError || !errors.Is(streamErr, http.ErrNotSupported) {
+		t.Errorf("expected 500 and ErrNotSupported, got %d %v", w.Code, streamErr)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// RouteMethod marks a field of a struct registered by Router.HandleStruct whose
+// route is served by a method of the struct.
+type RouteMethod struct{}
+
+var (
+	routeMethodType = reflect.TypeFor[RouteMethod]()
+	handlerType     = reflect.TypeFor[http.Handler]()
+	handlerFuncType = reflect.TypeFor[func(http.ResponseWriter, *http.Request)]()
+)
+
+// HandleStruct registers the routes declared by the `route` tags of the
+// fields of the struct v points to, so that routes are defined next to their
+// handlers:
+//
+//	type UserAPI struct {
+//		db *sql.DB
+//
+//		show   httpmux.RouteMethod `route:"GET /users/{id}"`
+//		create httpmux.RouteMethod `route:"POST /users"`
+//		Health http.HandlerFunc    `route:"GET /healthz"`
+//	}
+//
+//	func (api *UserAPI) Show(w http.ResponseWriter, req *http.Request)   { ... }
+//	func (api *UserAPI) Create(w http.ResponseWriter, req *http.Request) { ... }
+//
+//	router.HandleStruct(&UserAPI{db: db, Health: health})
+//
+// A field of type RouteMethod is served by the method named like the field
+// with an upper case first letter, which must be a handler function. Other
+// tagged fields must be exported handler functions or http.Handlers. The
+// struct is walked once, so fields assigned later have no effect.
+// HandleStruct panics if a tag is malformed or a handler is missing, like any
+// other invalid route registration.
+func (r *Router) HandleStruct(v any) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		panic("HandleStruct requires a pointer to a struct, got " + rv.Type().String())
+	}
+	st := rv.Elem().Type()
+
+	for i := range st.NumField() {
+		f := st.Field(i)
+		tag, ok := f.Tag.Lookup("route")
+		if !ok {
+			continue
+		}
+		where := st.Name() + "." + f.Name
+		method, path, ok := strings.Cut(strings.TrimSpace(tag), " ")
+		path = strings.TrimSpace(path)
+		if !ok || method == "" || path == "" {
+			panic("invalid route tag '" + tag + "' on " + where + ", expected \"METHOD /path\"")
+		}
+
+		var handler http.Handler
+		switch {
+		case f.Type == routeMethodType:
+			first, size := utf8.DecodeRuneInString(f.Name)
+			name := string(unicode.ToUpper(first)) + f.Name[size:]
+			m := rv.MethodByName(name)
+			if !m.IsValid() || !m.Type().ConvertibleTo(handlerFuncType) {
+				panic("route " + where + " requires a method " + name + "(http.ResponseWriter, *http.Request)")
+			}
+			handler = http.HandlerFunc(m.Convert(handlerFuncType).Interface().(func(http.ResponseWriter, *http.Request)))
+
+		case !f.IsExported():
+			panic("route " + where + " must be exported or of type httpmux.RouteMethod")
+
+		case f.Type.ConvertibleTo(handlerFuncType):
+			fn := rv.Elem().Field(i).Convert(handlerFuncType).Interface().(func(http.ResponseWriter, *http.Request))
+			if fn == nil {
+				panic("route " + where + " has no handler")
+			}
+			handler = http.HandlerFunc(fn)
+
+		case f.Type.Implements(handlerType):
+			fv := rv.Elem().Field(i)
+			if (fv.Kind() == reflect.Interface || fv.Kind() == reflect.Pointer) && fv.IsNil() {
+				panic("route " + where + " has no handler")
+			}
+			handler = fv.Interface().(http.Handler)
+
+		default:
+			panic("route " + where + " must be a handler function, an http.Handler or of type httpmux.RouteMethod")
+		}
+		r.Handle(method, path, handler)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type structAPI struct {
+	greeting string
+
+	show   RouteMethod      `route:"GET /users/{id}"`
+	create RouteMethod      `route:"POST /users"`
+	Health http.HandlerFunc `route:"GET /healthz"`
+	Files  http.Handler     `route:"GET /files/{path...}"`
+	Other  string
+}
+
+func (api *structAPI) Show(w http.ResponseWriter, req *http.Request) {
+	w.Write([]byte(api.greeting + " " + req.PathValue("id")))
+}
+
+func (api *structAPI) Create(w http.ResponseWriter, req *http.Request) {
+	w.WriteHeader(http.StatusCreated)
+}
+
+func TestRouterHandleStruct(t *testing.T) {
+	router := New()
+	router.HandleStruct(&structAPI{
+		greeting: "hello",
+		Health: func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte("ok"))
+		},
+		Files: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(req.PathValue("path")))
+		}),
+	})
+
+	for _, tt := range []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{http.MethodGet, "/users/7", http.StatusOK, "hello 7"},
+		{http.MethodPost, "/users", http.StatusCreated, ""},
+		{http.MethodGet, "/healthz", http.StatusOK, "ok"},
+		{http.MethodGet, "/files/a/b", http.StatusOK, "/a/b"},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.code || w.Body.String() != tt.body {
+			t.Errorf("%s %s: expected %d %q, got %d %q", tt.method, tt.path, tt.code, tt.body, w.Code, w.Body.String())
+		}
+	}
+}
+
+type missingMethodAPI struct {
+	list RouteMethod `route:"GET /items"`
+}
+
+type badTagAPI struct {
+	Show http.HandlerFunc `route:"/users"`
+}
+
+type nilHandlerAPI struct {
+	Show http.HandlerFunc `route:"GET /users"`
+}
+
+type unexportedAPI struct {
+	show http.HandlerFunc `route:"GET /users"`
+}
+
+type badTypeAPI struct {
+	Show int `route:"GET /users"`
+}
+
+func TestRouterHandleStructPanics(t *testing.T) {
+	for _, tt := range []struct {
+		v    any
+		want string
+	}{
+		{structAPI{}, "requires a pointer to a struct"},
+		{&missingMethodAPI{}, "requires a method List"},
+		{&badTagAPI{Show: func(http.ResponseWriter, *http.Request) {}}, "invalid route tag"},
+		{&nilHandlerAPI{}, "has no handler"},
+		{&unexportedAPI{}, "must be exported"},
+		{&badTypeAPI{}, "must be a handler function"},
+	} {
+		recv := catchPanic(func() {
+			New().HandleStruct(tt.v)
+		})
+		if msg, _ := recv.(string); !strings.Contains(msg, tt.want) {
+			t.Errorf("%T: expected a panic containing %q, got %v", tt.v, tt.want, recv)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"slices"
+	"sort"
+)
+
+// Tags tags the route, e.g. as "public", "internal" or "deprecated", to select
+// subsets of the routes with Routes and MultiRouter.AllRoutes:
+//
+//	router.GET("/users/{id}", showUser, httpmux.Tags("public"))
+//	router.GET("/debug/cache", dumpCache, httpmux.Tags("internal"))
+//
+// Routes of a MultiRouter group also have the tags of the group, see
+// WithGroupTags. OpenAPI lists the tags of routes not tagged by Describe.
+func Tags(tags ...string) RouteOption {
+	return func(o *routeOptions) {
+		o.tags = mergeTags(o.tags, tags)
+	}
+}
+
+// WithGroupTags tags all routes of the group, in addition to their own tags.
+func WithGroupTags(tags ...string) GroupOption {
+	return func(g *group) {
+		g.tags = mergeTags(g.tags, tags)
+	}
+}
+
+// mergeTags returns the sorted union of the tags, without duplicates.
+func mergeTags(a, b []string) []string {
+	if len(b) == 0 {
+		return a
+	}
+	tags := append(slices.Clone(a), b...)
+	slices.Sort(tags)
+	return slices.Compact(tags)
+}
+
+// hasTags reports whether all of the wanted tags are in the sorted tags.
+func hasTags(tags, wanted []string) bool {
+	for _, tag := range wanted {
+		if _, found := slices.BinarySearch(tags, tag); !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Routes returns the routes of the router having all of the tags, all routes
+// if no tags are given, sorted by path and method:
+//
+//	for _, route := range router.Routes("public") {
+//		fmt.Println(route.Method, route.Path)
+//	}
+func (r *Router) Routes(tags ...string) []Route {
+	routes := r.collectRoutes(nil, "", nil, tags, nil)
+	sortRoutes(routes)
+	return routes
+}
+
+// RouteTags returns the tags of the route with the method and registered
+// path, or nil. The returned slice must not be modified.
+func (r *Router) RouteTags(method, path string) []string {
+	root := r.trees.Load().get(method)
+	if root == nil {
+		return nil
+	}
+	var tags []string
+	root.walkRoutes(func(n *node) {
+		if n.fullPath == path {
+			tags = routeOptionsOf(n.handle).tags
+		}
+	})
+	return tags
+}
+
+// collectRoutes appends the routes of the router having all of the wanted
+// tags, with the path mapped by fullPath and the tags of the group added.
+func (r *Router) collectRoutes(routes []Route, group string, groupTags, wanted []string, fullPath func(string) string) []Route {
+	for method, root := range r.trees.Load().all() {
+		root.walkRoutes(func(n *node) {
+			opts := routeOptionsOf(n.handle)
+			tags := mergeTags(groupTags, opts.tags)
+			if !hasTags(tags, wanted) {
+				return
+			}
+			path := n.fullPath
+			if fullPath != nil {
+				path = fullPath(path)
+			}
+			routes = append(routes, Route{
+				Method: method,
+				Path:   path,
+				Group:  group,
+				Name:   opts.name,
+				Tags:   slices.Clone(tags),
+			})
+		})
+	}
+	return routes
+}
+
+func sortRoutes(routes []Route) {
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+}
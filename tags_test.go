@@ -0,0 +1,87 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestRouterTags(t *testing.T) {
+	ok := func(w http.ResponseWriter, _ *http.Request) {}
+
+	router := New()
+	router.GET("/users/{id}", ok, Tags("public"))
+	router.GET("/debug/cache", ok, Tags("internal", "debug"), Tags("internal"))
+	router.DELETE("/users/{id}", ok, Tags("public", "deprecated"))
+	router.GET("/healthz", ok)
+
+	tests := []struct {
+		tags []string
+		want []Route
+	}{
+		{nil, []Route{
+			{Method: http.MethodGet, Path: "/debug/cache", Tags: []string{"debug", "internal"}},
+			{Method: http.MethodGet, Path: "/healthz"},
+			{Method: http.MethodDelete, Path: "/users/{id}", Tags: []string{"deprecated", "public"}},
+			{Method: http.MethodGet, Path: "/users/{id}", Tags: []string{"public"}},
+		}},
+		{[]string{"public"}, []Route{
+			{Method: http.MethodDelete, Path: "/users/{id}", Tags: []string{"deprecated", "public"}},
+			{Method: http.MethodGet, Path: "/users/{id}", Tags: []string{"public"}},
+		}},
+		{[]string{"public", "deprecated"}, []Route{
+			{Method: http.MethodDelete, Path: "/users/{id}", Tags: []string{"deprecated", "public"}},
+		}},
+		{[]string{"missing"}, nil},
+	}
+	for _, tt := range tests {
+		if got := router.Routes(tt.tags...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Routes(%v) = %+v, want %+v", tt.tags, got, tt.want)
+		}
+	}
+
+	if got := router.RouteTags(http.MethodGet, "/debug/cache"); !reflect.DeepEqual(got, []string{"debug", "internal"}) {
+		t.Errorf("RouteTags = %v", got)
+	}
+	if got := router.RouteTags(http.MethodPost, "/debug/cache"); got != nil {
+		t.Errorf("RouteTags of missing route = %v, want nil", got)
+	}
+
+	doc := router.OpenAPI(OpenAPIInfo{Title: "test", Version: "1"})
+	if got := doc.Paths["/debug/cache"]["get"].Tags; !reflect.DeepEqual(got, []string{"debug", "internal"}) {
+		t.Errorf("OpenAPI tags = %v", got)
+	}
+}
+
+func TestMultiRouterGroupTags(t *testing.T) {
+	ok := func(w http.ResponseWriter, _ *http.Request) {}
+
+	multi := NewMultiRouter()
+	admin := multi.NewGroup("/admin", WithName("admin"), WithGroupTags("internal"))
+	admin.GET("/users", ok, Tags("deprecated"))
+	admin.GET("/stats", ok)
+	multi.NewGroup("/api", WithName("api")).GET("/users", ok, Tags("public"))
+
+	want := []Route{
+		{Method: http.MethodGet, Path: "/admin/stats", Group: "admin", Tags: []string{"internal"}},
+		{Method: http.MethodGet, Path: "/admin/users", Group: "admin", Tags: []string{"deprecated", "internal"}},
+	}
+	if got := multi.AllRoutes("internal"); !reflect.DeepEqual(got, want) {
+		t.Errorf("AllRoutes(internal) = %+v, want %+v", got, want)
+	}
+
+	flat, err := multi.Flatten()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := flat.Routes("internal"); len(got) != 2 {
+		t.Errorf("flattened internal routes = %+v, want 2", got)
+	}
+	if got := flat.RouteTags(http.MethodGet, "/admin/users"); !reflect.DeepEqual(got, []string{"deprecated", "internal"}) {
+		t.Errorf("flattened RouteTags = %v", got)
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// timeoutHandler runs next with a context deadline of d. If next does not
+// return in time, the client receives 504 Gateway Timeout and everything next
+// writes afterwards is discarded.
+// The response of next is buffered until it returns, so that it cannot race
+// with the timeout response.
+// WebSocket upgrades and event streams are passed through unchanged, since
+// the buffered writer cannot be hijacked or flushed and the connection
+// outlives any request timeout.
+func timeoutHandler(d time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if isStreamingRequest(req) {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(req.Context(), d)
+		defer cancel()
+
+		// The caller may modify the URL once we return, e.g. a MultiRouter
+		// restores the path, while next is still running
+		r := req.WithContext(ctx)
+		u := *req.URL
+		r.URL = &u
+
+		tw := &timeoutWriter{w: w, ctx: ctx, header: make(http.Header)}
+		done := make(chan struct{})
+		panicChan := make(chan any, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicChan <- p
+				}
+			}()
+			next.ServeHTTP(tw, r)
+			close(done)
+		}()
+
+		select {
+		case p := <-panicChan:
+			panic(p)
+		case <-done:
+		case <-ctx.Done():
+		}
+
+		tw.mu.Lock()
+		defer tw.mu.Unlock()
+		// Writes fail once the context is done, so a handler returning just
+		// after the deadline may have been cut short as well
+		if err := ctx.Err(); err != nil {
+			if err == context.DeadlineExceeded {
+				writeError(w, req, "504 gateway timeout", http.StatusGatewayTimeout)
+			}
+			return
+		}
+		dst := w.Header()
+		for k, v := range tw.header {
+			dst[k] = v
+		}
+		if tw.code == 0 {
+			tw.code = http.StatusOK
+		}
+		w.WriteHeader(tw.code)
+		w.Write(tw.buf.Bytes())
+	})
+}
+
+// isStreamingRequest reports whether the request is a WebSocket upgrade or
+// asks for server-sent events.
+func isStreamingRequest(req *http.Request) bool {
+	return isWebSocketUpgrade(req) || headerHasToken(req.Header, "Accept", "text/event-stream")
+}
+
+// TimeoutOption configures the middleware of Timeout.
+type TimeoutOption func(*timeoutConfig)
+
+type timeoutConfig struct {
+	exempt func(*http.Request) bool
+}
+
+// TimeoutExempt exempts the requests for which exempt returns true from the
+// time limit, e.g. those of streaming endpoints:
+//
+//	httpmux.Timeout(5*time.Second, httpmux.TimeoutExempt(func(req *http.Request) bool {
+//		return strings.HasPrefix(req.URL.Path, "/export/")
+//	}))
+func TimeoutExempt(exempt func(*http.Request) bool) TimeoutOption {
+	return func(c *timeoutConfig) {
+		c.exempt = exempt
+	}
+}
+
+// Timeout returns a middleware limiting the time handlers may take, for single
+// routes or, added by Router.Use, for all routes of a router:
+//
+//	router.Handle(http.MethodGet, "/report", httpmux.Timeout(10*time.Second)(report))
+//
+// It behaves like WithTimeout for groups: the context of the request gets a
+// deadline, which handlers should observe. If a handler does not return in
+// time, the client receives 504 Gateway Timeout and whatever the handler
+// writes afterwards is discarded. Responses are buffered until the handler
+// returns, so that they cannot race with the timeout response, which also
+// means handlers cannot stream responses. WebSocket upgrades and requests
+// accepting text/event-stream are therefore exempt from the limit, as are
+// those selected by TimeoutExempt. Panics of the handler are passed on to the
+// caller.
+func Timeout(d time.Duration, opts ...TimeoutOption) func(http.Handler) http.Handler {
+	var c timeoutConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return func(next http.Handler) http.Handler {
+		limited := timeoutHandler(d, next)
+		if c.exempt == nil {
+			return limited
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if c.exempt(req) {
+				next.ServeHTTP(w, req)
+			} else {
+				limited.ServeHTTP(w, req)
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers the response of a handler run by timeoutHandler.
+// It deliberately does not implement Unwrap, Flush or Hijack, since writing
+// to the underlying ResponseWriter would race with the timeout response.
+// Deadlines and full duplex mode are passed on to the underlying writer until
+// the request times out, so that http.ResponseController can still set them.
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	ctx    context.Context
+	mu     sync.Mutex
+	header http.Header
+	buf    bytes.Buffer
+	code   int
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.code == 0 && !w.expired() {
+		w.code = code
+	}
+}
+
+func (w *timeoutWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.expired() {
+		return 0, http.ErrHandlerTimeout
+	}
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.buf.Write(p)
+}
+
+// expired reports whether the handler ran out of time. From then on the
+// response belongs to timeoutHandler, even before it noticed.
+func (w *timeoutWriter) expired() bool {
+	return w.ctx.Err() != nil
+}
+
+func (w *timeoutWriter) SetReadDeadline(deadline time.Time) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.expired() {
+		return http.ErrHandlerTimeout
+	}
+	return http.NewResponseController(w.w).SetReadDeadline(deadline)
+}
+
+func (w *timeoutWriter) SetWriteDeadline(deadline time.Time) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.expired() {
+		return http.ErrHandlerTimeout
+	}
+	return http.NewResponseController(w.w).SetWriteDeadline(deadline)
+}
+
+func (w *timeoutWriter) EnableFullDuplex() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.expired() {
+		return http.ErrHandlerTimeout
+	}
+	return http.NewResponseController(w.w).EnableFullDuplex()
+}
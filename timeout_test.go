@@ -0,0 +1,141 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeoutHandler(t *testing.T) {
+	writeErr := make(chan error, 1)
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		_, err := w.Write([]byte("late"))
+		writeErr <- err
+	})
+	fast := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); !ok {
+			t.Error("expected context deadline")
+		}
+		w.Header().Set("X-Fast", "1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("fast"))
+	})
+
+	w := httptest.NewRecorder()
+	timeoutHandler(10*time.Millisecond, slow).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected 504, got %d", w.Code)
+	}
+	if err := <-writeErr; !errors.Is(err, http.ErrHandlerTimeout) {
+		t.Errorf("expected ErrHandlerTimeout for late writes, got %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	timeoutHandler(time.Second, fast).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusCreated || w.Body.String() != "fast" || w.Header().Get("X-Fast") != "1" {
+		t.Errorf("unexpected response %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	// A canceled client gets no response
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w = httptest.NewRecorder()
+	timeoutHandler(time.Second, slow).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	if w.Body.Len() != 0 {
+		t.Errorf("expected no response for canceled request, got %q", w.Body.String())
+	}
+	<-writeErr
+}
+
+func TestTimeoutHandlerPanic(t *testing.T) {
+	h := timeoutHandler(time.Second, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}))
+	recv := catchPanic(func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+	if recv != "boom" {
+		t.Errorf("expected panic to propagate, got %v", recv)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	var flushed bool
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, limited := r.Context().Deadline()
+		if !limited {
+			if err := http.NewResponseController(w).Flush(); err == nil {
+				flushed = true
+			}
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(50 * time.Millisecond):
+			w.Write([]byte("done"))
+		}
+	})
+
+	router := New()
+	router.Use(Timeout(10*time.Millisecond, TimeoutExempt(func(req *http.Request) bool {
+		return req.URL.Query().Has("exempt")
+	})))
+	router.GET("/slow", handler)
+
+	tests := []struct {
+		name, url, accept string
+		code              int
+		flushed           bool
+	}{
+		{"limited", "/slow", "", http.StatusGatewayTimeout, false},
+		{"event stream", "/slow", "text/event-stream", http.StatusOK, true},
+		{"exempt", "/slow?exempt", "", http.StatusOK, true},
+	}
+	for _, tt := range tests {
+		flushed = false
+		req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.code || flushed != tt.flushed {
+			t.Errorf("%s: got %d, flushed %v, want %d, flushed %v", tt.name, w.Code, flushed, tt.code, tt.flushed)
+		}
+	}
+}
+
+func TestTimeoutResponseController(t *testing.T) {
+	errs := make(chan error, 4)
+	router := New()
+	router.Use(Timeout(time.Second))
+	router.GET("/", func(w http.ResponseWriter, _ *http.Request) {
+		rc := http.NewResponseController(w)
+		errs <- rc.SetWriteDeadline(time.Now().Add(time.Second))
+		errs <- rc.SetReadDeadline(time.Now().Add(time.Second))
+		errs <- rc.Flush()
+		_, _, err := rc.Hijack()
+		errs <- err
+	})
+
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+	res, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	for i, want := range []error{nil, nil, http.ErrNotSupported, http.ErrNotSupported} {
+		if err := <-errs; !errors.Is(err, want) {
+			t.Errorf("ResponseController call %d: got %v, want %v", i, err, want)
+		}
+	}
+}
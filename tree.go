@@ -5,8 +5,11 @@
 package httpmux
 
 import (
+	"fmt"
 	"net/http"
+	"slices"
 	"strings"
+	"sync"
 	"unicode"
 	"unicode/utf8"
 )
@@ -18,6 +21,10 @@ func min(a, b int) int {
 	return b
 }
 
+// longestCommonPrefix is only used when adding routes. Lookups compare whole
+// node paths with ==, which the runtime already does a word at a time. Common
+// prefixes of routes are short enough that comparing words is slower here,
+// see BenchmarkLongestCommonPrefix and BenchmarkHttpMux_GithubRegister.
 func longestCommonPrefix(a, b string) int {
 	i := 0
 	max := min(len(a), len(b))
@@ -84,7 +91,8 @@ type node struct {
 	nType     nodeType
 	priority  uint32
 	children  []*node
-	handle    http.HandlerFunc
+	handle    http.Handler
+	fullPath  string // registered path of the handle, if any
 }
 
 // Increments priority of the given child and reorders if necessary
@@ -110,9 +118,22 @@ func (n *node) incrementChildPrio(pos int) int {
 	return newPos
 }
 
+// copyChild replaces the i-th child by a copy and returns it.
+// The children slice is copied as well, so that the copy can be modified
+// without affecting other trees sharing the child.
+func (n *node) copyChild(i int) *node {
+	n.children = slices.Clone(n.children)
+	child := *n.children[i]
+	n.children[i] = &child
+	return &child
+}
+
 // addRoute adds a node with the given handle to the path.
+// Nodes on the way to the new route are copied before they are modified, so
+// if n is a copy of the root of a tree in use, that tree remains unchanged and
+// can still be read concurrently.
 // Not concurrency-safe!
-func (n *node) addRoute(path string, handle http.HandlerFunc) {
+func (n *node) addRoute(path string, handle http.Handler) {
 	path = preCleanPath(path)
 
 	fullPath := path
@@ -141,6 +162,7 @@ walk:
 				indices:   n.indices,
 				children:  n.children,
 				handle:    n.handle,
+				fullPath:  n.fullPath,
 				priority:  n.priority - 1,
 			}
 
@@ -149,6 +171,7 @@ walk:
 			n.indices = string([]byte{n.path[i]})
 			n.path = path[:i]
 			n.handle = nil
+			n.fullPath = ""
 			n.wildChild = false
 		}
 
@@ -157,7 +180,7 @@ walk:
 			path = path[i:]
 
 			if n.wildChild {
-				n = n.children[0]
+				n = n.copyChild(0)
 				n.priority++
 
 				// Check if the wildcard matches
@@ -193,7 +216,7 @@ walk:
 
 			// '/' after param
 			if n.nType == param && idxc == '/' && len(n.children) == 1 {
-				n = n.children[0]
+				n = n.copyChild(0)
 				n.priority++
 				continue walk
 			}
@@ -201,6 +224,7 @@ walk:
 			// Check if a child with the next path byte exists
 			for i, c := range []byte(n.indices) {
 				if c == idxc {
+					n.copyChild(i)
 					i = n.incrementChildPrio(i)
 					n = n.children[i]
 					continue walk
@@ -212,7 +236,8 @@ walk:
 				// []byte for proper unicode char conversion, see #65
 				n.indices += string([]byte{idxc})
 				child := &node{}
-				n.children = append(n.children, child)
+				// Clip to not write into the array of a shared slice
+				n.children = append(slices.Clip(n.children), child)
 				n.incrementChildPrio(len(n.indices) - 1)
 				n = child
 			}
@@ -225,11 +250,12 @@ walk:
 			panic("a handle is already registered for path '" + fullPath + "'")
 		}
 		n.handle = handle
+		n.fullPath = fullPath
 		return
 	}
 }
 
-func (n *node) insertChild(path, fullPath string, handle http.HandlerFunc) {
+func (n *node) insertChild(path, fullPath string, handle http.Handler) {
 	for {
 		// Find prefix until first wildcard
 		wildcard, i, valid := findWildcard(path)
@@ -291,6 +317,7 @@ func (n *node) insertChild(path, fullPath string, handle http.HandlerFunc) {
 
 			// Otherwise we're done. Insert the handle in the new leaf
 			n.handle = handle
+			n.fullPath = fullPath
 			return
 		}
 
@@ -326,6 +353,7 @@ func (n *node) insertChild(path, fullPath string, handle http.HandlerFunc) {
 			path:     path[i:],
 			nType:    catchAll,
 			handle:   handle,
+			fullPath: fullPath,
 			priority: 1,
 		}
 		n.children = []*node{child}
@@ -336,18 +364,76 @@ func (n *node) insertChild(path, fullPath string, handle http.HandlerFunc) {
 	// If no wildcard was found, simply insert the path and handle
 	n.path = path
 	n.handle = handle
+	n.fullPath = fullPath
+}
+
+// maxStackParams is the number of path parameters a lookup can capture
+// without allocating.
+const maxStackParams = 8
+
+// pathValues buffers the path parameters captured during a lookup, so that
+// they are only written to the request once a route matched.
+type pathValues struct {
+	n      int
+	keys   [maxStackParams]string
+	values [maxStackParams]string
+	more   []string // key, value pairs exceeding the fixed-size buffer
+}
+
+func (pv *pathValues) add(key, value string) {
+	if pv.n < maxStackParams {
+		pv.keys[pv.n] = key
+		pv.values[pv.n] = value
+		pv.n++
+		return
+	}
+	pv.more = append(pv.more, key, value)
+}
+
+// apply writes the captured path values and the pattern of the matched route
+// to the request.
+// net/http offers no way to set several path values at once: the fields
+// backing PathValue are unexported, and without a matched ServeMux pattern
+// every value is stored in a map allocated by the first SetPathValue call.
+// Only the Pattern field can be populated directly.
+func (pv *pathValues) apply(req *http.Request, pattern string) {
+	for i := 0; i < pv.n; i++ {
+		req.SetPathValue(pv.keys[i], pv.values[i])
+	}
+	for i := 0; i < len(pv.more); i += 2 {
+		req.SetPathValue(pv.more[i], pv.more[i+1])
+	}
+	req.Pattern = pattern
 }
 
-// Returns the handle registered with the given path (key). The values of
-// wildcards are saved to a map.
+// Returns the handle registered with the given path (key). If req is not nil
+// and a handle is found, the values of wildcards are stored as path values of
+// the request and the registered path of the handle is stored as the request's
+// Pattern. The values are buffered on the stack during the lookup and written
+// to the request at once, so a failed lookup leaves the request untouched.
+// Values exceeding the stack buffer are appended to params, if not nil, which
+// is no longer referenced once getValue returns.
 // If no handle can be found, a TSR (trailing slash redirect) recommendation is
 // made if a handle exists with an extra (without the) trailing slash for the
 // given path.
-func (n *node) getValue(path string, req *http.Request) (handle http.HandlerFunc, tsr bool) {
+func (n *node) getValue(path string, req *http.Request, params *[]string) (handle http.Handler, tsr bool) {
+	return n.lookup(path, req, params, nil)
+}
+
+// lookup implements getValue. If e is not nil, the visited nodes and the
+// reason of a failed lookup are recorded in it for Router.Explain.
+func (n *node) lookup(path string, req *http.Request, params *[]string, e *Explanation) (handle http.Handler, tsr bool) {
+	var ps pathValues
+	if params != nil {
+		ps.more = (*params)[:0]
+	}
 
 walk: // Outer loop for walking the tree
 	for {
 		prefix := n.path
+		if e != nil {
+			e.visit(n, path)
+		}
 		if len(path) > len(prefix) {
 			if path[:len(prefix)] == prefix {
 				path = path[len(prefix):]
@@ -368,11 +454,17 @@ walk: // Outer loop for walking the tree
 					// We can recommend to redirect to the same URL without a
 					// trailing slash if a leaf exists for that path.
 					tsr = (path == "/" && n.handle != nil)
+					if e != nil {
+						e.Failure = fmt.Sprintf("no child node for %q", path)
+					}
 					return
 				}
 
 				// Handle wildcard child
 				n = n.children[0]
+				if e != nil {
+					e.visit(n, path)
+				}
 				switch n.nType {
 				case param:
 					// Find param end (either '/' or path end)
@@ -382,7 +474,10 @@ walk: // Outer loop for walking the tree
 					}
 
 					if req != nil {
-						req.SetPathValue(n.path[1:len(n.path)-1], path[:end])
+						ps.add(n.path[1:len(n.path)-1], path[:end])
+					}
+					if e != nil {
+						e.capture(n.path[1:len(n.path)-1], path[:end])
 					}
 
 					// We need to go deeper!
@@ -395,12 +490,25 @@ walk: // Outer loop for walking the tree
 
 						// ... but we can't
 						tsr = (len(path) == end+1)
+						if e != nil {
+							e.Failure = fmt.Sprintf("parameter node has no child node for %q", path[end:])
+						}
 						return
 					}
 
 					if handle = n.handle; handle != nil {
+						if req != nil {
+							ps.apply(req, n.fullPath)
+						}
+						if e != nil {
+							e.Route = n.fullPath
+						}
 						return
-					} else if len(n.children) == 1 {
+					}
+					if e != nil {
+						e.Failure = "parameter node has no handler"
+					}
+					if len(n.children) == 1 {
 						// No handle found. Check if a handle for this path + a
 						// trailing slash exists for TSR recommendation
 						n = n.children[0]
@@ -410,11 +518,15 @@ walk: // Outer loop for walking the tree
 					return
 
 				case catchAll:
-					if req != nil {
-						req.SetPathValue(n.path[2:len(n.path)-4], path)
-					}
-
 					handle = n.handle
+					if req != nil && handle != nil {
+						ps.add(n.path[2:len(n.path)-4], path)
+						ps.apply(req, n.fullPath)
+					}
+					if e != nil {
+						e.capture(n.path[2:len(n.path)-4], path)
+						e.Route = n.fullPath
+					}
 					return
 
 				default:
@@ -425,8 +537,17 @@ walk: // Outer loop for walking the tree
 			// We should have reached the node containing the handle.
 			// Check if this node has a handle registered.
 			if handle = n.handle; handle != nil {
+				if req != nil {
+					ps.apply(req, n.fullPath)
+				}
+				if e != nil {
+					e.Route = n.fullPath
+				}
 				return
 			}
+			if e != nil {
+				e.Failure = "node has no handler"
+			}
 
 			// If there is no handle for this route, but this route has a
 			// wildcard child, there must be a handle for this path with an
@@ -456,6 +577,9 @@ walk: // Outer loop for walking the tree
 
 		// Nothing found. We can recommend to redirect to the same URL with an
 		// extra trailing slash if a leaf exists for that path
+		if e != nil {
+			e.Failure = fmt.Sprintf("%q does not match node %q", path, prefix)
+		}
 		tsr = (path == "/") ||
 			(len(prefix) == len(path)+1 && prefix[len(path)] == '/' &&
 				path == prefix[:len(prefix)-1] && n.handle != nil)
@@ -463,28 +587,51 @@ walk: // Outer loop for walking the tree
 	}
 }
 
+// Buffers used by findCaseInsensitivePath.
+// Since RedirectFixedPath makes every request which can not be routed perform
+// a case-insensitive lookup, the buffers are pooled instead of being allocated
+// per request.
+var ciPathPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 0, ciPathBufSize)
+		return &buf
+	},
+}
+
+const (
+	ciPathBufSize    = 128
+	maxCIPathBufSize = 4096 // larger buffers are not returned to the pool
+)
+
 // Makes a case-insensitive lookup of the given path and tries to find a handler.
 // It can optionally also fix trailing slashes.
 // It returns the case-corrected path and a bool indicating whether the lookup
 // was successful.
 func (n *node) findCaseInsensitivePath(path string, fixTrailingSlash bool) (fixedPath string, found bool) {
-	const stackBufSize = 128
+	bp := ciPathPool.Get().(*[]byte)
+	buf := (*bp)[:0]
 
-	// Use a static sized buffer on the stack in the common case.
-	// If the path is too long, allocate a buffer on the heap instead.
-	buf := make([]byte, 0, stackBufSize)
-	if l := len(path) + 1; l > stackBufSize {
+	// Preallocate enough memory for the new path
+	if l := len(path) + 1; l > cap(buf) {
 		buf = make([]byte, 0, l)
 	}
 
 	ciPath := n.findCaseInsensitivePathRec(
 		path,
-		buf,       // Preallocate enough memory for new path
+		buf,
 		[4]byte{}, // Empty rune buffer
 		fixTrailingSlash,
 	)
+	if ciPath != nil {
+		fixedPath, found = string(ciPath), true
+	}
+
+	if cap(buf) <= maxCIPathBufSize {
+		*bp = buf
+	}
+	ciPathPool.Put(bp)
 
-	return string(ciPath), ciPath != nil
+	return fixedPath, found
 }
 
 // Shift bytes in array by n bytes left
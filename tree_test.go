@@ -5,7 +5,9 @@
 package httpmux
 
 import (
+	"encoding/binary"
 	"fmt"
+	"math/bits"
 	"net/http"
 	"regexp"
 	"strings"
@@ -40,7 +42,7 @@ type testRequests []struct {
 
 func checkRequests(t *testing.T, tree *node, requests testRequests) {
 	for _, request := range requests {
-		handler, _ := tree.getValue(request.path, nil)
+		handler, _ := tree.getValue(request.path, nil, nil)
 
 		switch {
 		case handler == nil:
@@ -50,7 +52,7 @@ func checkRequests(t *testing.T, tree *node, requests testRequests) {
 		case request.nilHandler:
 			t.Errorf("handle mismatch for route '%s': Expected nil handle", request.path)
 		default:
-			handler(nil, nil)
+			handler.ServeHTTP(nil, nil)
 			if fakeHandlerValue != request.route {
 				t.Errorf("handle mismatch for route '%s': Wrong handle (%s != %s)", request.path, fakeHandlerValue, request.route)
 			}
@@ -410,7 +412,7 @@ func TestTreeTrailingSlashRedirect(t *testing.T) {
 		"/vendor/x",
 	}
 	for _, route := range tsrRoutes {
-		handler, tsr := tree.getValue(route, nil)
+		handler, tsr := tree.getValue(route, nil, nil)
 		if handler != nil {
 			t.Fatalf("non-nil handler for TSR route '%s", route)
 		} else if !tsr {
@@ -427,7 +429,7 @@ func TestTreeTrailingSlashRedirect(t *testing.T) {
 		"/api/world/abc",
 	}
 	for _, route := range noTsrRoutes {
-		handler, tsr := tree.getValue(route, nil)
+		handler, tsr := tree.getValue(route, nil, nil)
 		if handler != nil {
 			t.Fatalf("non-nil handler for No-TSR route '%s", route)
 		} else if tsr {
@@ -446,7 +448,7 @@ func TestTreeRootTrailingSlashRedirect(t *testing.T) {
 		t.Fatalf("panic inserting test route: %v", recv)
 	}
 
-	handler, tsr := tree.getValue("/", nil)
+	handler, tsr := tree.getValue("/", nil, nil)
 	if handler != nil {
 		t.Fatalf("non-nil handler")
 	} else if tsr {
@@ -629,7 +631,7 @@ func TestTreeInvalidNodeType(t *testing.T) {
 
 	// normal lookup
 	recv := catchPanic(func() {
-		tree.getValue("/test", nil)
+		tree.getValue("/test", nil, nil)
 	})
 	if rs, ok := recv.(string); !ok || rs != panicMsg {
 		t.Fatalf("Expected panic '"+panicMsg+"', got '%v'", recv)
@@ -700,8 +702,74 @@ func TestRedirectTrailingSlash(t *testing.T) {
 		node.addRoute(item.path, fakeHandler("test"))
 	}
 
-	_, tsr := node.getValue("/hello/abx/", nil)
+	_, tsr := node.getValue("/hello/abx/", nil, nil)
 	if tsr != true {
 		t.Fatalf("want true, is false")
 	}
 }
+
+func TestTreeFindCaseInsensitivePathAllocs(t *testing.T) {
+	tree := &node{}
+	for _, route := range []string{"/hi", "/users/{id}/profile", "/files/{filepath...}"} {
+		tree.addRoute(route, fakeHandler(route))
+	}
+
+	long := "/" + strings.Repeat("x", 300)
+	for _, path := range []string{"/nope", "/USERS/1/nope", long} {
+		allocs := testing.AllocsPerRun(100, func() {
+			tree.findCaseInsensitivePath(path, true)
+		})
+		if allocs > 0 {
+			t.Errorf("%s: expected no allocations for failed lookup, got %v", path, allocs)
+		}
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		tree.findCaseInsensitivePath("/USERS/1/PROFILE", true)
+	})
+	if allocs > 1 {
+		t.Errorf("expected at most one allocation for the fixed path, got %v", allocs)
+	}
+}
+
+// longestCommonPrefixWords compares eight bytes at a time, for comparison with
+// longestCommonPrefix in BenchmarkLongestCommonPrefix.
+func longestCommonPrefixWords(a, b string) int {
+	n := min(len(a), len(b))
+	i := 0
+	for ; i+8 <= n; i += 8 {
+		x := binary.LittleEndian.Uint64([]byte(a[i:i+8])) ^ binary.LittleEndian.Uint64([]byte(b[i:i+8]))
+		if x != 0 {
+			return i + bits.TrailingZeros64(x)/8
+		}
+	}
+	for i < n && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+func BenchmarkLongestCommonPrefix(b *testing.B) {
+	var pairs [][2]string
+	for i := 1; i < len(githubAPIStd); i++ {
+		pairs = append(pairs, [2]string{githubAPIStd[i-1].path, githubAPIStd[i].path})
+	}
+	for _, p := range pairs {
+		if longestCommonPrefix(p[0], p[1]) != longestCommonPrefixWords(p[0], p[1]) {
+			b.Fatalf("implementations differ for %q and %q", p[0], p[1])
+		}
+	}
+
+	for name, lcp := range map[string]func(a, b string) int{
+		"bytes": longestCommonPrefix,
+		"words": longestCommonPrefixWords,
+	} {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, p := range pairs {
+					lcp(p[0], p[1])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"sort"
+	"unsafe"
+)
+
+// TreeStats describes the shape and size of route trees.
+// It is returned by Router.TreeStats.
+type TreeStats struct {
+	// Method of the tree, empty for the totals of all trees
+	Method string
+
+	// Number of registered routes
+	Routes int
+
+	// Number of nodes, including the root
+	Nodes int
+
+	// Number of nodes on the longest path from the root to a leaf
+	MaxDepth int
+
+	// Number of parameter and catch-all nodes
+	ParamNodes    int
+	CatchAllNodes int
+
+	// Largest number of path parameters of a single route
+	MaxParams int
+
+	// Estimated number of bytes allocated for the nodes, their paths and
+	// children. Handlers and strings shared with the registered patterns are
+	// counted as well, so the actual memory usage may be lower.
+	Memory int64
+
+	// Statistics per method tree, sorted by method. Only set for the totals.
+	Methods []TreeStats
+}
+
+// TreeStats walks the route trees and returns statistics about their size,
+// e.g. for capacity planning or to detect regressions in tests:
+//
+//	if s := router.TreeStats(); s.Memory > 64<<10 {
+//		t.Errorf("route trees use %d bytes", s.Memory)
+//	}
+func (r *Router) TreeStats() TreeStats {
+	var total TreeStats
+	for method, root := range r.trees.Load().all() {
+		s := TreeStats{Method: method}
+		root.collectStats(&s, 1, 0)
+
+		total.Routes += s.Routes
+		total.Nodes += s.Nodes
+		total.MaxDepth = max(total.MaxDepth, s.MaxDepth)
+		total.ParamNodes += s.ParamNodes
+		total.CatchAllNodes += s.CatchAllNodes
+		total.MaxParams = max(total.MaxParams, s.MaxParams)
+		total.Memory += s.Memory
+		total.Methods = append(total.Methods, s)
+	}
+	sort.Slice(total.Methods, func(i, j int) bool {
+		return total.Methods[i].Method < total.Methods[j].Method
+	})
+	return total
+}
+
+// collectStats adds n and its descendants to s. depth is the depth of n,
+// params the number of parameters on the path to n, excluding n itself.
+func (n *node) collectStats(s *TreeStats, depth, params int) {
+	s.Nodes++
+	s.MaxDepth = max(s.MaxDepth, depth)
+	s.Memory += int64(unsafe.Sizeof(*n)) + int64(len(n.path)+len(n.indices)+len(n.fullPath)) +
+		int64(cap(n.children))*int64(unsafe.Sizeof(n))
+
+	switch n.nType {
+	case param:
+		s.ParamNodes++
+		params++
+	case catchAll:
+		// A catch-all is stored as an empty node followed by the one holding
+		// the parameter
+		if n.path != "" {
+			s.CatchAllNodes++
+			params++
+		}
+	}
+
+	if n.handle != nil {
+		s.Routes++
+		s.MaxParams = max(s.MaxParams, params)
+	}
+
+	for _, child := range n.children {
+		child.collectStats(s, depth+1, params)
+	}
+}
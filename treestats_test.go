@@ -0,0 +1,61 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRouterTreeStats(t *testing.T) {
+	handlerFunc := func(_ http.ResponseWriter, _ *http.Request) {}
+
+	router := New()
+	if s := router.TreeStats(); s.Nodes != 0 || s.Routes != 0 || s.Methods != nil {
+		t.Errorf("expected empty stats for empty router, got %+v", s)
+	}
+
+	router.GET("/", handlerFunc)
+	router.GET("/users/{id}", handlerFunc)
+	router.GET("/users/{id}/posts/{post}", handlerFunc)
+	router.GET("/src/{filepath...}", handlerFunc)
+	router.POST("/users", handlerFunc)
+
+	s := router.TreeStats()
+	if s.Routes != 5 {
+		t.Errorf("expected 5 routes, got %d", s.Routes)
+	}
+	if len(s.Methods) != 2 || s.Methods[0].Method != http.MethodGet || s.Methods[1].Method != http.MethodPost {
+		t.Fatalf("unexpected per-method stats %+v", s.Methods)
+	}
+	get, post := s.Methods[0], s.Methods[1]
+	if get.Routes != 4 || post.Routes != 1 {
+		t.Errorf("unexpected route counts GET=%d POST=%d", get.Routes, post.Routes)
+	}
+	if post.Nodes != 1 || post.MaxDepth != 1 {
+		t.Errorf("expected single node POST tree, got %+v", post)
+	}
+	if s.Nodes != get.Nodes+post.Nodes {
+		t.Errorf("total node count %d does not match sum of trees", s.Nodes)
+	}
+	if get.ParamNodes != 2 || get.CatchAllNodes != 1 {
+		t.Errorf("unexpected wildcard node counts %+v", get)
+	}
+	if s.MaxParams != 2 {
+		t.Errorf("expected at most 2 params, got %d", s.MaxParams)
+	}
+	if s.MaxDepth <= 2 {
+		t.Errorf("unexpected max depth %d", s.MaxDepth)
+	}
+	if s.Memory <= 0 {
+		t.Errorf("expected positive memory estimate, got %d", s.Memory)
+	}
+
+	// Compaction merges nodes, but keeps all routes
+	router.Freeze()
+	if c := router.TreeStats(); c.Routes != s.Routes || c.Nodes > s.Nodes {
+		t.Errorf("unexpected stats after compaction %+v", c)
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GenerateURLs writes the Go source of package pkg with a function for each
+// route named by the Name option, returning the path of the route with the
+// path parameters as arguments. Building URLs with the generated functions
+// breaks at compile time when routes change:
+//
+//	// cmd/genurls/main.go, run by "//go:generate go run ./cmd/genurls"
+//	func main() {
+//		var src bytes.Buffer
+//		if err := httpmux.GenerateURLs(&src, "urls", app.NewRouter().Routes()); err != nil {
+//			log.Fatal(err)
+//		}
+//		if err := os.WriteFile("urls/urls.go", src.Bytes(), 0o644); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+//
+// A route named "user.show" with the path /users/{id} yields
+//
+//	func URLUserShow(id string) string
+//
+// Arguments are escaped as path segments; those of catch-all parameters keep
+// their slashes. Routes without a name are skipped. Routes sharing a name,
+// e.g. the GET and POST routes of a form, must have the same path.
+// GenerateURLs returns an error if they do not or if names map to the same
+// function.
+func GenerateURLs(w io.Writer, pkg string, routes []Route) error {
+	if !token.IsIdentifier(pkg) {
+		return errors.New("httpmux: invalid package name '" + pkg + "'")
+	}
+	named, err := namedRoutes(routes)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	var usesURL, usesHelper bool
+	funcs := make(map[string]string)
+	for _, route := range named {
+		fn := "URL" + goIdentifier(route.Name, true)
+		if other, ok := funcs[fn]; ok {
+			return fmt.Errorf("httpmux: routes '%s' and '%s' both generate %s", other, route.Name, fn)
+		}
+		funcs[fn] = route.Name
+
+		args, expr, err := urlExpr(route.Path, "urlEscapePath")
+		if err != nil {
+			return fmt.Errorf("httpmux: route '%s': %w", route.Name, err)
+		}
+		usesURL = usesURL || len(args) > 0
+		usesHelper = usesHelper || strings.Contains(expr, "urlEscapePath(")
+
+		fmt.Fprintf(&body, "\n// %s returns the path of the route %q, %s.\n", fn, route.Name, route.Path)
+		fmt.Fprintf(&body, "func %s(%s) string {\n\treturn %s\n}\n", fn, strings.Join(args, ", "), expr)
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by httpmux.GenerateURLs. DO NOT EDIT.\n\npackage %s\n", pkg)
+	switch {
+	case usesHelper:
+		src.WriteString("\nimport (\n\t\"net/url\"\n\t\"strings\"\n)\n")
+	case usesURL:
+		src.WriteString("\nimport \"net/url\"\n")
+	}
+	body.WriteTo(&src)
+	if usesHelper {
+		src.WriteString(`
+// urlEscapePath escapes the segments of a catch-all parameter.
+func urlEscapePath(s string) string {
+	segments := strings.Split(s, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+`)
+	}
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(formatted)
+	return err
+}
+
+// namedRoutes returns one route for each name of the routes, sorted by name.
+func namedRoutes(routes []Route) ([]Route, error) {
+	byName := make(map[string]Route)
+	for _, route := range routes {
+		if route.Name == "" {
+			continue
+		}
+		if other, ok := byName[route.Name]; ok && other.Path != route.Path {
+			return nil, fmt.Errorf("httpmux: route name '%s' is used for the paths '%s' and '%s'", route.Name, other.Path, route.Path)
+		}
+		byName[route.Name] = route
+	}
+
+	named := make([]Route, 0, len(byName))
+	for _, route := range byName {
+		named = append(named, route)
+	}
+	sort.Slice(named, func(i, j int) bool { return named[i].Name < named[j].Name })
+	return named, nil
+}
+
+// urlExpr returns the parameters and the Go expression building the path,
+// escaping catch-all parameters with the function escapePath.
+func urlExpr(path, escapePath string) (args []string, expr string, err error) {
+	var exprs []string
+	var lit strings.Builder
+	seen := make(map[string]bool)
+	for i, seg := range strings.Split(path, "/") {
+		if i > 0 {
+			lit.WriteByte('/')
+		}
+		if len(seg) <= 2 || seg[0] != '{' || seg[len(seg)-1] != '}' {
+			lit.WriteString(seg)
+			continue
+		}
+
+		name, catchAll := strings.CutSuffix(seg[1:len(seg)-1], "...")
+		arg := goParamIdentifier(name)
+		if seen[arg] {
+			return nil, "", errors.New("parameters map to the same argument " + arg)
+		}
+		seen[arg] = true
+		args = append(args, arg+" string")
+
+		if lit.Len() > 0 {
+			exprs = append(exprs, strconv.Quote(lit.String()))
+			lit.Reset()
+		}
+		if catchAll {
+			exprs = append(exprs, escapePath+"("+arg+")")
+		} else {
+			exprs = append(exprs, "url.PathEscape("+arg+")")
+		}
+	}
+	if lit.Len() > 0 {
+		exprs = append(exprs, strconv.Quote(lit.String()))
+	}
+	return args, strings.Join(exprs, " + "), nil
+}
+
+// goIdentifier converts a name like "user.show" or "user_id" to camel case,
+// "UserShow" or "userId".
+func goIdentifier(name string, exported bool) string {
+	var b strings.Builder
+	upper := exported
+	for _, c := range name {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			upper = exported || b.Len() > 0
+			continue
+		}
+		switch {
+		case upper:
+			c = unicode.ToUpper(c)
+		case b.Len() == 0:
+			c = unicode.ToLower(c)
+		}
+		upper = false
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// goParamIdentifier returns the name of the argument of a path parameter,
+// which must not shadow the imports and other arguments of generated code.
+func goParamIdentifier(name string) string {
+	arg := goIdentifier(name, false)
+	switch {
+	case arg == "" || unicode.IsDigit(rune(arg[0])):
+		arg = "p" + arg
+	case token.IsKeyword(arg) || reservedIdentifiers[arg]:
+		arg += "_"
+	}
+	return arg
+}
+
+var reservedIdentifiers = map[string]bool{
+	"c": true, "ctx": true, "contentType": true, "body": true,
+	"context": true, "http": true, "io": true, "strings": true, "url": true,
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGenerateURLs(t *testing.T) {
+	ok := func(w http.ResponseWriter, _ *http.Request) {}
+
+	router := New()
+	router.GET("/users/{id}", ok, Name("user.show"))
+	router.GET("/files/{user_id}/{path...}", ok, Name("user-files"))
+	router.GET("/signup", ok, Name("signup"))
+	router.POST("/signup", ok, Name("signup"))
+	router.GET("/types/{type}", ok, Name("types"))
+	router.GET("/healthz", ok)
+
+	var b bytes.Buffer
+	if err := GenerateURLs(&b, "urls", router.Routes()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `// Code generated by httpmux.GenerateURLs. DO NOT EDIT.
+
+package urls
+
+import (
+	"net/url"
+	"strings"
+)
+
+// URLSignup returns the path of the route "signup", /signup.
+func URLSignup() string {
+	return "/signup"
+}
+
+// URLTypes returns the path of the route "types", /types/{type}.
+func URLTypes(type_ string) string {
+	return "/types/" + url.PathEscape(type_)
+}
+
+// URLUserFiles returns the path of the route "user-files", /files/{user_id}/{path...}.
+func URLUserFiles(userId string, path string) string {
+	return "/files/" + url.PathEscape(userId) + "/" + urlEscapePath(path)
+}
+
+// URLUserShow returns the path of the route "user.show", /users/{id}.
+func URLUserShow(id string) string {
+	return "/users/" + url.PathEscape(id)
+}
+
+// urlEscapePath escapes the segments of a catch-all parameter.
+func urlEscapePath(s string) string {
+	segments := strings.Split(s, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+`
+	if got := b.String(); got != want {
+		t.Errorf("generated:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestGenerateURLsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		pkg    string
+		routes []Route
+		err    string
+	}{
+		{"package", "my-urls", nil, "invalid package name"},
+		{"paths", "urls", []Route{
+			{Method: http.MethodGet, Path: "/a", Name: "a"},
+			{Method: http.MethodGet, Path: "/b", Name: "a"},
+		}, "is used for the paths"},
+		{"functions", "urls", []Route{
+			{Method: http.MethodGet, Path: "/a", Name: "user.show"},
+			{Method: http.MethodGet, Path: "/b", Name: "user_show"},
+		}, "both generate URLUserShow"},
+	}
+	for _, tt := range tests {
+		err := GenerateURLs(&bytes.Buffer{}, tt.pkg, tt.routes)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+)
+
+// validator holds the validation function of a router.
+type validator struct {
+	validate func(any) error
+}
+
+// SetValidator sets the function validating the values bound by Bind and the
+// inputs of methods exposed by Service in handlers of the router, e.g. the
+// Struct method of a go-playground/validator Validate:
+//
+//	router.SetValidator(validator.New().Struct)
+//
+// Values failing validation are reported as a *ValidationError, which
+// RespondError answers with 422 Unprocessable Entity. The errors of validation
+// libraries are broken down into field errors if the error is a slice of
+// errors, like validator.ValidationErrors, or wraps several errors, like
+// errors.Join. The name of a field is taken from a Field method of the error,
+// if any.
+func (r *Router) SetValidator(validate func(any) error) {
+	var v *validator
+	if validate != nil {
+		v = &validator{validate}
+	}
+	r.updateSettings(func(s *settings) {
+		s.validator = v
+	})
+}
+
+// validateRequestValue validates the value with the validator of the router
+// serving the request, if any.
+func validateRequestValue(req *http.Request, value any) error {
+	v := requestSettings(req).validator
+	if v == nil {
+		return nil
+	}
+	if err := v.validate(value); err != nil {
+		return newValidationError(err)
+	}
+	return nil
+}
+
+// ValidationError is returned by Bind if the bound value fails the validation
+// set by Router.SetValidator. It is a StatusError with status 422
+// Unprocessable Entity.
+type ValidationError struct {
+	// Error returned by the validator
+	Err error
+
+	// Errors of single fields found in Err
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	return "validation failed: " + e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode returns 422.
+func (e *ValidationError) StatusCode() int {
+	return http.StatusUnprocessableEntity
+}
+
+func newValidationError(err error) *ValidationError {
+	var errs []error
+	if u, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = u.Unwrap()
+	} else if v := reflect.ValueOf(err); v.Kind() == reflect.Slice {
+		for i := range v.Len() {
+			if e, ok := v.Index(i).Interface().(error); ok {
+				errs = append(errs, e)
+			}
+		}
+	} else {
+		errs = []error{err}
+	}
+
+	ve := &ValidationError{Err: err}
+	for _, e := range errs {
+		fe := FieldError{Message: e.Error()}
+		if f, ok := e.(interface{ Field() string }); ok {
+			fe.Name = f.Field()
+		}
+		ve.Errors = append(ve.Errors, fe)
+	}
+	return ve
+}
+
+// RespondError answers the request with the error as JSON, like
+//
+//	{"error": "invalid request: query page: invalid integer 'x'",
+//	 "fields": [{"source": "query", "name": "page", "message": "invalid integer 'x'"}]}
+//
+// The status and message are taken from the error if it is a StatusError, e.g.
+// 400 Bad Request for a *BindError and 422 Unprocessable Entity for a
+// *ValidationError. Other errors are answered with 500 Internal Server Error
+// without their message, which may reveal internals, and logged to the logger
+// of the router, see SetLogger. The field errors of a *BindError or
+// *ValidationError are listed in "fields":
+//
+//	var in CreateUser
+//	if err := httpmux.Bind(req, &in); err != nil {
+//		httpmux.RespondError(w, req, err)
+//		return
+//	}
+func RespondError(w http.ResponseWriter, req *http.Request, err error) {
+	body := struct {
+		Error     string       `json:"error"`
+		Fields    []FieldError `json:"fields,omitempty"`
+		RequestID string       `json:"request_id,omitempty"`
+	}{RequestID: RequestID(req)}
+
+	code := http.StatusInternalServerError
+	var se StatusError
+	if errors.As(err, &se) {
+		code = se.StatusCode()
+		body.Error = err.Error()
+	} else {
+		body.Error = http.StatusText(code)
+		logError(req, err)
+	}
+	var be *BindError
+	var ve *ValidationError
+	switch {
+	case errors.As(err, &be):
+		body.Fields = be.Errors
+	case errors.As(err, &ve):
+		body.Fields = ve.Errors
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,151 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testFieldError struct {
+	field, msg string
+}
+
+func (e testFieldError) Error() string { return e.msg }
+func (e testFieldError) Field() string { return e.field }
+
+// testValidationErrors mimics validator.ValidationErrors
+type testValidationErrors []testFieldError
+
+func (e testValidationErrors) Error() string { return "invalid fields" }
+
+type validatedInput struct {
+	Name string `json:"name"`
+	Age  int    `query:"age"`
+}
+
+func validateInput(v any) error {
+	in, ok := v.(*validatedInput)
+	if !ok {
+		return nil
+	}
+	var errs testValidationErrors
+	if in.Name == "" {
+		errs = append(errs, testFieldError{"Name", "name is required"})
+	}
+	if in.Age < 0 {
+		errs = append(errs, testFieldError{"Age", "age must not be negative"})
+	}
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+func TestBindValidation(t *testing.T) {
+	router := New()
+	router.SetValidator(validateInput)
+	router.POST("/users", func(w http.ResponseWriter, req *http.Request) {
+		var in validatedInput
+		if err := Bind(req, &in); err != nil {
+			RespondError(w, req, err)
+			return
+		}
+		w.Write([]byte("created " + in.Name))
+	})
+
+	for _, tt := range []struct {
+		url, body string
+		code      int
+		resp      string
+	}{
+		{"/users?age=3", `{"name":"ann"}`, http.StatusOK, `created ann`},
+		{"/users?age=-1", `{}`, http.StatusUnprocessableEntity, `{"error":"validation failed: invalid fields","fields":[{"name":"Name","message":"name is required"},{"name":"Age","message":"age must not be negative"}]}`},
+		{"/users?age=x", `{}`, http.StatusBadRequest, `{"error":"invalid request: query age: invalid integer 'x'","fields":[{"source":"query","name":"age","message":"invalid integer 'x'"}]}`},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.url, strings.NewReader(tt.body)))
+		if w.Code != tt.code || strings.TrimSpace(w.Body.String()) != tt.resp {
+			t.Errorf("%s %s: expected %d %s, got %d %s", tt.url, tt.body, tt.code, tt.resp, w.Code, w.Body.String())
+		}
+	}
+}
+
+type validatedService struct{}
+
+func (validatedService) Create(ctx context.Context, in *validatedInput) (string, error) {
+	return in.Name, nil
+}
+
+func TestServiceValidation(t *testing.T) {
+	router := New()
+	router.SetValidator(validateInput)
+	router.Service("users", validatedService{})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users/Create", strings.NewReader(`{}`)))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestValidationErrorJoined(t *testing.T) {
+	err := newValidationError(errors.Join(errors.New("a is required"), testFieldError{"B", "b is too long"}))
+	want := []FieldError{{Message: "a is required"}, {Name: "B", Message: "b is too long"}}
+	if len(err.Errors) != 2 || err.Errors[0] != want[0] || err.Errors[1] != want[1] {
+		t.Errorf("unexpected field errors %+v", err.Errors)
+	}
+	if err.StatusCode() != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422, got %d", err.StatusCode())
+	}
+}
+
+func TestRespondError(t *testing.T) {
+	w := httptest.NewRecorder()
+	RespondError(w, httptest.NewRequest(http.MethodGet, "/", nil), errors.New("boom"))
+	if w.Code != http.StatusInternalServerError || strings.TrimSpace(w.Body.String()) != `{"error":"Internal Server Error"}` {
+		t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+}
+
+func TestRespondErrorLogsInternalErrors(t *testing.T) {
+	logger, buf := newTestLogger()
+	router := New()
+	router.SetLogger(logger)
+	router.GET("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "1" {
+			RespondError(w, r, &BindError{Errors: []FieldError{{Source: "path", Name: "id", Message: "taken"}}})
+			return
+		}
+		RespondError(w, r, errors.New("pq: connection refused"))
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/2", nil))
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "pq") {
+		t.Errorf("expected the error message to be hidden, got %d %s", w.Code, w.Body.String())
+	}
+	if out := buf.String(); !strings.Contains(out, "level=ERROR") || !strings.Contains(out, "pq: connection refused") ||
+		!strings.Contains(out, "route=/users/{id}") {
+		t.Errorf("expected the error to be logged, got:\n%s", out)
+	}
+
+	buf.Reset()
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid request: path id: taken") {
+		t.Errorf("expected the bind error, got %d %s", w.Code, w.Body.String())
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no log for status errors, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// WatchOption configures WatchRoutes.
+type WatchOption func(*watchConfig)
+
+type watchConfig struct {
+	interval  time.Duration
+	unmarshal func([]byte, any) error
+	onError   func(error)
+	onReload  func(*RouteManifest)
+}
+
+// WithWatchInterval sets how often the manifest file is checked for changes,
+// once per second by default.
+func WithWatchInterval(d time.Duration) WatchOption {
+	return func(c *watchConfig) {
+		c.interval = d
+	}
+}
+
+// WithManifestUnmarshal sets the function decoding the manifest file, see
+// ParseRouteManifest. By default, the file is decoded as JSON.
+func WithManifestUnmarshal(unmarshal func([]byte, any) error) WatchOption {
+	return func(c *watchConfig) {
+		c.unmarshal = unmarshal
+	}
+}
+
+// WithReloadError sets a function called with the errors of failed reloads.
+// By default, they are logged to the logger set by SetLogger.
+func WithReloadError(onError func(error)) WatchOption {
+	return func(c *watchConfig) {
+		c.onError = onError
+	}
+}
+
+// WithReload sets a function called after the routes of a changed manifest
+// were swapped in.
+func WithReload(onReload func(*RouteManifest)) WatchOption {
+	return func(c *watchConfig) {
+		c.onReload = onReload
+	}
+}
+
+// WatchRoutes loads the route manifest file like LoadRoutes and reloads it
+// whenever it changes, until the context is canceled:
+//
+//	err := router.WatchRoutes(ctx, "routes.json", registry)
+//
+// On a change, the routes of the manifest are registered on a copy of the
+// route trees as they were when WatchRoutes was called, which then replaces
+// the trees of the router at once, so requests never see a partially loaded
+// manifest. Routes removed from the manifest disappear. If the manifest
+// cannot be read or loaded, e.g. because of conflicting routes, the error is
+// reported and the previous routes stay in place.
+//
+// Routes registered in code must be registered before WatchRoutes is called;
+// routes registered later are lost on the next reload. WatchRoutes returns the
+// error of the initial load, in which case nothing is watched.
+func (r *Router) WatchRoutes(ctx context.Context, file string, reg *HandlerRegistry, opts ...WatchOption) error {
+	cfg := &watchConfig{interval: time.Second}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.onError == nil {
+		cfg.onError = func(err error) {
+			if r.logger != nil {
+				r.logger.Error("httpmux: cannot reload routes", slog.String("file", file), slog.Any("error", err))
+			}
+		}
+	}
+
+	base := r.trees.Load()
+	baseMetadata := r.metadata.Load()
+
+	load := func(data []byte) error {
+		m, err := ParseRouteManifest(data, cfg.unmarshal)
+		if err != nil {
+			return err
+		}
+
+		// The manifest is loaded into a scratch router starting from the
+		// base routes, which registers routes like r
+		scratch := r.scratchRouter()
+		scratch.trees.Store(base)
+		scratch.metadata.Store(baseMetadata)
+		if err := scratch.LoadRoutes(m, reg); err != nil {
+			return err
+		}
+
+		r.mu.Lock()
+		r.trees.Store(scratch.trees.Load())
+		r.metadata.Store(scratch.metadata.Load())
+		r.mu.Unlock()
+		if cfg.onReload != nil {
+			cfg.onReload(m)
+		}
+		return nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err := load(data); err != nil {
+		return err
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(cfg.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			next, err := os.Stat(file)
+			if err != nil {
+				cfg.onError(err)
+				continue
+			}
+			if next.ModTime().Equal(info.ModTime()) && next.Size() == info.Size() {
+				continue
+			}
+			info = next
+
+			nextData, err := os.ReadFile(file)
+			if err != nil {
+				cfg.onError(err)
+				continue
+			}
+			if bytes.Equal(nextData, data) {
+				continue
+			}
+			data = nextData
+			if err := load(data); err != nil {
+				cfg.onError(err)
+			}
+		}
+	}()
+	return nil
+}
+
+// scratchRouter returns an empty router registering routes like r, i.e. with
+// the same middlewares and route wrappers.
+func (r *Router) scratchRouter() *Router {
+	return &Router{
+		SaveMatchedRoutePath: r.SaveMatchedRoutePath,
+		HandleOPTIONS:        r.HandleOPTIONS,
+		ProfileLabels:        r.ProfileLabels,
+		logger:               r.logger,
+		middlewares:          r.middlewares,
+		stats:                r.stats,
+		latency:              r.latency,
+	}
+}
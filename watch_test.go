@@ -0,0 +1,96 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRouterWatchRoutes(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "routes.json")
+	write := func(routes string) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(`{"routes": [`+routes+`]}`), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var mu sync.Mutex
+	var errs []error
+	reloads := make(chan *RouteManifest, 10)
+
+	router := New()
+	router.GET("/static", dummyHandler)
+	write(`{"method": "GET", "path": "/a", "handler": "health"}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := router.WatchRoutes(ctx, file, newTestRegistry(),
+		WithWatchInterval(5*time.Millisecond),
+		WithReload(func(m *RouteManifest) { reloads <- m }),
+		WithReloadError(func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-reloads
+
+	serve := func(path string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+	if serve("/a") != http.StatusOK || serve("/static") != http.StatusOK {
+		t.Fatal("expected the initial routes")
+	}
+
+	// Routes are swapped on change
+	time.Sleep(10 * time.Millisecond)
+	write(`{"method": "GET", "path": "/b", "handler": "health"}`)
+	select {
+	case <-reloads:
+	case <-time.After(2 * time.Second):
+		t.Fatal("manifest was not reloaded")
+	}
+	if serve("/a") != http.StatusNotFound || serve("/b") != http.StatusOK || serve("/static") != http.StatusOK {
+		t.Error("expected the routes of the changed manifest")
+	}
+
+	// Conflicts are reported and keep the previous routes
+	write(`{"method": "GET", "path": "/static", "handler": "health"}, {"method": "GET", "path": "/c", "handler": "health"}`)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		n := len(errs)
+		mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("conflict was not reported")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if !strings.Contains(errs[0].Error(), "/static") {
+		t.Errorf("unexpected error %v", errs[0])
+	}
+	if serve("/b") != http.StatusOK || serve("/c") != http.StatusNotFound {
+		t.Error("expected the previous routes to stay in place")
+	}
+
+	if err := New().WatchRoutes(ctx, filepath.Join(t.TempDir(), "missing.json"), newTestRegistry()); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
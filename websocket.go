@@ -0,0 +1,52 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"strings"
+)
+
+// methodWebSocket is the pseudo method under which WEBSOCKET routes are
+// stored. No client sends it; upgrade requests use GET.
+const methodWebSocket = "WEBSOCKET"
+
+// WEBSOCKET registers a handler for WebSocket upgrade requests, i.e. GET
+// requests with the headers Connection: Upgrade and Upgrade: websocket.
+// Other GET requests for the path fall through to a GET route of the same
+// path, if any, so that a page and its WebSocket endpoint can share a URL:
+//
+//	router.GET("/chat", chatPage)
+//	router.WEBSOCKET("/chat", chatSocket)
+//
+// The handler typically hijacks the connection with a WebSocket library. All
+// ResponseWriter wrappers installed by this package support hijacking, and
+// group timeouts set by WithTimeout do not apply to upgrade requests.
+// The routes are listed with the method "WEBSOCKET"; in Allow headers, they
+// count as GET routes. Requests sending "WEBSOCKET" as their method do not
+// reach them.
+func (r *Router) WEBSOCKET(path string, handle http.HandlerFunc, opts ...RouteOption) {
+	r.handle(methodWebSocket, path, handle, opts...)
+}
+
+// isWebSocketUpgrade reports whether the request asks to upgrade the
+// connection to the WebSocket protocol.
+func isWebSocketUpgrade(req *http.Request) bool {
+	return headerHasToken(req.Header, "Upgrade", "websocket") &&
+		headerHasToken(req.Header, "Connection", "upgrade")
+}
+
+// headerHasToken reports whether the comma-separated values of the header
+// contain the token, ignoring case.
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for part := range strings.SplitSeq(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
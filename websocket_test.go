@@ -0,0 +1,110 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newUpgradeRequest(path string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	req.Header.Set("Upgrade", "WebSocket")
+	return req
+}
+
+func TestRouterWebSocket(t *testing.T) {
+	router := New()
+	router.GET("/chat", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("page"))
+	})
+	router.WEBSOCKET("/chat", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("socket"))
+	})
+	router.WEBSOCKET("/rooms/{room}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("room " + r.PathValue("room")))
+	})
+	router.GET("/plain", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("plain"))
+	})
+
+	tests := []struct {
+		req  *http.Request
+		code int
+		body string
+	}{
+		{newUpgradeRequest("/chat"), http.StatusOK, "socket"},
+		{httptest.NewRequest(http.MethodGet, "/chat", nil), http.StatusOK, "page"},
+		{newUpgradeRequest("/rooms/go"), http.StatusOK, "room go"},
+		{httptest.NewRequest(http.MethodGet, "/rooms/go", nil), http.StatusNotFound, ""},
+		{httptest.NewRequest(http.MethodPost, "/rooms/go", nil), http.StatusMethodNotAllowed, ""},
+		{newUpgradeRequest("/plain"), http.StatusOK, "plain"},
+		{httptest.NewRequest(methodWebSocket, "/rooms/go", nil), http.StatusMethodNotAllowed, ""},
+		{httptest.NewRequest(methodWebSocket, "/missing", nil), http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, tt.req)
+		if w.Code != tt.code || tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s %s: expected %d %q, got %d %q", tt.req.Method, tt.req.URL.Path, tt.code, tt.body, w.Code, w.Body.String())
+		}
+	}
+
+	if allow := router.allowed("/rooms/go", http.MethodPost); allow != "GET, OPTIONS" {
+		t.Errorf("unexpected Allow header %q", allow)
+	}
+	if allow := router.allowed("*", http.MethodOptions); allow != "GET, OPTIONS" {
+		t.Errorf("unexpected global Allow header %q", allow)
+	}
+	if e := router.Explain(methodWebSocket, "/rooms/go"); e.Outcome != OutcomeMethodNotAllowed || e.Allow != "GET, OPTIONS" {
+		t.Errorf("unexpected explanation %s", e)
+	}
+}
+
+func TestRouterWebSocketRouteOptions(t *testing.T) {
+	router := New()
+	router.SetAuthenticator(AuthenticatorFunc(func(*http.Request) (any, error) {
+		return nil, ErrNoCredentials
+	}), "private")
+	router.WEBSOCKET("/private", func(w http.ResponseWriter, r *http.Request) {}, Tags("private"))
+	router.WEBSOCKET("/public", func(w http.ResponseWriter, r *http.Request) {})
+
+	for path, code := range map[string]int{"/private": http.StatusUnauthorized, "/public": http.StatusOK} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newUpgradeRequest(path))
+		if w.Code != code {
+			t.Errorf("%s: got %d, want %d", path, w.Code, code)
+		}
+	}
+	if tags := router.RouteTags(methodWebSocket, "/private"); len(tags) != 1 || tags[0] != "private" {
+		t.Errorf("unexpected tags %v", tags)
+	}
+}
+
+func TestMultiRouterWebSocketHijack(t *testing.T) {
+	chat := New()
+	chat.LogRequests = true
+	chat.WEBSOCKET("/ws", func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := http.NewResponseController(w).Hijack(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	multi := NewMultiRouter()
+	multi.Group("/chat", chat, WithTimeout(time.Second), WithMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(WrapWriter(w), r)
+		})
+	}))
+
+	w := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	multi.ServeHTTP(w, newUpgradeRequest("/chat/ws"))
+	if !w.hijacked {
+		t.Error("expected the connection to be hijacked")
+	}
+}
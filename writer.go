@@ -0,0 +1,169 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// WrappedWriter is a http.ResponseWriter which records the status code and
+// the number of bytes written to the underlying ResponseWriter.
+type WrappedWriter interface {
+	http.ResponseWriter
+
+	// Status returns the status code sent to the client, or 0 if the response
+	// header was not written yet.
+	Status() int
+
+	// BytesWritten returns the number of response body bytes written.
+	BytesWritten() int64
+
+	// Unwrap returns the underlying ResponseWriter, which makes the wrapper
+	// compatible with http.ResponseController.
+	Unwrap() http.ResponseWriter
+}
+
+// WrapWriter wraps w into a WrappedWriter.
+// The returned writer implements http.Flusher and http.Hijacker if, and only
+// if, w does. It always implements io.ReaderFrom, using w's implementation if
+// available, so that sendfile optimizations are preserved.
+// If w already is a WrappedWriter, it is returned unchanged.
+//
+// Every ResponseWriter wrapper installed by this package, e.g. for request
+// logging, is a WrappedWriter. Since it implements Unwrap,
+// http.ResponseController can reach the connection through any number of
+// wrappers to set deadlines, flush or enable full duplex mode.
+// The only exception is the writer of timeouts, see WithTimeout and Timeout,
+// which buffers the response: it passes on deadlines and full duplex mode,
+// but flushing and hijacking fail with http.ErrNotSupported.
+func WrapWriter(w http.ResponseWriter) WrappedWriter {
+	if ww, ok := w.(WrappedWriter); ok {
+		return ww
+	}
+
+	rw := &responseWriter{ResponseWriter: w}
+	_, isFlusher := w.(http.Flusher)
+	_, isHijacker := w.(http.Hijacker)
+
+	switch {
+	case isFlusher && isHijacker:
+		return &flushHijackWriter{rw}
+	case isFlusher:
+		return &flushWriter{rw}
+	case isHijacker:
+		return &hijackWriter{rw}
+	default:
+		return rw
+	}
+}
+
+// Make sure the wrappers stay compatible with http.ResponseController
+var (
+	_ interface{ Unwrap() http.ResponseWriter } = (*responseWriter)(nil)
+	_ interface{ Unwrap() http.ResponseWriter } = (*flushWriter)(nil)
+	_ interface{ Unwrap() http.ResponseWriter } = (*hijackWriter)(nil)
+	_ interface{ Unwrap() http.ResponseWriter } = (*flushHijackWriter)(nil)
+)
+
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	// Informational responses may be followed by the final status code
+	if w.status == 0 && (code < 100 || code > 199 || code == http.StatusSwitchingProtocols) {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) ReadFrom(src io.Reader) (int64, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	var n int64
+	var err error
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		n, err = io.Copy(writerOnly{w.ResponseWriter}, src)
+	}
+	w.bytes += n
+	return n, err
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) BytesWritten() int64 {
+	return w.bytes
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *responseWriter) flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (w *responseWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+// writerOnly hides any optional interfaces of the wrapped writer, so that
+// io.Copy does not call back into ReadFrom.
+type writerOnly struct {
+	io.Writer
+}
+
+type flushWriter struct {
+	*responseWriter
+}
+
+func (w *flushWriter) Flush() {
+	w.flush()
+}
+
+type hijackWriter struct {
+	*responseWriter
+}
+
+func (w *hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.hijack()
+}
+
+type flushHijackWriter struct {
+	*responseWriter
+}
+
+func (w *flushHijackWriter) Flush() {
+	w.flush()
+}
+
+func (w *flushHijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.hijack()
+}
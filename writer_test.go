@@ -0,0 +1,128 @@
+// Copyright 2024 Graham Miles. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package httpmux
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	return nil, nil, nil
+}
+
+func TestWrapWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ww := WrapWriter(rec)
+
+	if ww.Status() != 0 {
+		t.Errorf("expected status 0 before writing, got %d", ww.Status())
+	}
+	ww.WriteHeader(http.StatusCreated)
+	ww.WriteHeader(http.StatusTeapot) // superfluous
+	ww.Write([]byte("hello "))
+	io.WriteString(ww, "world")
+	ww.(io.ReaderFrom).ReadFrom(strings.NewReader("!"))
+
+	if ww.Status() != http.StatusCreated {
+		t.Errorf("expected status %d, got %d", http.StatusCreated, ww.Status())
+	}
+	if ww.BytesWritten() != 12 {
+		t.Errorf("expected 12 bytes written, got %d", ww.BytesWritten())
+	}
+	if rec.Body.String() != "hello world!" {
+		t.Errorf("unexpected body %q", rec.Body.String())
+	}
+	if ww.Unwrap() != rec {
+		t.Error("Unwrap does not return the wrapped writer")
+	}
+	if WrapWriter(ww) != ww {
+		t.Error("expected WrappedWriter to be returned unchanged")
+	}
+}
+
+func TestWrapWriterInterfaces(t *testing.T) {
+	// httptest.ResponseRecorder is a Flusher, but not a Hijacker
+	ww := WrapWriter(httptest.NewRecorder())
+	if _, ok := ww.(http.Flusher); !ok {
+		t.Error("expected wrapper to implement http.Flusher")
+	}
+	if _, ok := ww.(http.Hijacker); ok {
+		t.Error("expected wrapper not to implement http.Hijacker")
+	}
+
+	// mockResponseWriter is neither
+	ww = WrapWriter(new(mockResponseWriter))
+	if _, ok := ww.(http.Flusher); ok {
+		t.Error("expected wrapper not to implement http.Flusher")
+	}
+	if _, ok := ww.(http.Hijacker); ok {
+		t.Error("expected wrapper not to implement http.Hijacker")
+	}
+
+	hr := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	ww = WrapWriter(hr)
+	f, ok := ww.(http.Flusher)
+	if !ok {
+		t.Fatal("expected wrapper to implement http.Flusher")
+	}
+	h, ok := ww.(http.Hijacker)
+	if !ok {
+		t.Fatal("expected wrapper to implement http.Hijacker")
+	}
+	h.Hijack()
+	if !hr.hijacked {
+		t.Error("Hijack was not passed through")
+	}
+	f.Flush()
+	if !hr.Flushed {
+		t.Error("Flush was not passed through")
+	}
+}
+
+func TestResponseControllerThroughWrappers(t *testing.T) {
+	logger, _ := newTestLogger()
+	router := New()
+	router.SetLogger(logger)
+	router.LogRequests = true
+	router.PanicHandler = func(_ http.ResponseWriter, _ *http.Request, _ interface{}) {}
+
+	errs := make(chan error, 4)
+	router.GET("/stream", func(w http.ResponseWriter, _ *http.Request) {
+		rc := http.NewResponseController(w)
+		errs <- rc.SetWriteDeadline(time.Now().Add(time.Second))
+		errs <- rc.SetReadDeadline(time.Now().Add(time.Second))
+		errs <- rc.EnableFullDuplex()
+		w.Write([]byte("data"))
+		errs <- rc.Flush()
+	})
+
+	srv := httptest.NewServer(RequestIDHandler(router))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("ResponseController call %d failed: %v", i, err)
+		}
+	}
+}